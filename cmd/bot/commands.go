@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/bwmarrin/discordgo"
+
+	"living-lands-bot/internal/bot"
+	"living-lands-bot/internal/config"
+)
+
+// handleCommands manages the registered slash commands:
+//
+//	commands sync             register the declared commands (bulk overwrite)
+//	commands diff             show drift between declared and registered commands
+//	commands purge [--global] delete registered commands
+//
+// Commands target --guild, else DISCORD_GUILD_ID. Without either, --global
+// is required, so global commands are never changed by accident.
+func handleCommands(cfg *config.Config, logger *slog.Logger) {
+	if len(os.Args) < 3 {
+		logger.Error("usage: bot commands sync|diff|purge")
+		os.Exit(1)
+	}
+	action := os.Args[2]
+
+	fs := flag.NewFlagSet("commands "+action, flag.ExitOnError)
+	guildFlag := fs.String("guild", cfg.Discord.GuildID, "Guild to target (defaults to DISCORD_GUILD_ID)")
+	globalFlag := fs.Bool("global", false, "Target global commands instead of a guild")
+	if err := fs.Parse(os.Args[3:]); err != nil {
+		logger.Error("flag parse failed", "error", err)
+		os.Exit(1)
+	}
+
+	guildID := *guildFlag
+	if *globalFlag {
+		guildID = ""
+	} else if guildID == "" {
+		logger.Error("no guild to target; set DISCORD_GUILD_ID or pass --guild or --global")
+		os.Exit(1)
+	}
+	scope := "global"
+	if guildID != "" {
+		scope = "guild " + guildID
+	}
+
+	// The REST API is enough; no gateway connection is needed
+	dg, err := discordgo.New("Bot " + cfg.Discord.Token)
+	if err != nil {
+		logger.Error("discord session failed", "error", err)
+		os.Exit(1)
+	}
+	self, err := dg.User("@me")
+	if err != nil {
+		logger.Error("failed to fetch bot user", "error", err)
+		os.Exit(1)
+	}
+	// The policy adds default member permissions to the definitions
+	policy, err := bot.LoadAccessPolicy(cfg.Bot.AccessPolicyFile)
+	if err != nil {
+		logger.Error("access policy load failed", "error", err)
+		os.Exit(1)
+	}
+	registry := bot.Commands().WithPolicy(policy)
+
+	switch action {
+	case "sync":
+		drift, err := registry.Sync(dg, self.ID, guildID, logger)
+		if err != nil {
+			logger.Error("command sync failed", "error", err)
+			os.Exit(1)
+		}
+		if len(drift) == 0 {
+			fmt.Printf("Commands for %s are up to date.\n", scope)
+			return
+		}
+		fmt.Printf("Synced %d command(s) for %s:\n", len(registry.Definitions()), scope)
+		printDrift(drift)
+
+	case "diff":
+		registered, err := dg.ApplicationCommands(self.ID, guildID)
+		if err != nil {
+			logger.Error("failed to fetch registered commands", "error", err)
+			os.Exit(1)
+		}
+		drift := registry.Diff(registered)
+		if len(drift) == 0 {
+			fmt.Printf("Commands for %s are up to date.\n", scope)
+			return
+		}
+		fmt.Printf("Drift for %s:\n", scope)
+		printDrift(drift)
+		// Non-zero so scripts can check for drift
+		os.Exit(2)
+
+	case "purge":
+		n, err := bot.PurgeCommands(dg, self.ID, guildID)
+		if err != nil {
+			logger.Error("command purge failed", "error", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Deleted %d command(s) for %s.\n", n, scope)
+		if n > 0 && guildID != "" {
+			fmt.Println("Run `./bot commands sync` or start the bot to register them again.")
+		}
+
+	default:
+		logger.Error("unknown commands action", "action", action)
+		os.Exit(1)
+	}
+}
+
+func printDrift(drift []bot.CommandDrift) {
+	for _, d := range drift {
+		fmt.Printf("  - %s\n", d)
+	}
+}
@@ -3,9 +3,12 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -16,8 +19,11 @@ import (
 	"living-lands-bot/internal/config"
 	"living-lands-bot/internal/database"
 	"living-lands-bot/internal/services"
+	"living-lands-bot/internal/tracing"
 	"living-lands-bot/internal/utils"
+	"living-lands-bot/pkg/llm"
 	"living-lands-bot/pkg/ollama"
+	"living-lands-bot/pkg/openai"
 )
 
 func main() {
@@ -38,8 +44,11 @@ func main() {
 		case "index-docs":
 			handleIndexDocs(cfg, logger)
 			return
-		case "cleanup-commands":
-			handleCleanup()
+		case "gaps":
+			handleGaps(cfg, logger)
+			return
+		case "commands":
+			handleCommands(cfg, logger)
 			return
 		case "help":
 			printHelp()
@@ -71,6 +80,21 @@ func handleMigrate(cfg *config.Config, logger *slog.Logger) {
 		os.Exit(1)
 	}
 
+	// Rows from the single-guild days belong to the primary guild
+	if cfg.Discord.GuildID != "" {
+		updated, err := database.BackfillGuildID(db.Gorm, cfg.Discord.GuildID)
+		if err != nil {
+			logger.Error("guild backfill failed", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("guild backfill complete",
+			"guild_id", cfg.Discord.GuildID,
+			"users", updated["users"],
+			"channel_routes", updated["channel_routes"],
+			"welcome_templates", updated["welcome_templates"],
+		)
+	}
+
 	logger.Info("migrations complete")
 }
 
@@ -78,6 +102,7 @@ func handleIndexDocs(cfg *config.Config, logger *slog.Logger) {
 	// Parse flags for index-docs command
 	fs := flag.NewFlagSet("index-docs", flag.ExitOnError)
 	pathFlag := fs.String("path", "", "Path to directory or file to index")
+	collectionFlag := fs.String("collection", "", "ChromaDB collection to index into, for guilds whose rag_collection setting names it (default: the shared collection)")
 
 	// Skip first two args (program name and command name)
 	if err := fs.Parse(os.Args[2:]); err != nil {
@@ -105,24 +130,24 @@ func handleIndexDocs(cfg *config.Config, logger *slog.Logger) {
 		}
 	}()
 
-	// Initialize Ollama client
-	ollamaClient := ollama.NewClient(cfg.Ollama.URL)
+	// Initialize LLM provider (used for embeddings only)
+	provider := newLLMProvider(cfg, time.Duration(cfg.Ollama.RequestTimeout)*time.Second, logger)
 
 	// Initialize RAG service
-	ragService, err := services.NewRAGService(cfg.Chroma.URL, ollamaClient, cfg.Ollama.EmbeddingModel, logger)
+	ragService, err := services.NewRAGService(cfg.Chroma.URL, provider, cfg.Ollama.EmbeddingModel, logger)
 	if err != nil {
 		logger.Error("rag service init failed", "error", err)
 		os.Exit(1)
 	}
 
-	// Initialize indexer
-	indexer := services.NewDocumentIndexer(ragService, logger)
+	// Initialize indexer, creating the collection if it is new
+	indexer := services.NewDocumentIndexer(ragService.Collection(*collectionFlag), logger)
 
 	// Index the documents
 	indexCtx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
 	defer cancel()
 
-	logger.Info("starting document indexing", "path", *pathFlag)
+	logger.Info("starting document indexing", "path", *pathFlag, "collection", *collectionFlag)
 	if err := indexer.IndexDirectory(indexCtx, *pathFlag); err != nil {
 		logger.Error("document indexing failed", "error", err)
 		os.Exit(1)
@@ -137,7 +162,108 @@ func handleIndexDocs(cfg *config.Config, logger *slog.Logger) {
 	}
 }
 
+func handleGaps(cfg *config.Config, logger *slog.Logger) {
+	fs := flag.NewFlagSet("gaps", flag.ExitOnError)
+	sinceFlag := fs.String("since", "7d", "How far back to look, e.g. 7d, 36h")
+	limitFlag := fs.Int("limit", 20, "Maximum number of topics to list (0 = all)")
+	guildFlag := fs.String("guild", "", "Only list questions asked in this guild (default: every guild)")
+
+	if err := fs.Parse(os.Args[2:]); err != nil {
+		logger.Error("flag parse failed", "error", err)
+		os.Exit(1)
+	}
+
+	window, err := parseSince(*sinceFlag)
+	if err != nil {
+		logger.Error("invalid --since value", "error", err)
+		os.Exit(1)
+	}
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		logger.Error("db open failed", "error", err)
+		os.Exit(1)
+	}
+	// Ensure database connection is closed on exit
+	defer func() {
+		if sqlDB, err := db.Gorm.DB(); err == nil {
+			if err := sqlDB.Close(); err != nil {
+				logger.Error("db close failed", "error", err)
+			}
+		}
+	}()
+
+	// Embeddings are only needed for gaps whose capture-time embedding failed
+	provider := newLLMProvider(cfg, time.Duration(cfg.Ollama.RequestTimeout)*time.Second, logger)
+	gapService := services.NewGapService(db.Gorm, provider, cfg.Ollama.EmbeddingModel, logger)
+	gapService.SetSimilarity(float32(cfg.Gaps.Similarity))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	since := time.Now().Add(-window)
+	clusters, err := gapService.Report(ctx, *guildFlag, since, *limitFlag)
+	if err != nil {
+		logger.Error("gap report failed", "error", err)
+		os.Exit(1)
+	}
+
+	if len(clusters) == 0 {
+		fmt.Printf("No unanswered questions since %s.\n", since.Format("2006-01-02 15:04"))
+		return
+	}
+
+	fmt.Printf("Unanswered topics since %s, most asked first:\n\n", since.Format("2006-01-02 15:04"))
+	for idx, c := range clusters {
+		fmt.Printf("%d. %s (asked %d times, last %s)\n", idx+1, c.Topic, c.Count, c.LastSeen.Format("2006-01-02"))
+		for _, q := range c.Examples {
+			fmt.Printf("     - %s\n", q)
+		}
+	}
+}
+
+// parseSince parses a look-back window such as "7d" or "36h". A "d" suffix
+// means days; anything else goes through time.ParseDuration.
+func parseSince(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("invalid number of days %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("duration must be positive, got %q", value)
+	}
+	return d, nil
+}
+
 func startBot(cfg *config.Config, logger *slog.Logger) {
+	// Initialize tracing before any instrumented client is used
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:     cfg.Tracing.Exporter,
+		OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
+		OTLPInsecure: cfg.Tracing.OTLPInsecure,
+		SampleRatio:  cfg.Tracing.SampleRatio,
+	}, logger)
+	if err != nil {
+		logger.Error("tracing init failed", "error", err)
+		os.Exit(1)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error("tracing shutdown failed", "error", err)
+		}
+	}()
+
 	// Open database
 	db, err := database.Open(cfg)
 	if err != nil {
@@ -178,17 +304,14 @@ func startBot(cfg *config.Config, logger *slog.Logger) {
 	welcomeService := services.NewWelcomeService(db.Gorm, logger)
 	channelService := services.NewChannelService(db.Gorm, logger)
 	rateLimiter := services.NewRateLimiter(redisClient, cfg.Bot.RateLimitPerMin, logger)
+	qaLogService := services.NewQALogService(db.Gorm, logger)
+	settingsService := services.NewSettingsService(db.Gorm, logger)
 
-	// Initialize Ollama client with custom timeout
-	ollamaTimeout := time.Duration(cfg.Ollama.RequestTimeout) * time.Second
-	ollamaClient := ollama.NewClientWithTimeout(cfg.Ollama.URL, ollamaTimeout)
-	logger.Info("ollama client initialized",
-		"url", cfg.Ollama.URL,
-		"timeout_seconds", cfg.Ollama.RequestTimeout,
-	)
+	// Initialize LLM provider with custom timeout
+	provider := newLLMProvider(cfg, time.Duration(cfg.Ollama.RequestTimeout)*time.Second, logger)
 
 	// Initialize RAG service
-	ragService, err := services.NewRAGService(cfg.Chroma.URL, ollamaClient, cfg.Ollama.EmbeddingModel, logger)
+	ragService, err := services.NewRAGService(cfg.Chroma.URL, provider, cfg.Ollama.EmbeddingModel, logger)
 	if err != nil {
 		logger.Error("rag service init failed", "error", err)
 		os.Exit(1)
@@ -209,24 +332,91 @@ func startBot(cfg *config.Config, logger *slog.Logger) {
 		DeepTopK:            40,
 		DeepTopP:            0.95,
 		RepeatPenalty:       1.1,
-		NumContext:          2048,
+		NumContext:          cfg.LLM.ContextWindow,
+		MaxNumContext:       cfg.LLM.ContextWindowMax,
+		BreakerFailures:     cfg.LLM.BreakerFailures,
+		BreakerCooldown:     time.Duration(cfg.LLM.BreakerCooldown) * time.Second,
+		SlowThreshold:       time.Duration(cfg.LLM.SlowThreshold) * time.Second,
+		AttemptTimeout:      time.Duration(cfg.LLM.AttemptTimeout) * time.Second,
+		MaxConcurrent:       cfg.LLM.MaxConcurrent,
+		MaxPerGuild:         cfg.LLM.MaxPerGuild,
+		MaxQueueDepth:       cfg.LLM.MaxQueueDepth,
 	}
 
-	// Initialize LLM service with config
-	llmService, err := services.NewLLMServiceWithConfig(ollamaClient, cfg.Ollama.Model, cfg.Bot.PersonalityFile, llmConfig, logger)
+	// Initialize LLM service with the primary model and any fallbacks
+	backends := llmBackends(cfg, provider, time.Duration(cfg.Ollama.RequestTimeout)*time.Second, logger)
+	personalities, err := services.NewPersonalityRegistry(cfg.Bot.PersonalityFile, cfg.Bot.PersonalityDir, logger)
+	if err != nil {
+		logger.Error("personality load failed", "error", err)
+		os.Exit(1)
+	}
+	llmService, err := services.NewLLMServiceWithBackends(backends, personalities, llmConfig, logger)
 	if err != nil {
 		logger.Error("llm service init failed", "error", err)
 		os.Exit(1)
 	}
+	settingsService.SetKnownPersonalities(personalities.Has)
+	settingsService.SetCollectionCheck(ragService.CollectionExists)
+
+	// Screen answers for links, blocked words and, with a model, toxicity
+	outputRules, err := services.LoadOutputRules(cfg.Guard.OutputRulesFile)
+	if err != nil {
+		logger.Error("output guard rules load failed", "error", err)
+		os.Exit(1)
+	}
+	outputGuard, err := services.NewOutputGuard(outputRules, logger)
+	if err != nil {
+		logger.Error("output guard init failed", "error", err)
+		os.Exit(1)
+	}
+	if cfg.Guard.ToxicityModel != "" {
+		outputGuard.SetToxicityModel(provider, cfg.Guard.ToxicityModel)
+	}
+	llmService.SetOutputGuard(outputGuard)
+
+	// Initialize documentation gap capture (embeds with the RAG embedding model)
+	gapService := services.NewGapService(db.Gorm, provider, cfg.Ollama.EmbeddingModel, logger)
+	gapService.SetSimilarity(float32(cfg.Gaps.Similarity))
+
+	// Initialize curated FAQ answers. Loading runs in the background: /ask
+	// still works through RAG, and entries added later are matched immediately.
+	faqService := services.NewFAQService(db.Gorm, provider, cfg.Ollama.EmbeddingModel, logger)
+	faqService.SetSimilarity(float32(cfg.FAQ.Similarity))
+	go loadFAQ(faqService, logger)
+
+	// Initialize the intent classifier. Until its examples are embedded,
+	// questions are routed by keyword rules, so embedding is retried in the
+	// background if the embedding model is not reachable yet.
+	intentExamples, err := services.LoadIntentExamples(cfg.Bot.IntentExamplesFile)
+	if err != nil {
+		logger.Error("intent examples load failed", "error", err)
+		os.Exit(1)
+	}
+	intentClassifier := services.NewIntentClassifier(provider, cfg.Ollama.EmbeddingModel, intentExamples, logger)
+	intentClassifier.SetMinConfidence(float32(cfg.Bot.IntentMinConfidence))
+	go loadIntentClassifier(intentClassifier, logger)
+
+	// Screen /ask questions for prompt injection, optionally with a classifier model
+	inputGuard := services.NewInputGuard(logger)
+	inputGuard.SetThresholds(cfg.Guard.InputWarnScore, cfg.Guard.InputRefuseScore)
+	if cfg.Guard.ClassifierModel != "" {
+		inputGuard.SetClassifierModel(provider, cfg.Guard.ClassifierModel)
+	}
+
+	accessPolicy, err := bot.LoadAccessPolicy(cfg.Bot.AccessPolicyFile)
+	if err != nil {
+		logger.Error("access policy load failed", "error", err)
+		os.Exit(1)
+	}
 
 	// Initialize bot and HTTP server
-	dBot, err := bot.New(cfg, accountService, ragService, llmService, welcomeService, channelService, rateLimiter, logger)
+	dBot, err := bot.New(cfg, accountService, ragService, llmService, welcomeService, channelService, rateLimiter, qaLogService, gapService, faqService, intentClassifier, inputGuard, settingsService, accessPolicy, logger)
 	if err != nil {
 		logger.Error("discord bot init failed", "error", err)
 		os.Exit(1)
 	}
 
-	httpServer := api.NewServer(cfg, accountService, logger)
+	httpServer := api.NewServer(cfg, accountService, llmService, logger)
 
 	rootCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
@@ -235,6 +425,29 @@ func startBot(cfg *config.Config, logger *slog.Logger) {
 	errCh := make(chan error, 1)
 	go func() { errCh <- httpServer.Start() }()
 
+	// Post the documentation gap digest on schedule (no-op without a channel)
+	go dBot.RunGapDigest(rootCtx)
+
+	// Reload personalities on SIGHUP, and when their files change
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for {
+			select {
+			case <-rootCtx.Done():
+				return
+			case <-hup:
+				if err := llmService.ReloadPersonalities(); err != nil {
+					logger.Error("personality reload failed, keeping the loaded set", "error", err)
+				}
+			}
+		}
+	}()
+	if cfg.Bot.PersonalityReloadInterval > 0 {
+		go llmService.WatchPersonalities(rootCtx, time.Duration(cfg.Bot.PersonalityReloadInterval)*time.Second)
+	}
+
 	// Start Discord with retry loop. Useful during initial setup when the token
 	// may be missing/invalid, or Discord is temporarily unavailable.
 	go func() {
@@ -283,6 +496,81 @@ func startBot(cfg *config.Config, logger *slog.Logger) {
 	_ = redisClient.Close()
 }
 
+// loadFAQ loads the curated FAQ answers, retrying every minute until every
+// variant is embedded.
+func loadFAQ(faq *services.FAQService, logger *slog.Logger) {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		err := faq.Load(ctx)
+		cancel()
+		if err == nil {
+			return
+		}
+		logger.Warn("faq load incomplete, retrying", "error", err)
+		time.Sleep(time.Minute)
+	}
+}
+
+// loadIntentClassifier embeds the intent examples, retrying every minute
+// until the embedding model is reachable.
+func loadIntentClassifier(classifier *services.IntentClassifier, logger *slog.Logger) {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		err := classifier.Load(ctx)
+		cancel()
+		if err == nil {
+			return
+		}
+		logger.Warn("intent classifier load failed, using keyword rules until retry", "error", err)
+		time.Sleep(time.Minute)
+	}
+}
+
+// newLLMProvider builds the generation/embedding backend selected by LLM_PROVIDER.
+func newLLMProvider(cfg *config.Config, timeout time.Duration, logger *slog.Logger) llm.Provider {
+	if cfg.LLM.Provider == "openai" {
+		logger.Info("openai-compatible client initialized",
+			"url", cfg.OpenAI.URL,
+			"timeout_seconds", cfg.Ollama.RequestTimeout,
+		)
+		return openai.NewClient(cfg.OpenAI.URL, cfg.OpenAI.APIKey, timeout)
+	}
+
+	logger.Info("ollama client initialized",
+		"url", cfg.Ollama.URL,
+		"timeout_seconds", cfg.Ollama.RequestTimeout,
+	)
+	return ollama.NewClientWithTimeout(cfg.Ollama.URL, timeout)
+}
+
+// llmBackends builds the generation fallback chain: the primary model on the
+// configured provider followed by each LLM_FALLBACKS entry. An entry with an
+// "@url" suffix gets its own client of the same provider type.
+func llmBackends(cfg *config.Config, primary llm.Provider, timeout time.Duration, logger *slog.Logger) []services.LLMBackend {
+	backends := []services.LLMBackend{
+		{Name: cfg.Ollama.Model, Generator: primary, Model: cfg.Ollama.Model},
+	}
+
+	for _, entry := range cfg.LLM.Fallbacks {
+		entry = strings.TrimSpace(entry)
+		model, url, hasURL := strings.Cut(entry, "@")
+
+		var generator llm.Generator = primary
+		if hasURL {
+			if cfg.LLM.Provider == "openai" {
+				generator = openai.NewClient(url, cfg.OpenAI.APIKey, timeout)
+			} else {
+				generator = ollama.NewClientWithTimeout(url, timeout)
+			}
+		}
+
+		backends = append(backends, services.LLMBackend{Name: entry, Generator: generator, Model: model})
+		logger.Info("llm fallback configured", "backend", entry)
+	}
+
+	return backends
+}
+
 func printHelp() {
 	help := `Living Lands Discord Bot
 
@@ -290,17 +578,30 @@ Usage:
   ./bot [command] [options]
 
 Commands:
-  migrate              Run database migrations
+  migrate              Run database migrations (and assign old rows to DISCORD_GUILD_ID)
   index-docs           Index documents for RAG
     --path <path>      Path to directory or file to index (required)
-  cleanup-commands     Remove all registered Discord commands (fixes duplicates)
+    --collection <name> Collection to index into (default: the shared one)
+  gaps                 List the most-asked questions the docs could not answer
+    --since <window>   How far back to look, e.g. 7d or 36h (default 7d)
+    --limit <n>        Maximum number of topics to list (default 20, 0 = all)
+    --guild <id>       Only list questions asked in this guild (default: every guild)
+  commands sync        Register the slash commands (replaces stale and duplicate ones)
+  commands diff        Show drift between declared and registered commands
+  commands purge       Delete the registered slash commands
+    --guild <id>       Target this guild instead of DISCORD_GUILD_ID
+    --global           Target global commands instead of a guild
   help                 Show this help message
   (no command)         Start the bot in normal mode
 
 Examples:
   ./bot migrate
   ./bot index-docs --path ./docs
-  ./bot cleanup-commands
+  ./bot index-docs --path ./modding-docs --collection modding_docs
+  ./bot gaps --since 7d
+  ./bot commands diff
+  ./bot commands sync --guild 123456789012345678
+  ./bot commands purge --global
   ./bot
 `
 	println(help)
@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"log/slog"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 
+	"living-lands-bot/internal/metrics"
 	"living-lands-bot/internal/services"
 )
 
@@ -32,6 +34,7 @@ func NewVerifyHandler(account *services.AccountService, logger *slog.Logger) *Ve
 func (h *VerifyHandler) Handle(c *fiber.Ctx) error {
 	var req VerifyRequest
 	if err := c.BodyParser(&req); err != nil {
+		metrics.VerificationAttempted("invalid_request")
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "invalid request body",
 		})
@@ -49,6 +52,7 @@ func (h *VerifyHandler) Handle(c *fiber.Ctx) error {
 			"ip", c.IP(),
 			"errors", errors,
 		)
+		metrics.VerificationAttempted("invalid_request")
 
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   "validation failed",
@@ -62,16 +66,30 @@ func (h *VerifyHandler) Handle(c *fiber.Ctx) error {
 			"code", req.Code[:2]+"***", // Partial code for logging
 			"username", req.HytaleUsername,
 		)
+		metrics.VerificationAttempted(verifyOutcome(err))
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
+	metrics.VerificationAttempted("success")
 	return c.JSON(fiber.Map{
 		"status": "success",
 	})
 }
 
+// verifyOutcome maps a VerifyLink error to a metrics outcome label.
+func verifyOutcome(err error) string {
+	switch {
+	case errors.Is(err, services.ErrInvalidCode):
+		return "invalid_code"
+	case errors.Is(err, services.ErrCodeExpired):
+		return "expired"
+	default:
+		return "error"
+	}
+}
+
 // formatValidationError converts a validation error to a user-friendly message
 func formatValidationError(err validator.FieldError) string {
 	switch err.Tag() {
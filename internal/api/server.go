@@ -7,11 +7,13 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"log/slog"
 
 	"living-lands-bot/internal/api/handlers"
 	"living-lands-bot/internal/config"
+	"living-lands-bot/internal/metrics"
 	"living-lands-bot/internal/services"
 )
 
@@ -19,10 +21,13 @@ type Server struct {
 	app    *fiber.App
 	addr   string
 	config *config.Config
+	llm    *services.LLMService
 	logger *slog.Logger
 }
 
-func NewServer(cfg *config.Config, account *services.AccountService, logger *slog.Logger) *Server {
+// NewServer creates the HTTP server. llm may be nil, in which case /health
+// omits backend state.
+func NewServer(cfg *config.Config, account *services.AccountService, llm *services.LLMService, logger *slog.Logger) *Server {
 	app := fiber.New(fiber.Config{
 		DisableStartupMessage: true,
 		ReadTimeout:           10 * time.Second,
@@ -36,11 +41,13 @@ func NewServer(cfg *config.Config, account *services.AccountService, logger *slo
 		app:    app,
 		addr:   cfg.HTTP.Addr,
 		config: cfg,
+		llm:    llm,
 		logger: logger,
 	}
 
 	// Routes
 	app.Get("/health", s.health)
+	app.Get("/metrics", adaptor.HTTPHandler(metrics.Handler()))
 
 	// API routes with auth
 	verifyHandler := handlers.NewVerifyHandler(account, logger)
@@ -70,9 +77,29 @@ func (s *Server) ShutdownWithContext(ctx context.Context) error {
 	}
 }
 
+// health reports liveness. A tripped LLM breaker reports "degraded" but keeps
+// 200 so container healthchecks don't restart a bot that is still answering.
 func (s *Server) health(c *fiber.Ctx) error {
+	if s.llm == nil {
+		return c.Status(http.StatusOK).JSON(fiber.Map{
+			"status": "healthy",
+		})
+	}
+
+	status := "healthy"
+	if s.llm.Degraded() {
+		status = "degraded"
+	}
+
+	active, waiting := s.llm.QueueStats()
+
 	return c.Status(http.StatusOK).JSON(fiber.Map{
-		"status": "healthy",
+		"status":       status,
+		"llm_backends": s.llm.BackendHealth(),
+		"llm_queue": fiber.Map{
+			"active":  active,
+			"waiting": waiting,
+		},
 	})
 }
 
@@ -17,19 +17,22 @@ type Bot struct {
 	welcome  *services.WelcomeService
 	channel  *services.ChannelService
 	limiter  *services.RateLimiter
+	gaps     *services.GapService
+	settings *services.SettingsService
 }
 
-func New(cfg *config.Config, account *services.AccountService, rag *services.RAGService, llm *services.LLMService, welcome *services.WelcomeService, channel *services.ChannelService, limiter *services.RateLimiter, logger *slog.Logger) (*Bot, error) {
+func New(cfg *config.Config, account *services.AccountService, rag *services.RAGService, llm *services.LLMService, welcome *services.WelcomeService, channel *services.ChannelService, limiter *services.RateLimiter, qa *services.QALogService, gaps *services.GapService, faq *services.FAQService, intents *services.IntentClassifier, guard *services.InputGuard, settings *services.SettingsService, policy *AccessPolicy, logger *slog.Logger) (*Bot, error) {
 	dg, err := discordgo.New("Bot " + cfg.Discord.Token)
 	if err != nil {
 		return nil, err
 	}
 
-	dg.Identify.Intents = discordgo.IntentsGuildMembers |
+	dg.Identify.Intents = discordgo.IntentsGuilds |
+		discordgo.IntentsGuildMembers |
 		discordgo.IntentsGuildMessages |
 		discordgo.IntentsMessageContent
 
-	handlers := NewCommandHandlers(account, rag, llm, limiter, logger)
+	handlers := NewCommandHandlers(account, rag, llm, limiter, qa, gaps, faq, intents, guard, settings, policy, logger)
 
 	b := &Bot{
 		session:  dg,
@@ -39,9 +42,12 @@ func New(cfg *config.Config, account *services.AccountService, rag *services.RAG
 		welcome:  welcome,
 		channel:  channel,
 		limiter:  limiter,
+		gaps:     gaps,
+		settings: settings,
 	}
 
 	dg.AddHandler(b.onReady)
+	dg.AddHandler(b.onGuildCreate)
 	dg.AddHandler(handlers.HandleInteraction)
 	dg.AddHandler(b.onGuildMemberAdd)
 
@@ -59,37 +65,60 @@ func (b *Bot) Stop() error {
 }
 
 func (b *Bot) onReady(s *discordgo.Session, r *discordgo.Ready) {
-	b.logger.Info("discord connected", "user", s.State.User.Username)
+	b.logger.Info("discord connected", "user", s.State.User.Username, "guilds", len(r.Guilds))
 
-	// Register commands
-	if err := b.handlers.RegisterCommands(s, b.config.Discord.GuildID); err != nil {
-		b.logger.Error("failed to register commands", "error", err)
+	// Commands are registered per guild as GuildCreate arrives for each one
+	b.handlers.WarnGlobalCommands(s, s.State.User.ID)
+}
+
+// onGuildCreate registers the commands in a guild when the bot joins it, and
+// for every guild it is in when the session (re)connects.
+func (b *Bot) onGuildCreate(s *discordgo.Session, g *discordgo.GuildCreate) {
+	if g.Unavailable {
+		return
+	}
+	if !b.config.AllowsGuild(g.ID) {
+		b.logger.Info("skipping guild not in DISCORD_GUILD_IDS", "guild_id", g.ID, "guild", g.Name)
+		return
+	}
+
+	if err := b.handlers.RegisterCommands(s, s.State.User.ID, g.ID); err != nil {
+		b.logger.Error("failed to register commands", "error", err, "guild_id", g.ID)
 	}
 }
 
 func (b *Bot) onGuildMemberAdd(s *discordgo.Session, m *discordgo.GuildMemberAdd) {
-	username := m.User.Username
-
-	message, err := b.welcome.GetRandomTemplate(username)
-	if err != nil {
-		b.logger.Error("failed to get welcome template", "error", err, "user", username)
+	if !b.config.AllowsGuild(m.GuildID) {
 		return
 	}
+	username := m.User.Username
 
-	// TODO: Make welcome channel configurable
-	// For now, send to the system channel or first available text channel
-	channels, err := s.GuildChannels(m.GuildID)
+	message, err := b.welcome.GetRandomTemplate(m.GuildID, username)
 	if err != nil {
-		b.logger.Error("failed to get channels", "error", err)
+		b.logger.Error("failed to get welcome template", "error", err, "user", username)
 		return
 	}
 
-	// Find first text channel
+	// Use the guild's welcome channel, or else the first text channel
 	var targetChannel string
-	for _, ch := range channels {
-		if ch.Type == discordgo.ChannelTypeGuildText {
-			targetChannel = ch.ID
-			break
+	if b.settings != nil {
+		settings, err := b.settings.Resolve(m.GuildID, "")
+		if err != nil {
+			b.logger.Warn("failed to resolve welcome channel", "error", err, "guild_id", m.GuildID)
+		}
+		targetChannel = settings.WelcomeChannelID
+	}
+	if targetChannel == "" {
+		channels, err := s.GuildChannels(m.GuildID)
+		if err != nil {
+			b.logger.Error("failed to get channels", "error", err)
+			return
+		}
+		for _, ch := range channels {
+			if ch.Type == discordgo.ChannelTypeGuildText {
+				targetChannel = ch.ID
+				break
+			}
 		}
 	}
 
@@ -2,105 +2,205 @@ package bot
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
+	"living-lands-bot/internal/i18n"
+	"living-lands-bot/internal/metrics"
 	"living-lands-bot/internal/services"
+	"living-lands-bot/internal/tracing"
+	"living-lands-bot/pkg/language"
 )
 
 type CommandHandlers struct {
-	account *services.AccountService
-	rag     *services.RAGService
-	llm     *services.LLMService
-	limiter *services.RateLimiter
-	logger  *slog.Logger
+	account  *services.AccountService
+	rag      *services.RAGService
+	llm      *services.LLMService
+	limiter  *services.RateLimiter
+	qa       *services.QALogService
+	gaps     *services.GapService
+	faq      *services.FAQService
+	intents  *services.IntentClassifier
+	guard    *services.InputGuard
+	settings *services.SettingsService
+	policy   *AccessPolicy
+	commands *CommandRegistry
+	logger   *slog.Logger
 }
 
-func NewCommandHandlers(account *services.AccountService, rag *services.RAGService, llm *services.LLMService, limiter *services.RateLimiter, logger *slog.Logger) *CommandHandlers {
+// NewCommandHandlers wires the command handlers. A nil policy leaves every
+// command open except for the permissions built into the registry; nil
+// settings apply the defaults everywhere; a nil input guard lets every
+// question through.
+func NewCommandHandlers(account *services.AccountService, rag *services.RAGService, llm *services.LLMService, limiter *services.RateLimiter, qa *services.QALogService, gaps *services.GapService, faq *services.FAQService, intents *services.IntentClassifier, guard *services.InputGuard, settings *services.SettingsService, policy *AccessPolicy, logger *slog.Logger) *CommandHandlers {
 	return &CommandHandlers{
-		account: account,
-		rag:     rag,
-		llm:     llm,
-		limiter: limiter,
-		logger:  logger,
+		account:  account,
+		rag:      rag,
+		llm:      llm,
+		limiter:  limiter,
+		qa:       qa,
+		gaps:     gaps,
+		faq:      faq,
+		intents:  intents,
+		guard:    guard,
+		settings: settings,
+		policy:   policy,
+		commands: commands.WithPolicy(policy),
+		logger:   logger,
 	}
 }
 
-var commands = []*discordgo.ApplicationCommand{
-	{
-		Name:        "link",
-		Description: "Link your Hytale account to Discord",
+// commands are the slash commands the bot serves. Names and descriptions
+// are written in English here and translated from the i18n catalogue.
+var commands = NewCommandRegistry(
+	Command{
+		Definition: &discordgo.ApplicationCommand{
+			Name:        "link",
+			Description: "Link your Hytale account to Discord",
+		},
+		Handler: (*CommandHandlers).handleLinkCommand,
 	},
-	{
-		Name:        "guide",
-		Description: "Get directions to important channels",
+	Command{
+		Definition: &discordgo.ApplicationCommand{
+			Name:        "guide",
+			Description: "Get directions to important channels",
+		},
+		Handler: (*CommandHandlers).handleGuideCommand,
 	},
-	{
-		Name:        "ask",
-		Description: "Ask a question about Living Lands",
-		Options: []*discordgo.ApplicationCommandOption{
-			{
-				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "question",
-				Description: "Your question about the mod",
-				Required:    true,
+	Command{
+		Definition: &discordgo.ApplicationCommand{
+			Name:        "ask",
+			Description: "Ask a question about Living Lands",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "question",
+					Description: "Your question about the mod",
+					Required:    true,
+				},
 			},
 		},
+		Handler: (*CommandHandlers).handleAskCommand,
 	},
+	faqCommand,
+	settingsCommand,
+)
+
+// Commands returns the registry of slash commands the bot serves.
+func Commands() *CommandRegistry {
+	return commands
 }
 
-func (h *CommandHandlers) RegisterCommands(s *discordgo.Session, guildID string) error {
-	h.logger.Info("registering commands", "guild_id", guildID, "bot_id", s.State.User.ID)
+// RegisterCommands syncs the declared commands to guildID, or globally if it
+// is empty. Registration never falls back from guild to global, since that is
+// what left duplicate commands behind; leftover global commands are only
+// reported, by WarnGlobalCommands.
+func (h *CommandHandlers) RegisterCommands(api CommandAPI, appID, guildID string) error {
+	h.logger.Info("registering commands", "guild_id", guildID, "bot_id", appID)
 
-	// Try guild commands first (instant), fallback to global if guild fails
-	for _, cmd := range commands {
-		created, err := s.ApplicationCommandCreate(s.State.User.ID, guildID, cmd)
-		if err != nil {
-			h.logger.Error("guild command failed, trying global",
-				"command", cmd.Name,
-				"error", err,
-				"guild_id", guildID,
-			)
+	_, err := h.commands.Sync(api, appID, guildID, h.logger)
+	return err
+}
 
-			// Fallback: try global command (takes up to 1hr to propagate)
-			created, err = s.ApplicationCommandCreate(s.State.User.ID, "", cmd)
-			if err != nil {
-				return fmt.Errorf("failed to create command %s (guild and global): %w", cmd.Name, err)
-			}
-			h.logger.Info("registered global command", "name", cmd.Name, "id", created.ID)
-		} else {
-			h.logger.Info("registered guild command", "name", cmd.Name, "id", created.ID, "guild", guildID)
-		}
+// WarnGlobalCommands logs a warning if global commands are registered, since
+// they duplicate the per-guild commands in every guild.
+func (h *CommandHandlers) WarnGlobalCommands(api CommandAPI, appID string) {
+	global, err := api.ApplicationCommands(appID, "")
+	if err != nil {
+		h.logger.Warn("failed to fetch global commands", "error", err)
+	} else if len(global) > 0 {
+		h.logger.Warn("global commands duplicate the guild commands; run ./bot commands purge --global",
+			"count", len(global),
+		)
 	}
-	return nil
 }
 
+// HandleInteraction is the discordgo event handler; it forwards to Dispatch
+// with the live session as the responder.
 func (h *CommandHandlers) HandleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	h.Dispatch(s, i)
+}
+
+// Dispatch routes an interaction to the matching command or component handler.
+// It only depends on Responder, so tests can replay fixture payloads without a gateway.
+// Each interaction is the root span of its trace, and its context carries the
+// effective guild and channel settings.
+func (h *CommandHandlers) Dispatch(r Responder, i *discordgo.InteractionCreate) {
+	ctx, span := tracing.Tracer().Start(context.Background(), "discord.interaction",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("discord.interaction_type", i.Type.String()),
+			attribute.String("discord.guild_id", i.GuildID),
+		),
+	)
+	defer span.End()
+	ctx = withSettings(ctx, h.resolveSettings(i))
+
 	switch i.Type {
 	case discordgo.InteractionApplicationCommand:
-		h.handleCommand(s, i)
+		h.handleCommand(ctx, r, i)
 	case discordgo.InteractionMessageComponent:
-		h.handleComponent(s, i)
+		h.handleComponent(r, i)
+	case discordgo.InteractionModalSubmit:
+		h.handleModalSubmit(ctx, r, i)
 	}
 }
 
-func (h *CommandHandlers) handleCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+// Command outcomes reported to the commands_total metric.
+const (
+	outcomeSuccess     = "success"
+	outcomeError       = "error"
+	outcomeTimeout     = "timeout"
+	outcomeInvalid     = "invalid"
+	outcomeRateLimited = "rate_limited"
+	outcomeShed        = "shed"
+	outcomeShortcut    = "shortcut"
+	outcomeCurated     = "curated"
+	outcomeDenied      = "denied"
+	outcomeWithheld    = "withheld"
+	outcomeRefused     = "refused"
+)
+
+func (h *CommandHandlers) handleCommand(ctx context.Context, r Responder, i *discordgo.InteractionCreate) {
 	data := i.ApplicationCommandData()
+	start := time.Now()
 
-	switch data.Name {
-	case "link":
-		h.handleLinkCommand(s, i)
-	case "guide":
-		h.handleGuideCommand(s, i)
-	case "ask":
-		h.handleAskCommand(s, i)
+	cmd, ok := h.commands.Lookup(data.Name)
+	if !ok {
+		return
+	}
+
+	var outcome string
+	path := commandPath(data)
+	switch reason, err := h.authorize(i, cmd, path); {
+	case err != nil:
+		h.logger.Error("access check failed", "command", path, "error", err)
+		respondDenied(r, i, i18n.MsgAccessCheckFailed)
+		outcome = outcomeError
+	case reason != allowed:
+		h.logger.Info("command denied", "command", path, "reason", reason, "channel_id", i.ChannelID)
+		respondDenied(r, i, reason.message())
+		outcome = outcomeDenied
+	default:
+		outcome = cmd.Handler(h, ctx, r, i)
 	}
+
+	metrics.CommandCompleted(data.Name, outcome, time.Since(start))
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("discord.command", data.Name),
+		attribute.String("outcome", outcome),
+	)
 }
 
-func (h *CommandHandlers) handleLinkCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func (h *CommandHandlers) handleLinkCommand(_ context.Context, r Responder, i *discordgo.InteractionCreate) string {
+	lang := replyLanguage(i, "")
+
 	var discordID string
 	var username string
 
@@ -120,45 +220,46 @@ func (h *CommandHandlers) handleLinkCommand(s *discordgo.Session, i *discordgo.I
 			"has_member", i.Member != nil,
 			"has_user", i.User != nil,
 		)
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		r.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: "Unable to identify your Discord account. Please try again.",
+				Content: i18n.T(lang, i18n.MsgUnknownUser),
 				Flags:   discordgo.MessageFlagsEphemeral,
 			},
 		})
-		return
+		return outcomeError
 	}
 
-	code, err := h.account.GenerateVerificationCode(discordID, username)
+	code, err := h.account.GenerateVerificationCode(i.GuildID, discordID, username)
 	if err != nil {
 		h.logger.Error("failed to generate code", "error", err)
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		r.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: "Failed to generate verification code. Please try again.",
+				Content: i18n.T(lang, i18n.MsgLinkCodeFailed),
 				Flags:   discordgo.MessageFlagsEphemeral,
 			},
 		})
-		return
+		return outcomeError
 	}
 
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+	r.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
-			Content: fmt.Sprintf("Your verification code is: `%s`\n\n"+
-				"Run `/verify %s` in Hytale to link your account.\n"+
-				"This code expires in 10 minutes.", code, code),
-			Flags: discordgo.MessageFlagsEphemeral,
+			Content: i18n.T(lang, i18n.MsgLinkCode, code, code),
+			Flags:   discordgo.MessageFlagsEphemeral,
 		},
 	})
+	return outcomeSuccess
 }
 
-func (h *CommandHandlers) handleGuideCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func (h *CommandHandlers) handleGuideCommand(_ context.Context, r Responder, i *discordgo.InteractionCreate) string {
+	lang := replyLanguage(i, "")
+
 	// Build embed with channel guide
 	embed := &discordgo.MessageEmbed{
-		Title:       "📍 Channel Guide",
-		Description: "Select a category below to navigate to the right channel:",
+		Title:       i18n.T(lang, i18n.MsgGuideTitle),
+		Description: i18n.T(lang, i18n.MsgGuideDescription),
 		Color:       0x2D6A4F, // Forest green from brand palette
 	}
 
@@ -167,22 +268,22 @@ func (h *CommandHandlers) handleGuideCommand(s *discordgo.Session, i *discordgo.
 		discordgo.ActionsRow{
 			Components: []discordgo.MessageComponent{
 				discordgo.Button{
-					Label:    "🐛 Bug Reports",
+					Label:    i18n.T(lang, i18n.MsgGuideBugs),
 					Style:    discordgo.PrimaryButton,
 					CustomID: "guide_bugs",
 				},
 				discordgo.Button{
-					Label:    "📋 Changelog",
+					Label:    i18n.T(lang, i18n.MsgGuideChangelog),
 					Style:    discordgo.PrimaryButton,
 					CustomID: "guide_changelog",
 				},
 				discordgo.Button{
-					Label:    "📚 Wiki",
+					Label:    i18n.T(lang, i18n.MsgGuideWiki),
 					Style:    discordgo.PrimaryButton,
 					CustomID: "guide_wiki",
 				},
 				discordgo.Button{
-					Label:    "💬 Support",
+					Label:    i18n.T(lang, i18n.MsgGuideSupport),
 					Style:    discordgo.PrimaryButton,
 					CustomID: "guide_support",
 				},
@@ -190,7 +291,7 @@ func (h *CommandHandlers) handleGuideCommand(s *discordgo.Session, i *discordgo.
 		},
 	}
 
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+	r.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
 			Embeds:     []*discordgo.MessageEmbed{embed},
@@ -198,11 +299,24 @@ func (h *CommandHandlers) handleGuideCommand(s *discordgo.Session, i *discordgo.
 			Flags:      discordgo.MessageFlagsEphemeral,
 		},
 	})
+	return outcomeSuccess
 }
 
-func (h *CommandHandlers) handleAskCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func (h *CommandHandlers) handleAskCommand(ctx context.Context, r Responder, i *discordgo.InteractionCreate) string {
 	startTime := time.Now()
 
+	settings := settingsFrom(ctx)
+	if !settings.AskEnabled {
+		h.logger.Info("ask disabled in channel", "guild_id", i.GuildID, "channel_id", i.ChannelID)
+		respondDenied(r, i, i18n.MsgAccessDeniedChannel)
+		return outcomeDenied
+	}
+	// Answers are public unless the channel or guild keeps them private
+	var answerFlags discordgo.MessageFlags
+	if settings.EphemeralAnswers {
+		answerFlags = discordgo.MessageFlagsEphemeral
+	}
+
 	// Get user ID for rate limiting
 	var userID string
 	var username string
@@ -214,71 +328,86 @@ func (h *CommandHandlers) handleAskCommand(s *discordgo.Session, i *discordgo.In
 		username = i.User.Username
 	}
 
+	// Canned replies use the player's language, judged from the question too
+	data := i.ApplicationCommandData()
+	var question string
+	if len(data.Options) > 0 {
+		question = data.Options[0].StringValue()
+	}
+	lang := replyLanguage(i, question)
+
 	// Check rate limit before processing
 	if userID != "" && h.limiter != nil {
-		allowed, remaining, retryAfter, err := h.limiter.IsAllowed(context.Background(), userID)
+		allowed, remaining, retryAfter, err := h.limiter.IsAllowedWithLimit(ctx, userID, settings.RateLimitPerMin)
 		if err != nil {
 			h.logger.Error("rate limit check failed", "error", err, "user_id", userID)
 			// Log but continue (don't block on rate limit failure)
 		} else if !allowed {
 			h.logger.Warn("rate limit exceeded", "user_id", userID, "username", username, "retry_after", retryAfter.Seconds())
-			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			metrics.RateLimitRejected()
+			r.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseChannelMessageWithSource,
 				Data: &discordgo.InteractionResponseData{
-					Content: fmt.Sprintf("The archives are experiencing many seekers at once. Please try again in %.0f seconds.", retryAfter.Seconds()),
+					Content: i18n.T(lang, i18n.MsgAskRateLimited, retryAfter.Seconds()),
 					Flags:   discordgo.MessageFlagsEphemeral,
 				},
 			})
-			return
+			return outcomeRateLimited
 		}
 		h.logger.Debug("rate limit allowed", "user_id", userID, "remaining", remaining)
 	}
 
-	// Get question from command options
-	data := i.ApplicationCommandData()
+	// A question is required
 	if len(data.Options) == 0 {
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		r.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: "Please provide a question.",
+				Content: i18n.T(lang, i18n.MsgAskNoQuestion),
 				Flags:   discordgo.MessageFlagsEphemeral,
 			},
 		})
-		return
+		return outcomeInvalid
 	}
 
-	question := data.Options[0].StringValue()
+	// Defer before screening, routing and FAQ matching: each may call a model,
+	// and together they can outlast Discord's 3 second deadline. Every reply
+	// from here on is a follow-up.
+	r.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: answerFlags},
+	})
+
+	// Screen the question for prompt injection before any work is done on it
+	if !h.screenQuestion(ctx, i, userID, question) {
+		h.followupPrivate(r, i, i18n.T(lang, i18n.MsgAskRefused), answerFlags)
+		return outcomeRefused
+	}
 
 	// Classify the query intent
-	intent := services.ClassifyIntent(question)
-	h.logger.Debug("query intent classified", "question", question, "intent", intent.String())
+	intent := h.classifyIntent(ctx, question).Intent
 
-	// Handle navigation and account intents with shortcuts (no LLM needed)
-	switch intent {
-	case services.IntentNavigation:
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "For channel navigation, use the `/guide` command - it will help you find the right place!",
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
-		return
-	case services.IntentAccountHelp:
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "For account linking, use the `/link` command - it will generate a verification code for you!",
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
-		return
-	}
+	// Curated FAQ answers take precedence over every other route
+	faq := h.matchFAQ(ctx, i.GuildID, question)
 
-	// Defer the interaction response (RAG+LLM takes >3 seconds)
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
-	})
+	// Handle curated answers, navigation and account intents with shortcuts (no LLM needed)
+	switch {
+	case faq != nil:
+		h.logger.Info("curated answer matched",
+			"question", question,
+			"faq_id", faq.EntryID,
+			"variant", faq.Variant,
+			"similarity", faq.Similarity,
+		)
+		// The marker tells readers the answer was written by staff
+		h.followupText(r, i, i18n.T(lang, i18n.MsgAskCurated)+"\n"+sanitizeMentions(faq.Answer), answerFlags)
+		return outcomeCurated
+	case intent == services.IntentNavigation:
+		h.followupPrivate(r, i, i18n.T(lang, i18n.MsgAskNavigation), answerFlags)
+		return outcomeShortcut
+	case intent == services.IntentAccountHelp:
+		h.followupPrivate(r, i, i18n.T(lang, i18n.MsgAskAccountHelp), answerFlags)
+		return outcomeShortcut
+	}
 
 	// Determine response mode based on intent
 	mode := services.DetermineMode(intent, false) // will be updated after RAG query
@@ -299,11 +428,12 @@ func (h *CommandHandlers) handleAskCommand(s *discordgo.Session, i *discordgo.In
 	}
 
 	// Create root context with total timeout
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	// 1. Only query RAG if the intent requires it
-	var ragContext []string
+	var ragResults []services.RAGResult
+	ragSucceeded := false
 	if intent.NeedsRAG() {
 		// Use a sub-context with shorter timeout for RAG
 		// Ensure RAG timeout doesn't exceed parent context timeout
@@ -316,7 +446,7 @@ func (h *CommandHandlers) handleAskCommand(s *discordgo.Session, i *discordgo.In
 		defer ragCancel()
 
 		var err error
-		ragContext, err = h.rag.Query(ragCtx, question, 5)
+		ragResults, err = h.rag.Collection(settings.RAGCollection).QueryResults(ragCtx, question, 5)
 		if err != nil {
 			ragTimeoutReached := ragCtx.Err() == context.DeadlineExceeded
 			h.logger.Warn("rag query failed, continuing without context",
@@ -326,20 +456,49 @@ func (h *CommandHandlers) handleAskCommand(s *discordgo.Session, i *discordgo.In
 				"rag_timeout_ms", ragTimeout.Milliseconds(),
 			)
 			// Continue without context if RAG fails
-			ragContext = []string{}
+			ragResults = nil
 		} else {
-			h.logger.Debug("rag context retrieved", "count", len(ragContext), "intent", intent.String())
+			ragSucceeded = true
+			h.logger.Debug("rag context retrieved", "count", len(ragResults), "intent", intent.String())
 		}
 	} else {
 		h.logger.Debug("skipping rag for conversational query", "question", question)
 	}
 
+	ragContext := make([]string, len(ragResults))
+	for idx, res := range ragResults {
+		ragContext[idx] = res.Text
+	}
+
 	// Update mode now that we know if we have RAG context
 	mode = services.DetermineMode(intent, len(ragContext) > 0)
 
-	// 2. Generate LLM response with intent-aware mode
-	answer, err := h.llm.GenerateResponseWithIntent(ctx, question, ragContext, intent)
-	if err != nil {
+	// 2. Wait for a generation slot (fast mode jumps the queue), then generate.
+	// If we had to wait, the deferred response shows the position in line and
+	// is later replaced by the answer.
+	queued := false
+	outcome := outcomeSuccess
+	var answer string
+	release, err := h.llm.Acquire(ctx, i.GuildID, mode, func(position int) {
+		queued = true
+		notice := i18n.T(lang, i18n.MsgAskQueued, position)
+		if _, editErr := r.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &notice}); editErr != nil {
+			h.logger.Warn("failed to show queue position", "error", editErr, "position", position)
+		}
+	})
+	var generated *services.Answer
+	if err == nil {
+		generated, err = h.llm.GenerateAnswer(services.WithSources(services.WithPersonality(ctx, settings.Personality), ragResults), question, ragContext, intent)
+		release()
+	}
+	if err == nil {
+		if generated.NoAnswer && generated.Text == "" {
+			// The model sent the no-answer marker and nothing else
+			generated.Text = i18n.T(lang, i18n.MsgAskNoAnswer)
+		}
+		answer = generated.Text
+	}
+	if err != nil && !errors.Is(err, services.ErrAnswerBlocked) {
 		h.logger.Error("llm generation failed",
 			"error", err,
 			"question", question,
@@ -348,23 +507,57 @@ func (h *CommandHandlers) handleAskCommand(s *discordgo.Session, i *discordgo.In
 			"elapsed_ms", time.Since(startTime).Milliseconds(),
 			"timeout_reached", ctx.Err() != nil,
 		)
-		// Provide a graceful fallback message
-		if ctx.Err() != nil {
-			answer = "The archives are being consulted by many travelers at this moment, causing some delay. Please try again shortly, seeker."
-		} else {
-			answer = "I apologize, traveler. The mists cloud my vision at this moment. Please try again."
+	}
+	if err != nil {
+		// Provide a graceful fallback message; the output guard has already
+		// logged a withheld answer for review
+		switch {
+		case errors.Is(err, services.ErrAnswerBlocked):
+			outcome = outcomeWithheld
+			answer = i18n.T(lang, i18n.MsgAskWithheld)
+		case errors.Is(err, services.ErrQueueFull):
+			outcome = outcomeShed
+			answer = i18n.T(lang, i18n.MsgAskShed)
+		case ctx.Err() != nil:
+			outcome = outcomeTimeout
+			answer = i18n.T(lang, i18n.MsgAskTimeout)
+		default:
+			outcome = outcomeError
+			answer = i18n.T(lang, i18n.MsgAskError)
 		}
 	}
 
-	// 3. Send follow-up response
-	_, sendErr := s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-		Content: answer,
-	})
+	// 3. Log the answer so it can be rated; only logged answers get feedback buttons
+	var components []discordgo.MessageComponent
+	if generated != nil {
+		components = h.logAnswer(i, userID, username, question, intent, ragResults, generated, startTime)
+	}
+	if reason := gapReason(ragSucceeded, ragResults, generated); reason != "" {
+		h.recordGap(i, userID, question, reason)
+	}
+
+	// 4. Send the answer, replacing the queue notice if one was shown
+	_, sendSpan := tracing.Tracer().Start(ctx, "discord.Followup", trace.WithAttributes(
+		attribute.Bool("discord.edit_original", queued),
+	))
+	var style *services.EmbedStyle
+	if generated != nil {
+		style = generated.Embed
+	}
+	messages := renderAnswer(answer, style, i18n.T(lang, i18n.MsgAskAttached))
+	sendErr := h.sendAnswer(r, i, queued, messages, components, answerFlags)
+	tracing.EndSpan(sendSpan, sendErr)
 
 	// username already obtained from rate limit check above, no need to redeclare
 
 	elapsedMs := time.Since(startTime).Milliseconds()
 
+	// The personality that answered, which may be seasonal
+	personality := settings.Personality
+	if generated != nil {
+		personality = generated.Personality
+	}
+
 	if sendErr != nil {
 		h.logger.Error("failed to send followup",
 			"error", sendErr,
@@ -379,12 +572,157 @@ func (h *CommandHandlers) handleAskCommand(s *discordgo.Session, i *discordgo.In
 			"mode", mode.String(),
 			"rag_contexts", len(ragContext),
 			"elapsed_ms", elapsedMs,
+			"queued", queued,
 			"success", err == nil,
+			"personality", personality,
+			"rag_collection", settings.RAGCollection,
 		)
 	}
+
+	if sendErr != nil {
+		return outcomeError
+	}
+	return outcome
 }
 
-func (h *CommandHandlers) handleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+// sendAnswer sends the messages of a rendered answer. The first replaces the
+// queue notice if one was shown; the feedback buttons go on the last.
+func (h *CommandHandlers) sendAnswer(r Responder, i *discordgo.InteractionCreate, queued bool, messages []renderedMessage, components []discordgo.MessageComponent, flags discordgo.MessageFlags) error {
+	for n, m := range messages {
+		var buttons []discordgo.MessageComponent
+		if n == len(messages)-1 {
+			buttons = components
+		}
+
+		var err error
+		if n == 0 && queued {
+			edit := &discordgo.WebhookEdit{
+				Content:         &m.Content,
+				Files:           m.Files,
+				AllowedMentions: noMentions,
+			}
+			if m.Embeds != nil {
+				edit.Embeds = &m.Embeds
+			}
+			if buttons != nil {
+				edit.Components = &buttons
+			}
+			_, err = r.InteractionResponseEdit(i.Interaction, edit)
+		} else {
+			_, err = r.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+				Content:         m.Content,
+				Embeds:          m.Embeds,
+				Files:           m.Files,
+				Components:      buttons,
+				AllowedMentions: noMentions,
+				Flags:           flags,
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// followupPrivate replies to a deferred /ask with a message only the asker
+// sees. The first follow-up takes over the deferred message and keeps its
+// visibility, so a public deferral is deleted first.
+func (h *CommandHandlers) followupPrivate(r Responder, i *discordgo.InteractionCreate, content string, deferred discordgo.MessageFlags) {
+	if deferred&discordgo.MessageFlagsEphemeral == 0 {
+		if err := r.InteractionResponseDelete(i.Interaction); err != nil {
+			h.logger.Warn("failed to delete deferred response", "error", err, "interaction_id", i.ID)
+		}
+	}
+	h.followupText(r, i, content, discordgo.MessageFlagsEphemeral)
+}
+
+// followupText replies to a deferred /ask with a canned message that pings
+// no one.
+func (h *CommandHandlers) followupText(r Responder, i *discordgo.InteractionCreate, content string, flags discordgo.MessageFlags) {
+	_, err := r.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content:         content,
+		AllowedMentions: noMentions,
+		Flags:           flags,
+	})
+	if err != nil {
+		h.logger.Error("failed to send followup", "error", err, "interaction_id", i.ID)
+	}
+}
+
+// replyLanguage picks the language of canned replies to an interaction from
+// the client locale and, if given, the text the player wrote.
+func replyLanguage(i *discordgo.InteractionCreate, text string) language.Language {
+	return i18n.ResolveLanguage(i.Locale, text)
+}
+
+// intentTimeout bounds embedding the question for intent classification;
+// on timeout the keyword rules decide.
+const intentTimeout = time.Second
+
+// screenQuestion runs the input guard on an /ask question and logs its
+// decision with the reason codes. It reports whether to answer.
+func (h *CommandHandlers) screenQuestion(ctx context.Context, i *discordgo.InteractionCreate, userID, question string) bool {
+	if h.guard == nil {
+		return true
+	}
+
+	verdict := h.guard.Check(ctx, question)
+	metrics.InputGuarded(string(verdict.Action), verdict.Reasons)
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("guard.input", string(verdict.Action)),
+		attribute.StringSlice("guard.reasons", verdict.Reasons),
+	)
+
+	args := []any{
+		"action", verdict.Action,
+		"score", verdict.Score,
+		"reasons", verdict.Reasons,
+		"user_id", userID,
+		"guild_id", i.GuildID,
+		"channel_id", i.ChannelID,
+		"question", question,
+	}
+	if verdict.Action == services.InputAllow {
+		h.logger.Debug("input guard decision", args...)
+	} else {
+		h.logger.Warn("input guard decision", args...)
+	}
+
+	return verdict.Action != services.InputRefuse
+}
+
+// classifyIntent routes an /ask question with the embedding classifier, or
+// the keyword rules if none is configured.
+func (h *CommandHandlers) classifyIntent(ctx context.Context, question string) services.IntentClassification {
+	ctx, span := tracing.Tracer().Start(ctx, "ClassifyIntent")
+	defer span.End()
+
+	var c services.IntentClassification
+	if h.intents != nil {
+		ctx, cancel := context.WithTimeout(ctx, intentTimeout)
+		c = h.intents.Classify(ctx, question)
+		cancel()
+	} else {
+		c = services.IntentClassification{Intent: services.ClassifyIntent(question), Method: services.IntentMethodKeyword}
+	}
+
+	span.SetAttributes(
+		attribute.String("intent", c.Intent.String()),
+		attribute.String("intent.method", c.Method),
+		attribute.Float64("intent.confidence", float64(c.Confidence)),
+	)
+	metrics.IntentClassified(c.Intent.String(), c.Method)
+	h.logger.Debug("query intent classified",
+		"question", question,
+		"intent", c.Intent.String(),
+		"method", c.Method,
+		"confidence", c.Confidence,
+	)
+	return c
+}
+
+func (h *CommandHandlers) handleComponent(r Responder, i *discordgo.InteractionCreate) {
 	customID := i.MessageComponentData().CustomID
 
 	// Handle guide button clicks
@@ -393,15 +731,21 @@ func (h *CommandHandlers) handleComponent(s *discordgo.Session, i *discordgo.Int
 
 		// For now, just acknowledge the click
 		// TODO: Look up channel_id from database and provide link
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		r.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: fmt.Sprintf("Navigation to **%s** coming soon! (Channel mapping needs configuration)", keyword),
+				Content: i18n.T(replyLanguage(i, ""), i18n.MsgGuideComingSoon, keyword),
 				Flags:   discordgo.MessageFlagsEphemeral,
 			},
 		})
 		return
 	}
 
+	// Handle answer feedback buttons
+	if strings.HasPrefix(customID, feedbackPrefix) {
+		h.handleFeedbackComponent(r, i, customID)
+		return
+	}
+
 	h.logger.Info("unknown component interaction", "custom_id", customID)
 }
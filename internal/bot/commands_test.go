@@ -0,0 +1,600 @@
+package bot
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"living-lands-bot/internal/database/models"
+	"living-lands-bot/internal/i18n"
+	"living-lands-bot/internal/services"
+	"living-lands-bot/internal/testutil"
+	"living-lands-bot/pkg/language"
+	"living-lands-bot/pkg/ollama"
+)
+
+const testPersonalityFile = "../../configs/personality.yaml"
+
+// testGuildID is the guild of the interaction fixtures in testdata.
+const testGuildID = "1000000000000000000"
+
+func getTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
+}
+
+// newTestHandlers builds CommandHandlers backed by offline Ollama/Chroma fakes.
+func newTestHandlers(t *testing.T) (*CommandHandlers, *testutil.OllamaServer) {
+	t.Helper()
+	return newTestHandlersWithConfig(t, services.DefaultLLMConfig())
+}
+
+// newTestHandlersWithConfig is newTestHandlers with a custom LLM configuration.
+func newTestHandlersWithConfig(t *testing.T, config services.LLMConfig) (*CommandHandlers, *testutil.OllamaServer) {
+	t.Helper()
+
+	logger := getTestLogger()
+	ollamaServer := testutil.NewOllamaServer(t)
+	chromaServer := testutil.NewChromaServer(t)
+	client := ollama.NewClient(ollamaServer.URL)
+
+	rag, err := services.NewRAGService(chromaServer.URL, client, "nomic-embed-text", logger)
+	require.NoError(t, err)
+	require.NoError(t, rag.AddDocuments(context.Background(), []services.Document{
+		{ID: "metabolism", Text: "The metabolism system tracks hunger and thirst.", Metadata: map[string]interface{}{"source": "metabolism.md"}},
+	}))
+
+	llm, err := services.NewLLMServiceWithConfig(client, "test-model", testPersonalityFile, config, logger)
+	require.NoError(t, err)
+
+	db := testutil.NewTestDB(t, &models.QAInteraction{}, &models.QAFeedback{}, &models.QAReport{}, &models.DocGap{}, &models.GapDigest{}, &models.FAQEntry{}, &models.FAQVariant{},
+		&models.GuildSettings{}, &models.ChannelSettings{})
+	qa := services.NewQALogService(db, logger)
+	gaps := services.NewGapService(db, client, "nomic-embed-text", logger)
+	faq := services.NewFAQService(db, client, "nomic-embed-text", logger)
+	settings := services.NewSettingsService(db, logger)
+
+	return NewCommandHandlers(nil, rag, llm, nil, qa, gaps, faq, nil, services.NewInputGuard(logger), settings, nil, logger), ollamaServer
+}
+
+func TestGuideCommand(t *testing.T) {
+	h, _ := newTestHandlers(t)
+	rec := testutil.NewInteractionRecorder()
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/guide.json"))
+
+	responses := rec.Responses()
+	require.Len(t, responses, 1)
+	resp := responses[0]
+	assert.Equal(t, discordgo.InteractionResponseChannelMessageWithSource, resp.Type)
+	assert.Equal(t, discordgo.MessageFlagsEphemeral, resp.Data.Flags)
+	require.Len(t, resp.Data.Embeds, 1)
+	assert.Equal(t, "📍 Channel Guide", resp.Data.Embeds[0].Title)
+
+	require.Len(t, resp.Data.Components, 1)
+	row, ok := resp.Data.Components[0].(discordgo.ActionsRow)
+	require.True(t, ok, "expected an actions row")
+
+	var ids []string
+	for _, c := range row.Components {
+		ids = append(ids, c.(discordgo.Button).CustomID)
+	}
+	assert.Equal(t, []string{"guide_bugs", "guide_changelog", "guide_wiki", "guide_support"}, ids)
+	assert.Empty(t, rec.Followups())
+}
+
+func TestGuideButtonComponent(t *testing.T) {
+	h, _ := newTestHandlers(t)
+	rec := testutil.NewInteractionRecorder()
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/component_guide_bugs.json"))
+
+	responses := rec.Responses()
+	require.Len(t, responses, 1)
+	assert.Equal(t, discordgo.MessageFlagsEphemeral, responses[0].Data.Flags)
+	assert.Contains(t, responses[0].Data.Content, "**bugs**")
+}
+
+func TestLinkCommandWithoutUser(t *testing.T) {
+	h, _ := newTestHandlers(t)
+	rec := testutil.NewInteractionRecorder()
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/link_no_user.json"))
+
+	responses := rec.Responses()
+	require.Len(t, responses, 1)
+	assert.Equal(t, discordgo.MessageFlagsEphemeral, responses[0].Data.Flags)
+	assert.Contains(t, responses[0].Data.Content, "Unable to identify your Discord account")
+}
+
+func TestAskCommandShortcuts(t *testing.T) {
+	tests := []struct {
+		name     string
+		fixture  string
+		contains string
+	}{
+		{"navigation", "testdata/ask_navigation.json", "`/guide`"},
+		{"account help", "testdata/ask_account.json", "`/link`"},
+		{"missing question", "testdata/ask_no_options.json", "Please provide a question."},
+		// The client locale decides unless the question is confidently in another language
+		{"navigation in client locale", "testdata/ask_navigation_de.json", "Nutze für die Kanalsuche den Befehl `/guide`"},
+		{"account help in question language", "testdata/ask_account_ru.json", "Чтобы привязать аккаунт, используй команду `/link`"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, ollamaServer := newTestHandlers(t)
+			rec := testutil.NewInteractionRecorder()
+
+			h.Dispatch(rec, testutil.LoadInteraction(t, tt.fixture))
+
+			responses := rec.Responses()
+			require.Len(t, responses, 1)
+			if tt.name == "missing question" {
+				// Rejected at once, before the response is deferred
+				assert.Equal(t, discordgo.InteractionResponseChannelMessageWithSource, responses[0].Type)
+				assert.Equal(t, discordgo.MessageFlagsEphemeral, responses[0].Data.Flags)
+				assert.Contains(t, responses[0].Data.Content, tt.contains)
+				assert.Empty(t, rec.Followups())
+			} else {
+				assert.Equal(t, discordgo.InteractionResponseDeferredChannelMessageWithSource, responses[0].Type)
+				followups := rec.Followups()
+				require.Len(t, followups, 1)
+				assert.Contains(t, followups[0].Content, tt.contains)
+				assert.Equal(t, discordgo.MessageFlagsEphemeral, followups[0].Flags)
+				assert.Equal(t, 1, rec.Deletes(), "the public deferral is replaced by a private reply")
+			}
+			assert.Empty(t, ollamaServer.ChatRequests(), "shortcuts must not call the LLM")
+		})
+	}
+}
+
+func TestAskCommandDeferredFollowup(t *testing.T) {
+	h, ollamaServer := newTestHandlers(t)
+	ollamaServer.Script(testutil.Completion{Response: "Hunger and thirst drain over time, traveler."})
+	rec := testutil.NewInteractionRecorder()
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/ask_knowledge.json"))
+
+	responses := rec.Responses()
+	require.Len(t, responses, 1)
+	assert.Equal(t, discordgo.InteractionResponseDeferredChannelMessageWithSource, responses[0].Type)
+
+	followups := rec.Followups()
+	require.Len(t, followups, 1)
+	assert.Equal(t, "Hunger and thirst drain over time, traveler.", followups[0].Content)
+
+	reqs := ollamaServer.ChatRequests()
+	require.Len(t, reqs, 1)
+	require.Len(t, reqs[0].Messages, 3)
+	assert.Contains(t, reqs[0].Messages[1].Content, "metabolism system tracks hunger", "RAG context should reach the prompt")
+}
+
+func TestAskCommandSplitsLongAnswer(t *testing.T) {
+	h, ollamaServer := newTestHandlers(t)
+	paragraph := strings.Repeat("Hunger and thirst drain over time, traveler. ", 20)
+	answer := strings.TrimSpace(strings.Repeat(paragraph+"\n\n", 3))
+	require.Greater(t, len(answer), 2000)
+	ollamaServer.Script(testutil.Completion{Response: answer})
+	rec := testutil.NewInteractionRecorder()
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/ask_knowledge.json"))
+
+	followups := rec.Followups()
+	require.Len(t, followups, 2, "an answer over Discord's limit is split, not cut")
+	var parts []string
+	for _, f := range followups {
+		assert.LessOrEqual(t, utf8.RuneCountInString(f.Content), 2000)
+		parts = append(parts, f.Content)
+	}
+	assert.Equal(t, answer, strings.Join(parts, "\n\n"))
+	assert.Empty(t, followups[0].Components)
+	assert.NotEmpty(t, followups[1].Components, "feedback buttons go on the last message")
+}
+
+func TestAskCommandFeedbackButtons(t *testing.T) {
+	h, ollamaServer := newTestHandlers(t)
+	ollamaServer.Script(testutil.Completion{Response: "Hunger and thirst drain over time, traveler."})
+	rec := testutil.NewInteractionRecorder()
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/ask_knowledge.json"))
+
+	followups := rec.Followups()
+	require.Len(t, followups, 1)
+	require.Len(t, followups[0].Components, 1)
+	row, ok := followups[0].Components[0].(discordgo.ActionsRow)
+	require.True(t, ok, "expected an actions row")
+
+	var ids []string
+	for _, c := range row.Components {
+		ids = append(ids, c.(discordgo.Button).CustomID)
+	}
+	assert.Equal(t, []string{"qa_up:1", "qa_down:1", "qa_report:1"}, ids)
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/component_feedback_up.json"))
+
+	responses := rec.Responses()
+	require.Len(t, responses, 2)
+	assert.Equal(t, discordgo.MessageFlagsEphemeral, responses[1].Data.Flags)
+	assert.Contains(t, responses[1].Data.Content, "Thank you for your feedback")
+
+	counts, err := h.qa.FeedbackCounts(1)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{models.RatingUp: 1}, counts)
+}
+
+func TestFeedbackComponentUnknownAnswer(t *testing.T) {
+	h, _ := newTestHandlers(t)
+	rec := testutil.NewInteractionRecorder()
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/component_feedback_up.json"))
+
+	responses := rec.Responses()
+	require.Len(t, responses, 1)
+	assert.Contains(t, responses[0].Data.Content, "no longer in the archives")
+}
+
+func TestAskCommandLLMFailureFallback(t *testing.T) {
+	h, ollamaServer := newTestHandlers(t)
+	ollamaServer.Script(testutil.Completion{Status: http.StatusInternalServerError})
+	rec := testutil.NewInteractionRecorder()
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/ask_knowledge.json"))
+
+	followups := rec.Followups()
+	require.Len(t, followups, 1)
+	assert.Contains(t, followups[0].Content, "The mists cloud my vision")
+}
+
+func TestAskCommandWithheldAnswer(t *testing.T) {
+	h, ollamaServer := newTestHandlers(t)
+	guard, err := services.NewOutputGuard(services.OutputRules{BlockedWords: []string{"grief"}}, getTestLogger())
+	require.NoError(t, err)
+	h.llm.SetOutputGuard(guard)
+	ollamaServer.Script(testutil.Completion{Response: "Grief their base while they sleep."})
+	rec := testutil.NewInteractionRecorder()
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/ask_knowledge.json"))
+
+	followups := rec.Followups()
+	require.Len(t, followups, 1)
+	assert.Equal(t, i18n.T(language.English, i18n.MsgAskWithheld), followups[0].Content)
+	assert.Empty(t, followups[0].Components, "withheld answers are not logged for feedback")
+}
+
+func TestAskCommandRefusesInjection(t *testing.T) {
+	h, ollamaServer := newTestHandlers(t)
+	rec := testutil.NewInteractionRecorder()
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/ask_injection.json"))
+
+	responses := rec.Responses()
+	require.Len(t, responses, 1)
+	assert.Equal(t, discordgo.InteractionResponseDeferredChannelMessageWithSource, responses[0].Type)
+	followups := rec.Followups()
+	require.Len(t, followups, 1)
+	assert.Equal(t, i18n.T(language.English, i18n.MsgAskRefused), followups[0].Content)
+	assert.Equal(t, discordgo.MessageFlagsEphemeral, followups[0].Flags)
+	assert.Equal(t, 1, rec.Deletes(), "the public deferral is replaced by a private refusal")
+	assert.Empty(t, ollamaServer.ChatRequests(), "refused questions must not reach the LLM")
+}
+
+// timedRecorder notes when the first interaction response was sent.
+type timedRecorder struct {
+	*testutil.InteractionRecorder
+	start     time.Time
+	responded time.Duration
+}
+
+func (r *timedRecorder) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error {
+	if r.responded == 0 {
+		r.responded = time.Since(r.start)
+	}
+	return r.InteractionRecorder.InteractionRespond(interaction, resp, options...)
+}
+
+func TestAskCommandDefersBeforeSlowModels(t *testing.T) {
+	h, ollamaServer := newTestHandlers(t)
+	_, err := h.faq.Add(context.Background(), testGuildID, "where can I download living lands", nil, "From CurseForge.", "900000000000000002")
+	require.NoError(t, err)
+	ollamaServer.DelayEmbeddings(time.Second)
+	ollamaServer.Script(testutil.Completion{Response: "Hunger and thirst drain over time, traveler."})
+	rec := &timedRecorder{InteractionRecorder: testutil.NewInteractionRecorder(), start: time.Now()}
+
+	// Not a curated question, so it is embedded for FAQ matching
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/ask_knowledge.json"))
+
+	responses := rec.Responses()
+	require.Len(t, responses, 1)
+	assert.Equal(t, discordgo.InteractionResponseDeferredChannelMessageWithSource, responses[0].Type)
+	assert.Less(t, rec.responded, 500*time.Millisecond, "deferred before embedding the question")
+	assert.GreaterOrEqual(t, time.Since(rec.start), time.Second)
+}
+
+func TestAskCommandShowsQueuePosition(t *testing.T) {
+	config := services.DefaultLLMConfig()
+	config.MaxConcurrent = 1
+	h, ollamaServer := newTestHandlersWithConfig(t, config)
+	ollamaServer.Script(testutil.Completion{Response: "Hunger and thirst drain over time, traveler."})
+	rec := testutil.NewInteractionRecorder()
+
+	// Occupy the only generation slot
+	hold, err := h.llm.Acquire(context.Background(), "", services.ModeDeep, nil)
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/ask_knowledge.json"))
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return len(rec.Edits()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Contains(t, *rec.Edits()[0].Content, "You are #1 in line")
+
+	hold()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ask command did not complete")
+	}
+
+	// The answer replaces the queue notice instead of arriving as a new message
+	edits := rec.Edits()
+	require.Len(t, edits, 2)
+	assert.Equal(t, "Hunger and thirst drain over time, traveler.", *edits[1].Content)
+	assert.Empty(t, rec.Followups())
+}
+
+func TestAskCommandShedsLoadWhenQueueFull(t *testing.T) {
+	config := services.DefaultLLMConfig()
+	config.MaxConcurrent = 1
+	config.MaxQueueDepth = 0
+	h, ollamaServer := newTestHandlersWithConfig(t, config)
+	rec := testutil.NewInteractionRecorder()
+
+	hold, err := h.llm.Acquire(context.Background(), "", services.ModeDeep, nil)
+	require.NoError(t, err)
+	defer hold()
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/ask_knowledge.json"))
+
+	followups := rec.Followups()
+	require.Len(t, followups, 1)
+	assert.Contains(t, followups[0].Content, "cannot take another question")
+	assert.Empty(t, rec.Edits())
+	assert.Empty(t, ollamaServer.ChatRequests())
+}
+
+func TestAskCommandTraceSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	h, _ := newTestHandlers(t)
+	rec := testutil.NewInteractionRecorder()
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/ask_knowledge.json"))
+
+	spans := recorder.Ended()
+	byName := make(map[string]sdktrace.ReadOnlySpan)
+	for _, s := range spans {
+		// Prefer child spans: seeding documents in newTestHandlers embeds outside any trace
+		if _, ok := byName[s.Name()]; !ok || s.Parent().IsValid() {
+			byName[s.Name()] = s
+		}
+	}
+
+	root, ok := byName["discord.interaction"]
+	require.True(t, ok, "missing interaction span")
+	traceID := root.SpanContext().TraceID()
+
+	for _, name := range []string{"ClassifyIntent", "rag.Query", "ollama.Embed", "chroma.Query", "llm.GenerateResponse", "ollama.Generate", "discord.Followup"} {
+		s, ok := byName[name]
+		if assert.True(t, ok, "missing span %s", name) {
+			assert.Equal(t, traceID, s.SpanContext().TraceID(), "span %s should belong to the interaction trace", name)
+		}
+	}
+
+	assert.Equal(t, byName["rag.Query"].SpanContext().SpanID(), byName["chroma.Query"].Parent().SpanID())
+	assert.Equal(t, byName["llm.GenerateResponse"].SpanContext().SpanID(), byName["ollama.Generate"].Parent().SpanID())
+}
+
+func TestAskCommandRecordsDocumentationGap(t *testing.T) {
+	h, ollamaServer := newTestHandlers(t)
+	ollamaServer.Script(testutil.Completion{Response: "[NO_ANSWER] I don't have information about that in my archives, traveler."})
+	rec := testutil.NewInteractionRecorder()
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/ask_knowledge.json"))
+	followups := rec.Followups()
+	require.NotEmpty(t, followups)
+	assert.Equal(t, "I don't have information about that in my archives, traveler.", followups[0].Content, "the marker is not sent")
+
+	var clusters []services.GapCluster
+	require.Eventually(t, func() bool {
+		var err error
+		clusters, err = h.gaps.Report(context.Background(), testGuildID, time.Now().Add(-time.Hour), 0)
+		return err == nil && len(clusters) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "How does the metabolism system work?", clusters[0].Topic)
+}
+
+func TestAskCommandNoAnswerMarkerOnly(t *testing.T) {
+	h, ollamaServer := newTestHandlers(t)
+	ollamaServer.Script(testutil.Completion{Response: "[NO_ANSWER]"})
+	rec := testutil.NewInteractionRecorder()
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/ask_knowledge.json"))
+
+	followups := rec.Followups()
+	require.NotEmpty(t, followups)
+	assert.Equal(t, i18n.T(language.English, i18n.MsgAskNoAnswer), followups[0].Content)
+}
+
+func TestAskCommandCuratedAnswer(t *testing.T) {
+	h, ollamaServer := newTestHandlers(t)
+	rec := testutil.NewInteractionRecorder()
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/modal_faq_add.json"))
+	followups := rec.Followups()
+	require.Len(t, followups, 1)
+	assert.Contains(t, followups[0].Content, "Curated answer #1 saved with 3 phrasing(s)")
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/ask_faq.json"))
+
+	responses := rec.Responses()
+	require.Len(t, responses, 2)
+	assert.Equal(t, discordgo.InteractionResponseDeferredChannelMessageWithSource, responses[1].Type)
+	followups = rec.Followups()
+	require.Len(t, followups, 2)
+	assert.Equal(t, i18n.T(language.English, i18n.MsgAskCurated)+"\nDownload it from CurseForge: https://example.com/living-lands", followups[1].Content)
+	assert.Empty(t, ollamaServer.ChatRequests(), "curated answers must not call the LLM")
+}
+
+func TestAskCommandCuratedAnswerScopedAndSilent(t *testing.T) {
+	h, _ := newTestHandlers(t)
+	rec := testutil.NewInteractionRecorder()
+	ctx := context.Background()
+
+	// Another guild's curated answer is not used
+	_, err := h.faq.Add(ctx, "1000000000000000001", "where can I download living lands", nil, "Not here.", "900000000000000002")
+	require.NoError(t, err)
+	_, err = h.faq.Add(ctx, testGuildID, "where can I download living lands", nil, "@everyone check <@&1234> or <@5678>.", "900000000000000002")
+	require.NoError(t, err)
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/ask_faq.json"))
+
+	followups := rec.Followups()
+	require.Len(t, followups, 1)
+	data := followups[0]
+	assert.NotContains(t, data.Content, "Not here.")
+	assert.NotContains(t, data.Content, "@everyone")
+	assert.NotContains(t, data.Content, "<@&1234>")
+	assert.NotContains(t, data.Content, "<@5678>")
+	require.NotNil(t, data.AllowedMentions)
+	assert.Empty(t, data.AllowedMentions.Parse, "curated answers must not ping anyone")
+}
+
+func TestFAQCommandRequiresPermission(t *testing.T) {
+	h, _ := newTestHandlers(t)
+	rec := testutil.NewInteractionRecorder()
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/faq_list_not_admin.json"))
+
+	responses := rec.Responses()
+	require.Len(t, responses, 1)
+	assert.Equal(t, discordgo.MessageFlagsEphemeral, responses[0].Data.Flags)
+	assert.Equal(t, i18n.T(language.English, i18n.MsgAccessDeniedPermission), responses[0].Data.Content)
+}
+
+func TestFAQListCommand(t *testing.T) {
+	h, _ := newTestHandlers(t)
+	rec := testutil.NewInteractionRecorder()
+
+	_, err := h.faq.Add(context.Background(), testGuildID, "What Hytale version is supported?", nil, "Living Lands supports Hytale 1.0.", "900000000000000002")
+	require.NoError(t, err)
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/faq_list.json"))
+
+	responses := rec.Responses()
+	require.Len(t, responses, 1)
+	require.Len(t, responses[0].Data.Embeds, 1)
+	fields := responses[0].Data.Embeds[0].Fields
+	require.Len(t, fields, 1)
+	assert.Equal(t, "#1 What Hytale version is supported?", fields[0].Name)
+}
+
+func TestAskCommandEmbeddingIntentRouting(t *testing.T) {
+	h, ollamaServer := newTestHandlers(t)
+	rec := testutil.NewInteractionRecorder()
+
+	// Keyword rules alone send this to the /link shortcut
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/ask_link_creatures.json"))
+	require.Len(t, rec.Followups(), 1)
+	assert.Contains(t, rec.Followups()[0].Content, "`/link`")
+
+	examples, err := services.LoadIntentExamples("../../configs/intents.yaml")
+	require.NoError(t, err)
+	h.intents = services.NewIntentClassifier(ollama.NewClient(ollamaServer.URL), "nomic-embed-text", examples, getTestLogger())
+	h.intents.SetMinConfidence(0.3)
+	require.NoError(t, h.intents.Load(context.Background()))
+
+	ollamaServer.Script(testutil.Completion{Response: "Creatures cannot be linked, traveler."})
+	rec = testutil.NewInteractionRecorder()
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/ask_link_creatures.json"))
+
+	responses := rec.Responses()
+	require.Len(t, responses, 1)
+	assert.Equal(t, discordgo.InteractionResponseDeferredChannelMessageWithSource, responses[0].Type)
+	require.Len(t, rec.Followups(), 1)
+	assert.Equal(t, "Creatures cannot be linked, traveler.", rec.Followups()[0].Content)
+}
+
+// commandNamePattern is Discord's rule for command and option names.
+var commandNamePattern = regexp.MustCompile(`^[-_\p{L}\p{N}\p{M}]{1,32}$`)
+
+func TestCommandLocalizations(t *testing.T) {
+	var locales []discordgo.Locale
+	for _, lang := range i18n.Languages()[1:] {
+		locales = append(locales, i18n.DiscordLocales(lang)...)
+	}
+	require.NotEmpty(t, locales)
+
+	check := func(path, name, description string, names, descriptions map[discordgo.Locale]string) {
+		assert.Equal(t, name, i18n.T(language.English, i18n.MessageID("command."+path+".name")), "English name of %s", path)
+		assert.Equal(t, description, i18n.T(language.English, i18n.MessageID("command."+path+".description")), "English description of %s", path)
+		for _, locale := range locales {
+			localized := names[locale]
+			assert.Regexp(t, commandNamePattern, localized, "%s name for %s", path, locale)
+			assert.Equal(t, strings.ToLower(localized), localized, "%s name for %s must be lowercase", path, locale)
+			assert.NotEmpty(t, descriptions[locale], "%s description for %s", path, locale)
+			assert.LessOrEqual(t, len([]rune(descriptions[locale])), 100, "%s description for %s", path, locale)
+		}
+	}
+
+	var checkOptions func(parent string, options []*discordgo.ApplicationCommandOption)
+	checkOptions = func(parent string, options []*discordgo.ApplicationCommandOption) {
+		seen := make(map[discordgo.Locale]map[string]bool)
+		for _, opt := range options {
+			path := parent + "." + opt.Name
+			check(path, opt.Name, opt.Description, opt.NameLocalizations, opt.DescriptionLocalizations)
+			for locale, name := range opt.NameLocalizations {
+				if seen[locale] == nil {
+					seen[locale] = make(map[string]bool)
+				}
+				assert.False(t, seen[locale][name], "%s name %q for %s is not unique", path, name, locale)
+				seen[locale][name] = true
+			}
+			checkOptions(path, opt.Options)
+		}
+	}
+
+	seen := make(map[discordgo.Locale]map[string]bool)
+	for _, cmd := range commands.Definitions() {
+		require.NotNil(t, cmd.NameLocalizations, cmd.Name)
+		require.NotNil(t, cmd.DescriptionLocalizations, cmd.Name)
+		check(cmd.Name, cmd.Name, cmd.Description, *cmd.NameLocalizations, *cmd.DescriptionLocalizations)
+		for locale, name := range *cmd.NameLocalizations {
+			if seen[locale] == nil {
+				seen[locale] = make(map[string]bool)
+			}
+			assert.False(t, seen[locale][name], "command name %q for %s is not unique", name, locale)
+			seen[locale][name] = true
+		}
+		checkOptions(cmd.Name, cmd.Options)
+	}
+}
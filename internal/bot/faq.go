@@ -0,0 +1,302 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"go.opentelemetry.io/otel/attribute"
+
+	"living-lands-bot/internal/i18n"
+	"living-lands-bot/internal/services"
+	"living-lands-bot/internal/tracing"
+	"living-lands-bot/pkg/language"
+)
+
+const (
+	// faqAddModalID is the custom ID of the /faq add modal.
+	faqAddModalID = "faq_add"
+	// faqMatchTimeout bounds embedding the question for FAQ matching; on
+	// timeout the question goes through RAG as usual.
+	faqMatchTimeout = 2 * time.Second
+)
+
+var faqCommand = Command{
+	Definition: &discordgo.ApplicationCommand{
+		Name:        "faq",
+		Description: "Manage curated answers for /ask",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "add",
+				Description: "Add a curated answer",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "List curated answers",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "Remove a curated answer",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "id",
+						Description: "ID shown by /faq list",
+						Required:    true,
+						MinValue:    floatPtr(1),
+					},
+				},
+			},
+		},
+	},
+	Permissions: discordgo.PermissionManageGuild,
+	Handler:     (*CommandHandlers).handleFAQCommand,
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+// matchFAQ returns the guild's curated answer for an /ask question, or nil if
+// there is none or matching failed (the question then goes through RAG as usual).
+func (h *CommandHandlers) matchFAQ(ctx context.Context, guildID, question string) *services.FAQMatch {
+	if h.faq == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, faqMatchTimeout)
+	defer cancel()
+	ctx, span := tracing.Tracer().Start(ctx, "faq.Match")
+
+	match, err := h.faq.Match(ctx, guildID, question)
+	if match != nil {
+		span.SetAttributes(
+			attribute.Int("faq.entry_id", int(match.EntryID)),
+			attribute.Float64("faq.similarity", float64(match.Similarity)),
+		)
+	}
+	tracing.EndSpan(span, err)
+
+	if err != nil {
+		h.logger.Warn("faq match failed, continuing without it", "error", err)
+		return nil
+	}
+	return match
+}
+
+func (h *CommandHandlers) handleFAQCommand(_ context.Context, r Responder, i *discordgo.InteractionCreate) string {
+	lang := replyLanguage(i, "")
+	reply := func(content string) {
+		r.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+	}
+
+	if h.faq == nil {
+		reply(i18n.T(lang, i18n.MsgFAQUnavailable))
+		return outcomeError
+	}
+
+	data := i.ApplicationCommandData()
+	if len(data.Options) == 0 {
+		reply(i18n.T(lang, i18n.MsgFAQNoSubcommand))
+		return outcomeInvalid
+	}
+	sub := data.Options[0]
+
+	switch sub.Name {
+	case "add":
+		r.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseModal,
+			Data: &discordgo.InteractionResponseData{
+				CustomID:   faqAddModalID,
+				Title:      i18n.T(lang, i18n.MsgFAQModalTitle),
+				Components: faqAddModalComponents(lang),
+			},
+		})
+		return outcomeSuccess
+
+	case "list":
+		entries, err := h.faq.List(i.GuildID)
+		if err != nil {
+			h.logger.Error("failed to list faq entries", "error", err)
+			reply(i18n.T(lang, i18n.MsgFAQListFailed))
+			return outcomeError
+		}
+		if len(entries) == 0 {
+			reply(i18n.T(lang, i18n.MsgFAQListEmpty))
+			return outcomeSuccess
+		}
+
+		embed := &discordgo.MessageEmbed{
+			Title: i18n.T(lang, i18n.MsgFAQListTitle),
+			Color: 0x2D6A4F, // Forest green from brand palette
+		}
+		for idx, e := range entries {
+			if idx == 25 {
+				embed.Footer = &discordgo.MessageEmbedFooter{Text: i18n.T(lang, i18n.MsgFAQListTruncated, len(entries))}
+				break
+			}
+			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+				Name:  truncate(fmt.Sprintf("#%d %s", e.ID, e.Question), 256),
+				Value: truncate(fmt.Sprintf("%s\n*%s*", truncate(e.Answer, 200), i18n.T(lang, i18n.MsgFAQListVariants, len(e.Variants))), 1024),
+			})
+		}
+		r.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Embeds: []*discordgo.MessageEmbed{embed},
+				Flags:  discordgo.MessageFlagsEphemeral,
+			},
+		})
+		return outcomeSuccess
+
+	case "remove":
+		if len(sub.Options) == 0 {
+			reply(i18n.T(lang, i18n.MsgFAQRemoveNoID))
+			return outcomeInvalid
+		}
+		id := sub.Options[0].IntValue()
+		if err := h.faq.Remove(i.GuildID, uint(id)); err != nil {
+			if errors.Is(err, services.ErrFAQEntryNotFound) {
+				reply(i18n.T(lang, i18n.MsgFAQRemoveNotFound, id))
+				return outcomeInvalid
+			}
+			h.logger.Error("failed to remove faq entry", "error", err, "faq_id", id)
+			reply(i18n.T(lang, i18n.MsgFAQRemoveFailed))
+			return outcomeError
+		}
+		reply(i18n.T(lang, i18n.MsgFAQRemoved, id))
+		return outcomeSuccess
+
+	default:
+		reply(i18n.T(lang, i18n.MsgFAQUnknownSubcommand))
+		return outcomeInvalid
+	}
+}
+
+func faqAddModalComponents(lang language.Language) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.TextInput{
+				CustomID:  "question",
+				Label:     i18n.T(lang, i18n.MsgFAQModalQuestion),
+				Style:     discordgo.TextInputShort,
+				Required:  true,
+				MaxLength: 200,
+			},
+		}},
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.TextInput{
+				CustomID:    "variants",
+				Label:       i18n.T(lang, i18n.MsgFAQModalVariants),
+				Style:       discordgo.TextInputParagraph,
+				Placeholder: "where can I download the mod\nhow do I get Living Lands",
+				MaxLength:   2000,
+			},
+		}},
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.TextInput{
+				CustomID:  "answer",
+				Label:     i18n.T(lang, i18n.MsgFAQModalAnswer),
+				Style:     discordgo.TextInputParagraph,
+				Required:  true,
+				MaxLength: 1900,
+			},
+		}},
+	}
+}
+
+// modalValues collects text input values from a modal submission by custom ID.
+func modalValues(components []discordgo.MessageComponent) map[string]string {
+	values := make(map[string]string)
+	for _, c := range components {
+		var row []discordgo.MessageComponent
+		switch r := c.(type) {
+		case *discordgo.ActionsRow:
+			row = r.Components
+		case discordgo.ActionsRow:
+			row = r.Components
+		}
+		for _, inner := range row {
+			switch input := inner.(type) {
+			case *discordgo.TextInput:
+				values[input.CustomID] = input.Value
+			case discordgo.TextInput:
+				values[input.CustomID] = input.Value
+			}
+		}
+	}
+	return values
+}
+
+func (h *CommandHandlers) handleModalSubmit(ctx context.Context, r Responder, i *discordgo.InteractionCreate) {
+	data := i.ModalSubmitData()
+	if data.CustomID != faqAddModalID {
+		h.logger.Warn("unknown modal submitted", "custom_id", data.CustomID)
+		return
+	}
+	lang := replyLanguage(i, "")
+
+	if h.faq == nil {
+		r.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: i18n.T(lang, i18n.MsgFAQUnavailable),
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		return
+	}
+	// The modal comes from /faq add, so it is held to the same rules
+	faq, _ := h.commands.Lookup("faq")
+	switch reason, err := h.authorize(i, faq, "faq add"); {
+	case err != nil:
+		h.logger.Error("access check failed", "command", "faq add", "error", err)
+		respondDenied(r, i, i18n.MsgAccessCheckFailed)
+		return
+	case reason != allowed:
+		respondDenied(r, i, reason.message())
+		return
+	}
+
+	// Embedding every variant can take longer than Discord's 3 second window
+	r.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	})
+
+	values := modalValues(data.Components)
+	var variants []string
+	for _, line := range strings.Split(values["variants"], "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			variants = append(variants, line)
+		}
+	}
+
+	var content string
+	entry, err := h.faq.Add(ctx, i.GuildID, values["question"], variants, values["answer"], i.Member.User.ID)
+	if err != nil {
+		h.logger.Error("failed to add faq entry", "error", err)
+		content = i18n.T(lang, i18n.MsgFAQSaveFailed)
+	} else {
+		content = i18n.T(lang, i18n.MsgFAQSaved, entry.ID, len(entry.Variants))
+	}
+
+	if _, err := r.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: content,
+		Flags:   discordgo.MessageFlagsEphemeral,
+	}); err != nil {
+		h.logger.Error("failed to send faq followup", "error", err)
+	}
+}
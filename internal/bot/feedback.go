@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"living-lands-bot/internal/database/models"
+	"living-lands-bot/internal/i18n"
+	"living-lands-bot/internal/services"
+	"living-lands-bot/pkg/language"
+)
+
+// feedbackPrefix starts the custom ID of answer feedback buttons:
+// "qa_<rating>:<qa interaction id>", e.g. "qa_up:42".
+const feedbackPrefix = "qa_"
+
+// logAnswer stores an /ask answer in the Q&A log and returns the feedback
+// buttons to attach to it. It returns nil if logging is disabled or failed,
+// since buttons without a logged answer would have nothing to rate.
+func (h *CommandHandlers) logAnswer(i *discordgo.InteractionCreate, userID, username, question string, intent services.QueryIntent, results []services.RAGResult, answer *services.Answer, start time.Time) []discordgo.MessageComponent {
+	if h.qa == nil {
+		return nil
+	}
+
+	retrieved := make(models.RetrievedChunks, len(results))
+	for idx, res := range results {
+		retrieved[idx] = models.RetrievedChunk{ID: res.ID, Distance: res.Distance, Source: res.Source}
+	}
+
+	qa := &models.QAInteraction{
+		InteractionID:   i.ID,
+		DiscordID:       userID,
+		DiscordUsername: username,
+		GuildID:         i.GuildID,
+		ChannelID:       i.ChannelID,
+		Question:        question,
+		Intent:          intent.String(),
+		Mode:            answer.Metrics.Mode.String(),
+		Retrieved:       retrieved,
+		Answer:          answer.Text,
+		Model:           answer.Metrics.Model,
+		LatencyMs:       time.Since(start).Milliseconds(),
+	}
+	if err := h.qa.RecordInteraction(qa); err != nil {
+		h.logger.Error("failed to log answer", "error", err, "interaction_id", i.ID)
+		return nil
+	}
+
+	return feedbackButtons(replyLanguage(i, question), qa.ID)
+}
+
+// feedbackButtons builds the 👍/👎/report row for a logged answer.
+func feedbackButtons(lang language.Language, qaID uint) []discordgo.MessageComponent {
+	id := strconv.FormatUint(uint64(qaID), 10)
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Emoji:    &discordgo.ComponentEmoji{Name: "👍"},
+					Style:    discordgo.SecondaryButton,
+					CustomID: feedbackPrefix + models.RatingUp + ":" + id,
+				},
+				discordgo.Button{
+					Emoji:    &discordgo.ComponentEmoji{Name: "👎"},
+					Style:    discordgo.SecondaryButton,
+					CustomID: feedbackPrefix + models.RatingDown + ":" + id,
+				},
+				discordgo.Button{
+					Label:    i18n.T(lang, i18n.MsgFeedbackReport),
+					Style:    discordgo.DangerButton,
+					CustomID: feedbackPrefix + models.RatingReport + ":" + id,
+				},
+			},
+		},
+	}
+}
+
+// parseFeedbackID splits a feedback custom ID into rating and answer ID.
+func parseFeedbackID(customID string) (string, uint, bool) {
+	rating, idStr, ok := strings.Cut(strings.TrimPrefix(customID, feedbackPrefix), ":")
+	if !ok {
+		return "", 0, false
+	}
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil || id == 0 {
+		return "", 0, false
+	}
+	return rating, uint(id), true
+}
+
+func (h *CommandHandlers) handleFeedbackComponent(r Responder, i *discordgo.InteractionCreate, customID string) {
+	lang := replyLanguage(i, "")
+	reply := func(id i18n.MessageID) {
+		r.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: i18n.T(lang, id),
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+	}
+
+	rating, qaID, ok := parseFeedbackID(customID)
+	if !ok || h.qa == nil {
+		h.logger.Warn("invalid feedback component", "custom_id", customID)
+		reply(i18n.MsgFeedbackUnavailable)
+		return
+	}
+
+	var userID string
+	if i.Member != nil && i.Member.User != nil {
+		userID = i.Member.User.ID
+	} else if i.User != nil {
+		userID = i.User.ID
+	}
+	if userID == "" {
+		reply(i18n.MsgUnknownUser)
+		return
+	}
+
+	if err := h.qa.RecordFeedback(i.GuildID, qaID, userID, rating); err != nil {
+		if errors.Is(err, services.ErrQAInteractionNotFound) {
+			reply(i18n.MsgFeedbackNotFound)
+			return
+		}
+		h.logger.Error("failed to record feedback", "error", err, "qa_id", qaID, "rating", rating)
+		reply(i18n.MsgFeedbackFailed)
+		return
+	}
+
+	switch rating {
+	case models.RatingReport:
+		reply(i18n.MsgFeedbackReported)
+	default:
+		reply(i18n.MsgFeedbackThanks)
+	}
+}
@@ -0,0 +1,183 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+
+	"living-lands-bot/internal/database/models"
+	"living-lands-bot/internal/services"
+)
+
+const (
+	// gapRecordTimeout bounds embedding and storing a captured gap.
+	gapRecordTimeout = 15 * time.Second
+	// gapDigestCheckInterval is how often the digest loop checks whether a digest is due.
+	gapDigestCheckInterval = time.Hour
+
+	// Discord rejects embeds with more than 25 fields or 6000 characters of
+	// text in total (title, description, field names and values, footer)
+	embedMaxFields = 25
+	embedMaxChars  = 6000
+	// gapDigestFooterRoom is kept free for the "more topics" footer
+	gapDigestFooterRoom = 100
+)
+
+// gapReason returns why an /ask answer counts as a documentation gap, or ""
+// if it does not. A failed RAG query is an outage rather than a gap, so only
+// a query that succeeded but kept no documents counts.
+func gapReason(ragSucceeded bool, results []services.RAGResult, answer *services.Answer) string {
+	switch {
+	case ragSucceeded && len(results) == 0:
+		return models.GapNoContext
+	case answer != nil && answer.NoAnswer:
+		return models.GapNoAnswer
+	default:
+		return ""
+	}
+}
+
+// recordGap stores an unanswered question in the background so embedding it
+// does not delay the reply.
+func (h *CommandHandlers) recordGap(i *discordgo.InteractionCreate, userID, question, reason string) {
+	if h.gaps == nil {
+		return
+	}
+
+	gap := &models.DocGap{
+		InteractionID: i.ID,
+		DiscordID:     userID,
+		GuildID:       i.GuildID,
+		Question:      question,
+		Reason:        reason,
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), gapRecordTimeout)
+		defer cancel()
+		if err := h.gaps.Record(ctx, gap); err != nil {
+			h.logger.Error("failed to record documentation gap", "error", err, "interaction_id", gap.InteractionID)
+		}
+	}()
+}
+
+// embedSender is the part of the Discord session used to post the digest.
+type embedSender interface {
+	ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed, options ...discordgo.RequestOption) (*discordgo.Message, error)
+}
+
+// RunGapDigest posts the documentation-gap digest to the staff channel every
+// GAPS_DIGEST_INTERVAL hours until ctx is cancelled. Posted digests are kept
+// in the database, so restarts neither repeat nor skip one. It returns
+// immediately if no digest channel is configured.
+func (b *Bot) RunGapDigest(ctx context.Context) {
+	channelID := b.config.Gaps.DigestChannelID
+	if b.gaps == nil || channelID == "" {
+		return
+	}
+
+	// The digest only covers the guild the staff channel belongs to
+	guildID := b.config.Discord.GuildID
+	if channel, err := b.session.Channel(channelID); err != nil {
+		b.logger.Warn("failed to resolve gap digest guild, using DISCORD_GUILD_ID", "error", err, "channel_id", channelID)
+	} else {
+		guildID = channel.GuildID
+	}
+
+	interval := time.Duration(b.config.Gaps.DigestInterval) * time.Hour
+	b.logger.Info("gap digest enabled", "channel_id", channelID, "guild_id", guildID, "interval_hours", b.config.Gaps.DigestInterval)
+
+	ticker := time.NewTicker(gapDigestCheckInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := postGapDigestIfDue(ctx, b.session, b.gaps, guildID, channelID, interval, b.config.Gaps.DigestTopics, time.Now()); err != nil {
+			b.logger.Error("gap digest failed", "error", err, "channel_id", channelID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// postGapDigestIfDue posts a digest of the guild's gaps since the previous
+// one (or the last interval, for the first digest) once interval has passed.
+func postGapDigestIfDue(ctx context.Context, sender embedSender, gaps *services.GapService, guildID, channelID string, interval time.Duration, topics int, now time.Time) (bool, error) {
+	last, err := gaps.LastDigest(channelID)
+	if err != nil {
+		return false, err
+	}
+	if !last.IsZero() && now.Sub(last) < interval {
+		return false, nil
+	}
+
+	since := now.Add(-interval)
+	if !last.IsZero() {
+		since = last
+	}
+
+	clusters, err := gaps.Report(ctx, guildID, since, topics)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := sender.ChannelMessageSendEmbed(channelID, gapDigestEmbed(clusters, since)); err != nil {
+		return false, fmt.Errorf("failed to post gap digest: %w", err)
+	}
+	if err := gaps.RecordDigest(channelID, now); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// gapDigestEmbed lists the most-asked unanswered topics with example
+// questions. Topics that would push the embed past Discord's size limits are
+// left out and counted in the footer, so the digest is never rejected.
+func gapDigestEmbed(clusters []services.GapCluster, since time.Time) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title: "📚 Documentation Gaps",
+		Color: 0x2D6A4F, // Forest green from brand palette
+	}
+
+	if len(clusters) == 0 {
+		embed.Description = fmt.Sprintf("No unanswered questions since <t:%d:D>. The archives held up well!", since.Unix())
+		return embed
+	}
+
+	embed.Description = fmt.Sprintf("Questions the archives could not answer since <t:%d:D>, most asked first.", since.Unix())
+	size := utf8.RuneCountInString(embed.Title) + utf8.RuneCountInString(embed.Description)
+	for idx, c := range clusters {
+		var examples strings.Builder
+		for _, q := range c.Examples {
+			examples.WriteString("• " + truncate(q, 200) + "\n")
+		}
+		field := &discordgo.MessageEmbedField{
+			Name:  truncate(fmt.Sprintf("%d. %s (asked %d×)", idx+1, c.Topic, c.Count), 256),
+			Value: truncate(examples.String(), 1024),
+		}
+		fieldSize := utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+		if len(embed.Fields) == embedMaxFields || size+fieldSize > embedMaxChars-gapDigestFooterRoom {
+			embed.Footer = &discordgo.MessageEmbedFooter{
+				Text: fmt.Sprintf("+%d more topics, see ./bot gaps for the full report", len(clusters)-idx),
+			}
+			break
+		}
+		embed.Fields = append(embed.Fields, field)
+		size += fieldSize
+	}
+	return embed
+}
+
+// truncate shortens s to at most limit runes, marking the cut with an ellipsis.
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit-1]) + "…"
+}
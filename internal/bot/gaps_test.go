@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"living-lands-bot/internal/database/models"
+	"living-lands-bot/internal/services"
+	"living-lands-bot/internal/testutil"
+)
+
+// fakeEmbedSender records embeds posted to channels.
+type fakeEmbedSender struct {
+	err    error
+	embeds []*discordgo.MessageEmbed
+}
+
+func (f *fakeEmbedSender) ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.embeds = append(f.embeds, embed)
+	return &discordgo.Message{ChannelID: channelID}, nil
+}
+
+func TestGapReason(t *testing.T) {
+	answered := &services.Answer{Text: "Hunger drains over time."}
+	unanswered := &services.Answer{Text: "Dazu habe ich keine Informationen in meinen Archiven.", NoAnswer: true}
+	results := []services.RAGResult{{ID: "metabolism"}}
+
+	assert.Equal(t, models.GapNoContext, gapReason(true, nil, answered))
+	assert.Equal(t, models.GapNoAnswer, gapReason(true, results, unanswered))
+	assert.Equal(t, models.GapNoAnswer, gapReason(false, nil, unanswered))
+	assert.Equal(t, "", gapReason(true, results, answered))
+	assert.Equal(t, "", gapReason(false, nil, nil), "a failed RAG query is an outage, not a gap")
+}
+
+func TestPostGapDigestIfDue(t *testing.T) {
+	db := testutil.NewTestDB(t, &models.DocGap{}, &models.GapDigest{})
+	gaps := services.NewGapService(db, nil, "", getTestLogger())
+	ctx := context.Background()
+	week := 7 * 24 * time.Hour
+
+	require.NoError(t, gaps.Record(ctx, &models.DocGap{GuildID: testGuildID, Question: "how do I tame a wolf", Reason: models.GapNoContext}))
+	require.NoError(t, gaps.Record(ctx, &models.DocGap{GuildID: testGuildID, Question: "How do I tame a wolf?", Reason: models.GapNoAnswer}))
+	require.NoError(t, gaps.Record(ctx, &models.DocGap{GuildID: "1000000000000000001", Question: "can I ride horses", Reason: models.GapNoContext}))
+
+	sender := &fakeEmbedSender{}
+	now := time.Now()
+
+	posted, err := postGapDigestIfDue(ctx, sender, gaps, testGuildID, "staff", week, 10, now)
+	require.NoError(t, err)
+	assert.True(t, posted, "first digest should post immediately")
+	require.Len(t, sender.embeds, 1)
+	require.Len(t, sender.embeds[0].Fields, 1)
+	assert.Equal(t, "1. how do I tame a wolf (asked 2×)", sender.embeds[0].Fields[0].Name)
+
+	posted, err = postGapDigestIfDue(ctx, sender, gaps, testGuildID, "staff", week, 10, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.False(t, posted, "digest is not due until the interval has passed")
+
+	posted, err = postGapDigestIfDue(ctx, sender, gaps, testGuildID, "staff", week, 10, now.Add(week))
+	require.NoError(t, err)
+	assert.True(t, posted)
+	require.Len(t, sender.embeds, 2)
+	assert.Empty(t, sender.embeds[1].Fields, "gaps from before the previous digest are not repeated")
+	assert.Contains(t, sender.embeds[1].Description, "No unanswered questions")
+
+	// A failed post is retried on the next check
+	sender.err = errors.New("discord unavailable")
+	posted, err = postGapDigestIfDue(ctx, sender, gaps, testGuildID, "staff", week, 10, now.Add(2*week))
+	assert.Error(t, err)
+	assert.False(t, posted)
+	last, err := gaps.LastDigest("staff")
+	require.NoError(t, err)
+	assert.WithinDuration(t, now.Add(week), last, time.Second)
+}
+
+func TestGapDigestEmbedStaysWithinDiscordLimits(t *testing.T) {
+	clusters := make([]services.GapCluster, 25)
+	for i := range clusters {
+		clusters[i] = services.GapCluster{
+			Topic:    fmt.Sprintf("%02d %s", i, strings.Repeat("why does my wolf companion ", 12)),
+			Count:    100 - i,
+			Examples: []string{strings.Repeat("a", 300), strings.Repeat("b", 300), strings.Repeat("c", 300)},
+		}
+	}
+
+	embed := gapDigestEmbed(clusters, time.Now())
+
+	size := utf8.RuneCountInString(embed.Title) + utf8.RuneCountInString(embed.Description)
+	for _, f := range embed.Fields {
+		assert.LessOrEqual(t, utf8.RuneCountInString(f.Name), 256)
+		assert.LessOrEqual(t, utf8.RuneCountInString(f.Value), 1024)
+		size += utf8.RuneCountInString(f.Name) + utf8.RuneCountInString(f.Value)
+	}
+	require.NotNil(t, embed.Footer)
+	size += utf8.RuneCountInString(embed.Footer.Text)
+
+	assert.LessOrEqual(t, size, embedMaxChars)
+	assert.NotEmpty(t, embed.Fields)
+	assert.Less(t, len(embed.Fields), len(clusters))
+	assert.Contains(t, embed.Footer.Text, fmt.Sprintf("+%d more topics", len(clusters)-len(embed.Fields)))
+	assert.True(t, strings.HasPrefix(embed.Fields[0].Name, "1. 00 "), "the most-asked topics are kept")
+}
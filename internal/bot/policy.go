@@ -0,0 +1,263 @@
+package bot
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"gopkg.in/yaml.v3"
+
+	"living-lands-bot/internal/i18n"
+)
+
+// Requirement is what a member needs to use a command or subcommand. Every
+// field that is set must be satisfied.
+type Requirement struct {
+	// Permissions the member must all have in the channel. Administrator
+	// satisfies any permission.
+	Permissions int64
+	// Roles lists role IDs; the member needs at least one of them.
+	Roles []string
+	// Channels lists the channel IDs the command may be used in.
+	Channels []string
+	// Linked requires a Hytale account linked with /link.
+	Linked bool
+}
+
+// Reasons a member is denied a command, in the order they are checked.
+type denial int
+
+const (
+	allowed denial = iota
+	deniedChannel
+	deniedPermission
+	deniedRole
+	deniedLinked
+)
+
+// message returns the catalogue message shown for the denial.
+func (d denial) message() i18n.MessageID {
+	switch d {
+	case deniedChannel:
+		return i18n.MsgAccessDeniedChannel
+	case deniedRole:
+		return i18n.MsgAccessDeniedRole
+	case deniedLinked:
+		return i18n.MsgAccessDeniedLinked
+	default:
+		return i18n.MsgAccessDeniedPermission
+	}
+}
+
+func (d denial) String() string {
+	switch d {
+	case allowed:
+		return "allowed"
+	case deniedChannel:
+		return "channel"
+	case deniedPermission:
+		return "permission"
+	case deniedRole:
+		return "role"
+	case deniedLinked:
+		return "linked"
+	default:
+		return "unknown"
+	}
+}
+
+// check evaluates everything but Linked, which needs the account store.
+// member is nil for commands used in DMs, which can satisfy neither
+// permissions nor roles.
+func (r Requirement) check(member *discordgo.Member, channelID string) denial {
+	if len(r.Channels) > 0 && !slices.Contains(r.Channels, channelID) {
+		return deniedChannel
+	}
+	if r.Permissions != 0 {
+		if member == nil {
+			return deniedPermission
+		}
+		if member.Permissions&discordgo.PermissionAdministrator == 0 && member.Permissions&r.Permissions != r.Permissions {
+			return deniedPermission
+		}
+	}
+	if len(r.Roles) > 0 {
+		if member == nil || !slices.ContainsFunc(member.Roles, func(role string) bool { return slices.Contains(r.Roles, role) }) {
+			return deniedRole
+		}
+	}
+	return allowed
+}
+
+// AccessPolicy maps command paths to requirements. A path is a command name
+// ("ask") or a command and its subcommand ("faq remove"); a member must meet
+// the requirements of both.
+type AccessPolicy struct {
+	rules map[string]Requirement
+}
+
+// NewAccessPolicy returns a policy with the given rules.
+func NewAccessPolicy(rules map[string]Requirement) *AccessPolicy {
+	return &AccessPolicy{rules: rules}
+}
+
+// requirements returns the requirements of path and of the commands it
+// belongs to, outermost first.
+func (p *AccessPolicy) requirements(path string) []Requirement {
+	if p == nil {
+		return nil
+	}
+	var reqs []Requirement
+	fields := strings.Fields(path)
+	for n := 1; n <= len(fields); n++ {
+		if req, ok := p.rules[strings.Join(fields[:n], " ")]; ok {
+			reqs = append(reqs, req)
+		}
+	}
+	return reqs
+}
+
+// Permissions returns the permissions the policy requires for the top-level
+// command name, for use as its DefaultMemberPermissions.
+func (p *AccessPolicy) Permissions(name string) int64 {
+	if p == nil {
+		return 0
+	}
+	return p.rules[name].Permissions
+}
+
+// permissionNames are the permission names accepted in policy files.
+var permissionNames = map[string]int64{
+	"administrator":    discordgo.PermissionAdministrator,
+	"manage_guild":     discordgo.PermissionManageGuild,
+	"manage_channels":  discordgo.PermissionManageChannels,
+	"manage_roles":     discordgo.PermissionManageRoles,
+	"manage_messages":  discordgo.PermissionManageMessages,
+	"kick_members":     discordgo.PermissionKickMembers,
+	"ban_members":      discordgo.PermissionBanMembers,
+	"moderate_members": discordgo.PermissionModerateMembers,
+	"mention_everyone": discordgo.PermissionMentionEveryone,
+	"send_messages":    discordgo.PermissionSendMessages,
+}
+
+// policyRule is a rule as written in the policy file.
+type policyRule struct {
+	Permissions []string `yaml:"permissions"`
+	Roles       []string `yaml:"roles"`
+	Channels    []string `yaml:"channels"`
+	Linked      bool     `yaml:"linked"`
+}
+
+// LoadAccessPolicy reads a policy file mapping command paths to rules. Paths
+// must name registered commands and subcommands.
+func LoadAccessPolicy(filePath string) (*AccessPolicy, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read access policy file: %w", err)
+	}
+
+	var raw map[string]policyRule
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse access policy yaml: %w", err)
+	}
+
+	rules := make(map[string]Requirement, len(raw))
+	for path, rule := range raw {
+		path = strings.Join(strings.Fields(path), " ")
+		if !commands.hasPath(path) {
+			return nil, fmt.Errorf("invalid access policy file: unknown command %q", path)
+		}
+
+		req := Requirement{Roles: rule.Roles, Channels: rule.Channels, Linked: rule.Linked}
+		for _, name := range rule.Permissions {
+			bit, ok := permissionNames[strings.ToLower(name)]
+			if !ok {
+				return nil, fmt.Errorf("invalid access policy file: unknown permission %q for %q (known: %s)", name, path, knownPermissions())
+			}
+			req.Permissions |= bit
+		}
+		rules[path] = req
+	}
+	return NewAccessPolicy(rules), nil
+}
+
+func knownPermissions() string {
+	names := make([]string, 0, len(permissionNames))
+	for name := range permissionNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// commandPath returns the policy path of an invocation: the command name
+// followed by the subcommand group and subcommand, if any.
+func commandPath(data discordgo.ApplicationCommandInteractionData) string {
+	parts := []string{data.Name}
+	options := data.Options
+	for len(options) > 0 {
+		opt := options[0]
+		if opt.Type != discordgo.ApplicationCommandOptionSubCommand && opt.Type != discordgo.ApplicationCommandOptionSubCommandGroup {
+			break
+		}
+		parts = append(parts, opt.Name)
+		options = opt.Options
+	}
+	return strings.Join(parts, " ")
+}
+
+// authorize checks the invoking member against the permissions built into
+// cmd and the policy for path. Discord already hides commands from members
+// without their default permissions, but server admins can override command
+// permissions, so they are checked again here. Errors mean account linking
+// could not be checked.
+func (h *CommandHandlers) authorize(i *discordgo.InteractionCreate, cmd Command, path string) (denial, error) {
+	reqs := append([]Requirement{{Permissions: cmd.Permissions}}, h.policy.requirements(path)...)
+
+	needsLink := false
+	for _, req := range reqs {
+		if reason := req.check(i.Member, i.ChannelID); reason != allowed {
+			return reason, nil
+		}
+		needsLink = needsLink || req.Linked
+	}
+	if !needsLink {
+		return allowed, nil
+	}
+
+	var userID string
+	if i.Member != nil && i.Member.User != nil {
+		userID = i.Member.User.ID
+	} else if i.User != nil {
+		userID = i.User.ID
+	}
+	if userID == "" {
+		return deniedLinked, nil
+	}
+	if h.account == nil {
+		return allowed, fmt.Errorf("account service unavailable")
+	}
+	linked, err := h.account.IsLinked(i.GuildID, userID)
+	if err != nil {
+		return allowed, err
+	}
+	if !linked {
+		return deniedLinked, nil
+	}
+	return allowed, nil
+}
+
+// respondDenied answers an interaction the member may not use with the
+// ephemeral message id, so every denial looks the same.
+func respondDenied(r Responder, i *discordgo.InteractionCreate, id i18n.MessageID) {
+	r.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: i18n.T(replyLanguage(i, ""), id),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
@@ -0,0 +1,175 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"living-lands-bot/internal/database/models"
+	"living-lands-bot/internal/i18n"
+	"living-lands-bot/internal/services"
+	"living-lands-bot/internal/testutil"
+	"living-lands-bot/pkg/language"
+)
+
+func writePolicy(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "access.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadAccessPolicy(t *testing.T) {
+	policy, err := LoadAccessPolicy("../../configs/access.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, int64(discordgo.PermissionManageGuild), policy.Permissions("faq"))
+
+	policy, err = LoadAccessPolicy(writePolicy(t, `
+ask:
+  linked: true
+  channels: ["100"]
+"faq  remove":
+  permissions: [Manage_Messages]
+  roles: ["500"]
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []Requirement{{Channels: []string{"100"}, Linked: true}}, policy.requirements("ask"))
+	assert.Equal(t, []Requirement{{Permissions: discordgo.PermissionManageMessages, Roles: []string{"500"}}}, policy.requirements("faq remove"))
+	assert.Empty(t, policy.requirements("faq list"))
+
+	_, err = LoadAccessPolicy(writePolicy(t, "ask remove:\n  linked: true\n"))
+	assert.ErrorContains(t, err, `unknown command "ask remove"`)
+	_, err = LoadAccessPolicy(writePolicy(t, "faq:\n  permissions: [be_nice]\n"))
+	assert.ErrorContains(t, err, `unknown permission "be_nice"`)
+	_, err = LoadAccessPolicy(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestRequirementCheck(t *testing.T) {
+	member := func(permissions int64, roles ...string) *discordgo.Member {
+		return &discordgo.Member{User: &discordgo.User{ID: "1"}, Permissions: permissions, Roles: roles}
+	}
+
+	tests := []struct {
+		name    string
+		req     Requirement
+		member  *discordgo.Member
+		channel string
+		want    denial
+	}{
+		{"no requirement", Requirement{}, nil, "100", allowed},
+		{"permission held", Requirement{Permissions: discordgo.PermissionManageGuild}, member(discordgo.PermissionManageGuild), "100", allowed},
+		{"permission missing", Requirement{Permissions: discordgo.PermissionManageGuild}, member(discordgo.PermissionSendMessages), "100", deniedPermission},
+		{"all permissions needed", Requirement{Permissions: discordgo.PermissionManageGuild | discordgo.PermissionManageMessages}, member(discordgo.PermissionManageGuild), "100", deniedPermission},
+		{"administrator passes", Requirement{Permissions: discordgo.PermissionManageGuild}, member(discordgo.PermissionAdministrator), "100", allowed},
+		{"permission in DMs", Requirement{Permissions: discordgo.PermissionManageGuild}, nil, "100", deniedPermission},
+		{"one of the roles", Requirement{Roles: []string{"500", "501"}}, member(0, "400", "501"), "100", allowed},
+		{"role missing", Requirement{Roles: []string{"500"}}, member(discordgo.PermissionAdministrator, "400"), "100", deniedRole},
+		{"allowed channel", Requirement{Channels: []string{"100"}}, member(0), "100", allowed},
+		{"other channel", Requirement{Channels: []string{"100"}}, member(discordgo.PermissionAdministrator), "200", deniedChannel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.req.check(tt.member, tt.channel))
+		})
+	}
+}
+
+func TestCommandPath(t *testing.T) {
+	assert.Equal(t, "ask", commandPath(discordgo.ApplicationCommandInteractionData{
+		Name: "ask",
+		Options: []*discordgo.ApplicationCommandInteractionDataOption{
+			{Name: "question", Type: discordgo.ApplicationCommandOptionString, Value: "hi"},
+		},
+	}))
+	assert.Equal(t, "faq remove", commandPath(discordgo.ApplicationCommandInteractionData{
+		Name: "faq",
+		Options: []*discordgo.ApplicationCommandInteractionDataOption{
+			{Name: "remove", Type: discordgo.ApplicationCommandOptionSubCommand, Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "id", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(1)},
+			}},
+		},
+	}))
+}
+
+func TestCommandRegistryWithPolicy(t *testing.T) {
+	policy := NewAccessPolicy(map[string]Requirement{
+		"ask": {Permissions: discordgo.PermissionSendMessages, Linked: true},
+		"faq": {Permissions: discordgo.PermissionManageMessages},
+	})
+	reg := commands.WithPolicy(policy)
+
+	ask, _ := reg.Lookup("ask")
+	require.NotNil(t, ask.Definition.DefaultMemberPermissions)
+	assert.Equal(t, int64(discordgo.PermissionSendMessages), *ask.Definition.DefaultMemberPermissions)
+	faq, _ := reg.Lookup("faq")
+	assert.Equal(t, int64(discordgo.PermissionManageGuild|discordgo.PermissionManageMessages), *faq.Definition.DefaultMemberPermissions)
+
+	// The shared registry is left untouched
+	ask, _ = commands.Lookup("ask")
+	assert.Nil(t, ask.Definition.DefaultMemberPermissions)
+	guide, _ := reg.Lookup("guide")
+	assert.Nil(t, guide.Definition.DefaultMemberPermissions)
+}
+
+// withPolicy makes h enforce policy, with account linking backed by a test
+// database.
+func withPolicy(t *testing.T, h *CommandHandlers, policy *AccessPolicy) *services.AccountService {
+	t.Helper()
+	db := testutil.NewTestDB(t, &models.User{})
+	h.account = services.NewAccountService(db, 600, getTestLogger())
+	h.policy = policy
+	h.commands = commands.WithPolicy(policy)
+	return h.account
+}
+
+func TestCommandDeniedByPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  map[string]Requirement
+		fixture string
+		want    i18n.MessageID
+	}{
+		{"wrong channel", map[string]Requirement{"ask": {Channels: []string{"1000000000000000999"}}}, "testdata/ask_navigation.json", i18n.MsgAccessDeniedChannel},
+		{"missing role", map[string]Requirement{"guide": {Roles: []string{"1000000000000000500"}}}, "testdata/guide.json", i18n.MsgAccessDeniedRole},
+		{"not linked", map[string]Requirement{"ask": {Linked: true}}, "testdata/ask_navigation.json", i18n.MsgAccessDeniedLinked},
+		{"subcommand rule", map[string]Requirement{"faq list": {Roles: []string{"1000000000000000500"}}}, "testdata/faq_list.json", i18n.MsgAccessDeniedRole},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, ollamaServer := newTestHandlers(t)
+			withPolicy(t, h, NewAccessPolicy(tt.policy))
+			rec := testutil.NewInteractionRecorder()
+
+			h.Dispatch(rec, testutil.LoadInteraction(t, tt.fixture))
+
+			responses := rec.Responses()
+			require.Len(t, responses, 1)
+			assert.Equal(t, discordgo.InteractionResponseChannelMessageWithSource, responses[0].Type)
+			assert.Equal(t, discordgo.MessageFlagsEphemeral, responses[0].Data.Flags)
+			assert.Equal(t, i18n.T(language.English, tt.want), responses[0].Data.Content)
+			assert.Empty(t, ollamaServer.ChatRequests())
+		})
+	}
+}
+
+func TestCommandAllowedForLinkedMember(t *testing.T) {
+	h, _ := newTestHandlers(t)
+	account := withPolicy(t, h, NewAccessPolicy(map[string]Requirement{
+		"ask": {Linked: true, Channels: []string{"1000000000000000100"}},
+	}))
+	code, err := account.GenerateVerificationCode("1000000000000000000", "900000000000000001", "wanderer")
+	require.NoError(t, err)
+	require.NoError(t, account.VerifyLink(code, "Wanderer", "uuid-1"))
+	rec := testutil.NewInteractionRecorder()
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/ask_navigation.json"))
+
+	followups := rec.Followups()
+	require.Len(t, followups, 1)
+	assert.Contains(t, followups[0].Content, "`/guide`")
+}
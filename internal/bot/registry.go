@@ -0,0 +1,313 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"living-lands-bot/internal/i18n"
+)
+
+// Command is a slash command: the definition registered with Discord, the
+// handler that answers it and the member permissions it requires by default.
+type Command struct {
+	// Definition uses English names and descriptions; translations are filled
+	// in from the i18n catalogue by NewCommandRegistry.
+	Definition *discordgo.ApplicationCommand
+	// Permissions is the DefaultMemberPermissions bit set. Zero leaves the
+	// command open to everyone.
+	Permissions int64
+	// Handler answers an invocation and returns its outcome for metrics.
+	Handler func(h *CommandHandlers, ctx context.Context, r Responder, i *discordgo.InteractionCreate) string
+}
+
+// CommandRegistry is the declarative set of slash commands the bot serves.
+// It is the single source for registration, drift detection and dispatch.
+type CommandRegistry struct {
+	commands []Command
+	byName   map[string]Command
+}
+
+// NewCommandRegistry builds a registry from cmds, localizing their
+// definitions and applying their permissions. It panics on duplicate names,
+// which is a programming error.
+func NewCommandRegistry(cmds ...Command) *CommandRegistry {
+	reg := &CommandRegistry{byName: make(map[string]Command, len(cmds))}
+	for _, cmd := range cmds {
+		name := cmd.Definition.Name
+		if _, ok := reg.byName[name]; ok {
+			panic(fmt.Sprintf("duplicate command %s", name))
+		}
+
+		localizeCommand(cmd.Definition)
+		if cmd.Permissions != 0 {
+			permissions := cmd.Permissions
+			cmd.Definition.DefaultMemberPermissions = &permissions
+		}
+
+		reg.commands = append(reg.commands, cmd)
+		reg.byName[name] = cmd
+	}
+	return reg
+}
+
+// localizeCommand fills in the name and description localizations of cmd
+// and its options from the catalogue entries command.<path>.*, where path
+// joins the English names, e.g. "faq.remove.id".
+func localizeCommand(cmd *discordgo.ApplicationCommand) {
+	names, descriptions := i18n.CommandLocalizations(cmd.Name)
+	cmd.NameLocalizations = &names
+	cmd.DescriptionLocalizations = &descriptions
+	localizeOptions(cmd.Name, cmd.Options)
+}
+
+func localizeOptions(parent string, options []*discordgo.ApplicationCommandOption) {
+	for _, opt := range options {
+		path := parent + "." + opt.Name
+		opt.NameLocalizations, opt.DescriptionLocalizations = i18n.CommandLocalizations(path)
+		localizeOptions(path, opt.Options)
+	}
+}
+
+// Lookup returns the command with the given name.
+func (reg *CommandRegistry) Lookup(name string) (Command, bool) {
+	cmd, ok := reg.byName[name]
+	return cmd, ok
+}
+
+// WithPolicy returns a copy of the registry whose definitions also require
+// the permissions policy sets for each command, so Discord hides commands
+// from members who could not use them anyway. Roles, channels and account
+// linking cannot be expressed in DefaultMemberPermissions and are only
+// enforced when a command is used.
+func (reg *CommandRegistry) WithPolicy(policy *AccessPolicy) *CommandRegistry {
+	out := &CommandRegistry{byName: make(map[string]Command, len(reg.commands))}
+	for _, cmd := range reg.commands {
+		if permissions := cmd.Permissions | policy.Permissions(cmd.Definition.Name); permissions != cmd.Permissions {
+			def := *cmd.Definition
+			def.DefaultMemberPermissions = &permissions
+			cmd.Definition = &def
+		}
+		out.commands = append(out.commands, cmd)
+		out.byName[cmd.Definition.Name] = cmd
+	}
+	return out
+}
+
+// hasPath reports whether path names a command, or a subcommand (group) of
+// one, such as "faq remove".
+func (reg *CommandRegistry) hasPath(path string) bool {
+	fields := strings.Fields(path)
+	if len(fields) == 0 {
+		return false
+	}
+	cmd, ok := reg.byName[fields[0]]
+	if !ok {
+		return false
+	}
+	options := cmd.Definition.Options
+	for _, name := range fields[1:] {
+		idx := slices.IndexFunc(options, func(opt *discordgo.ApplicationCommandOption) bool {
+			return opt.Name == name && (opt.Type == discordgo.ApplicationCommandOptionSubCommand ||
+				opt.Type == discordgo.ApplicationCommandOptionSubCommandGroup)
+		})
+		if idx < 0 {
+			return false
+		}
+		options = options[idx].Options
+	}
+	return true
+}
+
+// Definitions returns the command definitions in registration order.
+func (reg *CommandRegistry) Definitions() []*discordgo.ApplicationCommand {
+	defs := make([]*discordgo.ApplicationCommand, len(reg.commands))
+	for i, cmd := range reg.commands {
+		defs[i] = cmd.Definition
+	}
+	return defs
+}
+
+// Drift kinds reported by CommandRegistry.Diff.
+const (
+	DriftMissing = "missing" // Declared but not registered
+	DriftChanged = "changed" // Registered with a different definition
+	DriftStale   = "stale"   // Registered but no longer declared
+)
+
+// CommandDrift is a difference between a declared command and what Discord
+// has registered.
+type CommandDrift struct {
+	Name   string
+	Kind   string
+	Fields []string // Differing fields, for DriftChanged
+}
+
+func (d CommandDrift) String() string {
+	if d.Kind == DriftChanged {
+		return fmt.Sprintf("%s: %s %v", d.Name, d.Kind, d.Fields)
+	}
+	return fmt.Sprintf("%s: %s", d.Name, d.Kind)
+}
+
+// Diff compares the declared commands with registered, as returned by
+// Discord, and returns the drift sorted by command name.
+func (reg *CommandRegistry) Diff(registered []*discordgo.ApplicationCommand) []CommandDrift {
+	var drift []CommandDrift
+	seen := make(map[string]bool, len(registered))
+	for _, have := range registered {
+		seen[have.Name] = true
+		cmd, ok := reg.byName[have.Name]
+		if !ok {
+			drift = append(drift, CommandDrift{Name: have.Name, Kind: DriftStale})
+			continue
+		}
+		if fields := commandDiff(cmd.Definition, have); len(fields) > 0 {
+			drift = append(drift, CommandDrift{Name: have.Name, Kind: DriftChanged, Fields: fields})
+		}
+	}
+	for _, cmd := range reg.commands {
+		if !seen[cmd.Definition.Name] {
+			drift = append(drift, CommandDrift{Name: cmd.Definition.Name, Kind: DriftMissing})
+		}
+	}
+
+	sort.Slice(drift, func(a, b int) bool { return drift[a].Name < drift[b].Name })
+	return drift
+}
+
+// commandDiff returns the names of the fields that differ between the
+// declared command want and the registered command have. Discord fills in
+// defaults (the chat input type) and drops empty collections, so those are
+// normalised first.
+func commandDiff(want, have *discordgo.ApplicationCommand) []string {
+	var fields []string
+	if commandType(want) != commandType(have) {
+		fields = append(fields, "type")
+	}
+	if want.Description != have.Description {
+		fields = append(fields, "description")
+	}
+	if !maps.Equal(derefLocalizations(want.NameLocalizations), derefLocalizations(have.NameLocalizations)) {
+		fields = append(fields, "name_localizations")
+	}
+	if !maps.Equal(derefLocalizations(want.DescriptionLocalizations), derefLocalizations(have.DescriptionLocalizations)) {
+		fields = append(fields, "description_localizations")
+	}
+	if !permissionsEqual(want.DefaultMemberPermissions, have.DefaultMemberPermissions) {
+		fields = append(fields, "default_member_permissions")
+	}
+	if !reflect.DeepEqual(normalizeOptions(want.Options), normalizeOptions(have.Options)) {
+		fields = append(fields, "options")
+	}
+	return fields
+}
+
+func commandType(cmd *discordgo.ApplicationCommand) discordgo.ApplicationCommandType {
+	if cmd.Type == 0 {
+		return discordgo.ChatApplicationCommand
+	}
+	return cmd.Type
+}
+
+func derefLocalizations(m *map[discordgo.Locale]string) map[discordgo.Locale]string {
+	if m == nil {
+		return nil
+	}
+	return *m
+}
+
+func permissionsEqual(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// normalizeOptions returns copies of options with empty maps and slices set
+// to nil, so that they compare equal to what Discord returns.
+func normalizeOptions(options []*discordgo.ApplicationCommandOption) []*discordgo.ApplicationCommandOption {
+	if len(options) == 0 {
+		return nil
+	}
+	out := make([]*discordgo.ApplicationCommandOption, len(options))
+	for i, opt := range options {
+		o := *opt
+		if len(o.NameLocalizations) == 0 {
+			o.NameLocalizations = nil
+		}
+		if len(o.DescriptionLocalizations) == 0 {
+			o.DescriptionLocalizations = nil
+		}
+		if len(o.ChannelTypes) == 0 {
+			o.ChannelTypes = nil
+		}
+		if len(o.Choices) == 0 {
+			o.Choices = nil
+		}
+		o.Options = normalizeOptions(o.Options)
+		out[i] = &o
+	}
+	return out
+}
+
+// CommandAPI is the subset of *discordgo.Session used to manage registered
+// commands, so registration can be tested without Discord.
+type CommandAPI interface {
+	ApplicationCommands(appID, guildID string, options ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error)
+	ApplicationCommandBulkOverwrite(appID string, guildID string, commands []*discordgo.ApplicationCommand, options ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error)
+}
+
+var _ CommandAPI = (*discordgo.Session)(nil)
+
+// Sync makes the commands registered for guildID (global commands if it is
+// empty) match the registry with a single bulk overwrite, which creates,
+// updates and deletes as needed and so never leaves duplicates behind. The
+// drift found beforehand is logged and returned; when there is none the
+// overwrite is skipped.
+func (reg *CommandRegistry) Sync(api CommandAPI, appID, guildID string, logger *slog.Logger) ([]CommandDrift, error) {
+	var drift []CommandDrift
+	registered, err := api.ApplicationCommands(appID, guildID)
+	if err != nil {
+		// Overwriting is still safe; only the drift report is lost
+		logger.Warn("failed to fetch registered commands", "guild_id", guildID, "error", err)
+	} else {
+		drift = reg.Diff(registered)
+		if len(drift) == 0 {
+			logger.Info("commands up to date", "guild_id", guildID, "count", len(registered))
+			return nil, nil
+		}
+		for _, d := range drift {
+			logger.Info("command drift", "guild_id", guildID, "command", d.Name, "kind", d.Kind, "fields", d.Fields)
+		}
+	}
+
+	if _, err := api.ApplicationCommandBulkOverwrite(appID, guildID, reg.Definitions()); err != nil {
+		return drift, fmt.Errorf("failed to overwrite commands: %w", err)
+	}
+	logger.Info("commands synced", "guild_id", guildID, "count", len(reg.commands), "drift", len(drift))
+	return drift, nil
+}
+
+// PurgeCommands deletes every command registered for guildID, or every
+// global command if guildID is empty, and returns how many there were.
+func PurgeCommands(api CommandAPI, appID, guildID string) (int, error) {
+	registered, err := api.ApplicationCommands(appID, guildID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch registered commands: %w", err)
+	}
+	if len(registered) == 0 {
+		return 0, nil
+	}
+	if _, err := api.ApplicationCommandBulkOverwrite(appID, guildID, []*discordgo.ApplicationCommand{}); err != nil {
+		return 0, fmt.Errorf("failed to delete commands: %w", err)
+	}
+	return len(registered), nil
+}
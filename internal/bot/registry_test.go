@@ -0,0 +1,159 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCommandAPI keeps registered commands in memory, keyed by guild ID
+// ("" for global commands).
+type fakeCommandAPI struct {
+	registered map[string][]*discordgo.ApplicationCommand
+	fetchErr   error
+	writeErr   error
+	overwrites map[string]int
+}
+
+func newFakeCommandAPI() *fakeCommandAPI {
+	return &fakeCommandAPI{
+		registered: make(map[string][]*discordgo.ApplicationCommand),
+		overwrites: make(map[string]int),
+	}
+}
+
+func (f *fakeCommandAPI) ApplicationCommands(appID, guildID string, options ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error) {
+	if f.fetchErr != nil {
+		return nil, f.fetchErr
+	}
+	return f.registered[guildID], nil
+}
+
+func (f *fakeCommandAPI) ApplicationCommandBulkOverwrite(appID string, guildID string, commands []*discordgo.ApplicationCommand, options ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error) {
+	if f.writeErr != nil {
+		return nil, f.writeErr
+	}
+	f.overwrites[guildID]++
+	f.registered[guildID] = registeredCopy(commands)
+	return f.registered[guildID], nil
+}
+
+// registeredCopy returns commands as Discord would return them: round-tripped
+// through JSON, with IDs and the default type filled in.
+func registeredCopy(commands []*discordgo.ApplicationCommand) []*discordgo.ApplicationCommand {
+	data, err := json.Marshal(commands)
+	if err != nil {
+		panic(err)
+	}
+	var out []*discordgo.ApplicationCommand
+	if err := json.Unmarshal(data, &out); err != nil {
+		panic(err)
+	}
+	for i, cmd := range out {
+		cmd.ID = strconv.Itoa(i + 1)
+		cmd.Type = discordgo.ChatApplicationCommand
+	}
+	return out
+}
+
+func TestCommandRegistry(t *testing.T) {
+	ask, ok := commands.Lookup("ask")
+	require.True(t, ok)
+	assert.Nil(t, ask.Definition.DefaultMemberPermissions)
+	assert.NotNil(t, ask.Handler)
+
+	faq, ok := commands.Lookup("faq")
+	require.True(t, ok)
+	require.NotNil(t, faq.Definition.DefaultMemberPermissions)
+	assert.Equal(t, int64(discordgo.PermissionManageGuild), *faq.Definition.DefaultMemberPermissions)
+
+	_, ok = commands.Lookup("cleanup")
+	assert.False(t, ok)
+
+	var names []string
+	for _, def := range commands.Definitions() {
+		names = append(names, def.Name)
+	}
+	assert.Equal(t, []string{"link", "guide", "ask", "faq", "settings"}, names)
+
+	assert.Panics(t, func() {
+		NewCommandRegistry(
+			Command{Definition: &discordgo.ApplicationCommand{Name: "dup", Description: "a"}},
+			Command{Definition: &discordgo.ApplicationCommand{Name: "dup", Description: "b"}},
+		)
+	})
+}
+
+func TestCommandRegistryDiff(t *testing.T) {
+	registered := registeredCopy(commands.Definitions())
+	assert.Empty(t, commands.Diff(registered), "an unchanged registration has no drift")
+
+	registered = registeredCopy(commands.Definitions())
+	var kept []*discordgo.ApplicationCommand
+	for _, cmd := range registered {
+		switch cmd.Name {
+		case "guide":
+			continue
+		case "ask":
+			cmd.Description = "Old description"
+			cmd.Options[0].Required = false
+		case "faq":
+			cmd.DefaultMemberPermissions = nil
+		}
+		kept = append(kept, cmd)
+	}
+	kept = append(kept, &discordgo.ApplicationCommand{Name: "cleanup", Description: "Removed long ago"})
+
+	assert.Equal(t, []CommandDrift{
+		{Name: "ask", Kind: DriftChanged, Fields: []string{"description", "options"}},
+		{Name: "cleanup", Kind: DriftStale},
+		{Name: "faq", Kind: DriftChanged, Fields: []string{"default_member_permissions"}},
+		{Name: "guide", Kind: DriftMissing},
+	}, commands.Diff(kept))
+}
+
+func TestCommandRegistrySync(t *testing.T) {
+	logger := getTestLogger()
+	api := newFakeCommandAPI()
+
+	drift, err := commands.Sync(api, "app", "guild", logger)
+	require.NoError(t, err)
+	assert.Len(t, drift, len(commands.Definitions()), "every command is missing at first")
+	assert.Equal(t, 1, api.overwrites["guild"])
+	assert.Empty(t, commands.Diff(api.registered["guild"]))
+
+	drift, err = commands.Sync(api, "app", "guild", logger)
+	require.NoError(t, err)
+	assert.Empty(t, drift)
+	assert.Equal(t, 1, api.overwrites["guild"], "no overwrite without drift")
+	assert.Zero(t, api.overwrites[""], "guild sync never touches global commands")
+
+	api.fetchErr = errors.New("unavailable")
+	_, err = commands.Sync(api, "app", "guild", logger)
+	require.NoError(t, err)
+	assert.Equal(t, 2, api.overwrites["guild"], "overwrites when drift cannot be checked")
+
+	api.writeErr = errors.New("forbidden")
+	_, err = commands.Sync(api, "app", "guild", logger)
+	assert.ErrorIs(t, err, api.writeErr)
+}
+
+func TestPurgeCommands(t *testing.T) {
+	api := newFakeCommandAPI()
+	api.registered[""] = registeredCopy(commands.Definitions())
+
+	n, err := PurgeCommands(api, "app", "")
+	require.NoError(t, err)
+	assert.Equal(t, len(commands.Definitions()), n)
+	assert.Empty(t, api.registered[""])
+
+	n, err = PurgeCommands(api, "app", "")
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	assert.Equal(t, 1, api.overwrites[""])
+}
@@ -0,0 +1,237 @@
+package bot
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+
+	"living-lands-bot/internal/services"
+)
+
+// Discord limits, in characters.
+const (
+	messageLimit          = 2000
+	embedDescriptionLimit = 4096
+)
+
+// maxAnswerMessages is how many messages an answer may be split into before
+// it is sent as a Markdown file instead.
+const maxAnswerMessages = 3
+
+// answerFileName is the attachment used for answers too long to split.
+const answerFileName = "answer.md"
+
+// noMentions stops a message from pinging anyone, whatever its text says.
+var noMentions = &discordgo.MessageAllowedMentions{Parse: []discordgo.AllowedMentionType{}}
+
+// renderedMessage is one Discord message of a rendered answer.
+type renderedMessage struct {
+	Content string
+	Embeds  []*discordgo.MessageEmbed
+	Files   []*discordgo.File
+}
+
+// renderAnswer lays out generated text as Discord messages. Mentions are
+// defanged and unclosed code fences closed. An answer with an embed style
+// goes in one embed if it fits; otherwise answers are split at paragraph or
+// code-fence boundaries into up to maxAnswerMessages messages, and anything
+// longer is attached as a Markdown file with notice as the message text.
+func renderAnswer(answer string, style *services.EmbedStyle, notice string) []renderedMessage {
+	answer = closeCodeFences(sanitizeMentions(answer))
+
+	if style != nil && utf8.RuneCountInString(answer) <= embedDescriptionLimit {
+		return []renderedMessage{{Embeds: []*discordgo.MessageEmbed{answerEmbed(answer, style)}}}
+	}
+
+	chunks := splitMarkdown(answer, messageLimit)
+	if len(chunks) <= maxAnswerMessages {
+		messages := make([]renderedMessage, len(chunks))
+		for i, chunk := range chunks {
+			messages[i] = renderedMessage{Content: chunk}
+		}
+		return messages
+	}
+
+	return []renderedMessage{{
+		Content: notice,
+		Files: []*discordgo.File{{
+			Name:        answerFileName,
+			ContentType: "text/markdown",
+			Reader:      strings.NewReader(answer),
+		}},
+	}}
+}
+
+// answerEmbed presents an /ask answer in a personality's embed style.
+func answerEmbed(answer string, style *services.EmbedStyle) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:       style.Title,
+		Description: answer,
+		Color:       style.ColorValue(),
+	}
+	if style.Footer != "" {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: style.Footer}
+	}
+	return embed
+}
+
+var (
+	// massMention matches @everyone and @here
+	massMention = regexp.MustCompile(`@(everyone|here)\b`)
+	// userOrRoleMention matches <@id>, <@!id> and <@&id>
+	userOrRoleMention = regexp.MustCompile(`<@([!&]?)([0-9]+)>`)
+)
+
+// sanitizeMentions defangs mentions with a zero-width space, so generated
+// text can't ping even where allowed mentions aren't enforced.
+func sanitizeMentions(text string) string {
+	text = massMention.ReplaceAllString(text, "@\u200b$1")
+	return userOrRoleMention.ReplaceAllString(text, "<@\u200b$1$2>")
+}
+
+// closeCodeFences appends a closing fence if the text leaves one open, as a
+// truncated answer can.
+func closeCodeFences(text string) string {
+	if strings.Count(text, "```")%2 == 1 {
+		return strings.TrimRight(text, "\n") + "\n```"
+	}
+	return text
+}
+
+// splitMarkdown splits text into chunks of at most limit characters. It
+// breaks between paragraphs and code blocks where it can. A block longer
+// than a chunk is broken between lines, then between words; a code block
+// broken this way is closed at the end of each chunk and reopened, with its
+// language, at the start of the next.
+func splitMarkdown(text string, limit int) []string {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+
+	var chunks []string
+	current := ""
+	for _, block := range markdownBlocks(text) {
+		switch {
+		case utf8.RuneCountInString(block) > limit:
+			if current != "" {
+				chunks = append(chunks, current)
+			}
+			pieces := splitBlock(block, limit)
+			chunks = append(chunks, pieces[:len(pieces)-1]...)
+			current = pieces[len(pieces)-1]
+		case current == "":
+			current = block
+		case utf8.RuneCountInString(current)+2+utf8.RuneCountInString(block) <= limit:
+			current += "\n\n" + block
+		default:
+			chunks = append(chunks, current)
+			current = block
+		}
+	}
+	if current != "" {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+// markdownBlocks splits text into paragraphs and code blocks. Blank lines
+// inside a code block don't end it.
+func markdownBlocks(text string) []string {
+	var blocks, lines []string
+	inFence := false
+	flush := func() {
+		if block := strings.Trim(strings.Join(lines, "\n"), "\n"); block != "" {
+			blocks = append(blocks, block)
+		}
+		lines = nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		isFence := strings.HasPrefix(strings.TrimSpace(line), "```")
+		switch {
+		case isFence && !inFence:
+			flush()
+			lines = append(lines, line)
+			inFence = true
+		case isFence && inFence:
+			lines = append(lines, line)
+			flush()
+			inFence = false
+		case line == "" && !inFence:
+			flush()
+		default:
+			lines = append(lines, line)
+		}
+	}
+	flush()
+	return blocks
+}
+
+// splitBlock breaks a paragraph or code block longer than limit into pieces.
+func splitBlock(block string, limit int) []string {
+	lines := strings.Split(block, "\n")
+	if !strings.HasPrefix(strings.TrimSpace(lines[0]), "```") {
+		return packLines(lines, limit)
+	}
+
+	// Code block: pack the body, then wrap each piece in the fences
+	open := strings.TrimSpace(lines[0])
+	body := lines[1:]
+	if n := len(body); n > 0 && strings.TrimSpace(body[n-1]) == "```" {
+		body = body[:n-1]
+	}
+	room := limit - utf8.RuneCountInString(open) - len("\n\n```")
+	pieces := packLines(body, room)
+	for i, piece := range pieces {
+		pieces[i] = open + "\n" + piece + "\n```"
+	}
+	return pieces
+}
+
+// packLines joins lines into pieces of at most limit characters, splitting
+// lines that are longer than that between words.
+func packLines(lines []string, limit int) []string {
+	var pieces []string
+	current, started := "", false
+	add := func(line string) {
+		switch {
+		case !started:
+			current, started = line, true
+		case utf8.RuneCountInString(current)+1+utf8.RuneCountInString(line) <= limit:
+			current += "\n" + line
+		default:
+			pieces = append(pieces, current)
+			current = line
+		}
+	}
+
+	for _, line := range lines {
+		for utf8.RuneCountInString(line) > limit {
+			head, tail := splitLine(line, limit)
+			add(head)
+			line = tail
+		}
+		add(line)
+	}
+	return append(pieces, current)
+}
+
+// splitLine splits line into a head of at most n characters, broken at the
+// last space in its second half if there is one, and the rest.
+func splitLine(line string, n int) (string, string) {
+	runes := []rune(line)
+	if len(runes) <= n {
+		return line, ""
+	}
+	cut := n
+	for i := n; i > n/2; i-- {
+		if runes[i] == ' ' {
+			cut = i
+			break
+		}
+	}
+	return string(runes[:cut]), strings.TrimLeft(string(runes[cut:]), " ")
+}
@@ -0,0 +1,133 @@
+package bot
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"living-lands-bot/internal/services"
+	"living-lands-bot/internal/testutil"
+)
+
+// paragraph returns a sentence repeated to roughly n characters.
+func paragraph(n int) string {
+	const sentence = "The metabolism system drains hunger and thirst over time. "
+	return strings.TrimSpace(strings.Repeat(sentence, n/len(sentence)+1)[:n])
+}
+
+func TestSplitMarkdownParagraphs(t *testing.T) {
+	text := paragraph(900) + "\n\n" + paragraph(900) + "\n\n" + paragraph(900)
+
+	chunks := splitMarkdown(text, messageLimit)
+	require.Len(t, chunks, 2)
+	assert.Equal(t, paragraph(900)+"\n\n"+paragraph(900), chunks[0], "whole paragraphs are packed together")
+	assert.Equal(t, paragraph(900), chunks[1])
+}
+
+func TestSplitMarkdownCodeBlocks(t *testing.T) {
+	var code strings.Builder
+	for range 120 {
+		code.WriteString("hunger_drain_rate = 0.25 # per in-game hour\n")
+	}
+	text := "Set these in the config:\n\n```toml\n" + code.String() + "```\n\nThen restart."
+
+	chunks := splitMarkdown(text, 1000)
+	require.Greater(t, len(chunks), 2)
+	for i, chunk := range chunks {
+		assert.LessOrEqual(t, utf8.RuneCountInString(chunk), 1000, "chunk %d", i)
+		assert.Equal(t, 0, strings.Count(chunk, "```")%2, "chunk %d has balanced fences", i)
+	}
+	assert.Equal(t, "Set these in the config:", chunks[0], "a code block starts a new chunk rather than being cut")
+	assert.True(t, strings.HasPrefix(chunks[2], "```toml\n"), "split code reopens with its language")
+	assert.Equal(t, "Then restart.", chunks[len(chunks)-1][strings.LastIndex(chunks[len(chunks)-1], "\n")+1:])
+}
+
+func TestSplitMarkdownLongLine(t *testing.T) {
+	// No newlines at all, and multi-byte characters
+	text := strings.Repeat("Путник идёт по дороге. ", 200)
+
+	chunks := splitMarkdown(text, messageLimit)
+	require.Len(t, chunks, 3)
+	for _, chunk := range chunks {
+		assert.LessOrEqual(t, utf8.RuneCountInString(chunk), messageLimit)
+		assert.True(t, utf8.ValidString(chunk))
+		assert.False(t, strings.HasPrefix(chunk, " "), "lines are broken between words")
+	}
+	assert.Equal(t, strings.Join(strings.Fields(text), " "), strings.Join(strings.Fields(strings.Join(chunks, " ")), " "), "nothing is lost")
+}
+
+func TestSanitizeMentions(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Attention @everyone and @here!", "Attention @\u200beveryone and @\u200bhere!"},
+		{"Ask <@123456789012345678> or <@!123456789012345678>", "Ask <@\u200b123456789012345678> or <@\u200b!123456789012345678>"},
+		{"Ping <@&987654321098765432> for help", "Ping <@\u200b&987654321098765432> for help"},
+		{"Email admin@example.com", "Email admin@example.com"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, sanitizeMentions(tt.in))
+	}
+}
+
+func TestRenderAnswer(t *testing.T) {
+	t.Run("short answer", func(t *testing.T) {
+		messages := renderAnswer("Well met, @everyone!", nil, "attached")
+		require.Len(t, messages, 1)
+		assert.Equal(t, "Well met, @\u200beveryone!", messages[0].Content)
+	})
+
+	t.Run("unclosed fence", func(t *testing.T) {
+		messages := renderAnswer("Run:\n```sh\n./bot migrate", nil, "attached")
+		require.Len(t, messages, 1)
+		assert.Equal(t, "Run:\n```sh\n./bot migrate\n```", messages[0].Content)
+	})
+
+	t.Run("embed holds more than a message", func(t *testing.T) {
+		answer := paragraph(3000)
+		messages := renderAnswer(answer, &services.EmbedStyle{Title: "Tinker", Color: "#3A86FF"}, "attached")
+		require.Len(t, messages, 1)
+		require.Len(t, messages[0].Embeds, 1)
+		assert.Equal(t, answer, messages[0].Embeds[0].Description)
+		assert.Equal(t, 0x3A86FF, messages[0].Embeds[0].Color)
+	})
+
+	t.Run("split into messages", func(t *testing.T) {
+		messages := renderAnswer(paragraph(1500)+"\n\n"+paragraph(1500), nil, "attached")
+		require.Len(t, messages, 2)
+		assert.Empty(t, messages[0].Files)
+	})
+
+	t.Run("too long falls back to a file", func(t *testing.T) {
+		answer := paragraph(1900) + "\n\n" + paragraph(1900) + "\n\n" + paragraph(1900) + "\n\n" + paragraph(1900)
+		messages := renderAnswer(answer, &services.EmbedStyle{Title: "Tinker"}, "attached")
+		require.Len(t, messages, 1)
+		assert.Equal(t, "attached", messages[0].Content)
+		require.Len(t, messages[0].Files, 1)
+		assert.Equal(t, answerFileName, messages[0].Files[0].Name)
+		body, err := io.ReadAll(messages[0].Files[0].Reader)
+		require.NoError(t, err)
+		assert.Equal(t, answer, string(body))
+	})
+}
+
+func TestAskCommandNeverPings(t *testing.T) {
+	h, ollamaServer := newTestHandlers(t)
+	ollamaServer.Script(testutil.Completion{Response: "Hear me, @everyone: hunger drains over time."})
+	rec := testutil.NewInteractionRecorder()
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/ask_knowledge.json"))
+
+	followups := rec.Followups()
+	require.Len(t, followups, 1)
+	assert.Equal(t, "Hear me, @\u200beveryone: hunger drains over time.", followups[0].Content)
+	require.NotNil(t, followups[0].AllowedMentions)
+	assert.Empty(t, followups[0].AllowedMentions.Parse)
+	assert.NotEmpty(t, followups[0].Components, "feedback buttons are kept")
+	assert.Equal(t, discordgo.MessageFlags(0), followups[0].Flags)
+}
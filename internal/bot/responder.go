@@ -0,0 +1,15 @@
+package bot
+
+import "github.com/bwmarrin/discordgo"
+
+// Responder is the subset of *discordgo.Session that command handlers use to
+// answer interactions. Keeping it narrow lets tests substitute an in-memory
+// recorder (see testutil.InteractionRecorder) for a live gateway session.
+type Responder interface {
+	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error
+	InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	InteractionResponseDelete(interaction *discordgo.Interaction, options ...discordgo.RequestOption) error
+	FollowupMessageCreate(interaction *discordgo.Interaction, wait bool, data *discordgo.WebhookParams, options ...discordgo.RequestOption) (*discordgo.Message, error)
+}
+
+var _ Responder = (*discordgo.Session)(nil)
@@ -0,0 +1,258 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+
+	"living-lands-bot/internal/i18n"
+	"living-lands-bot/internal/services"
+)
+
+// settingChoices are the settings offered by /settings guild; /settings
+// channel offers all but the welcome channel.
+func settingChoices(guild bool) []*discordgo.ApplicationCommandOptionChoice {
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, key := range services.SettingKeys {
+		if key == services.SettingWelcomeChannel && !guild {
+			continue
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: string(key), Value: string(key)})
+	}
+	return choices
+}
+
+var settingsCommand = Command{
+	Definition: &discordgo.ApplicationCommand{
+		Name:        "settings",
+		Description: "View and change bot settings for this server",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "show",
+				Description: "Show the effective settings in a channel",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "Channel to show (defaults to this one)",
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "guild",
+				Description: "Change a setting for the whole server",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "setting",
+						Description: "Setting to change",
+						Required:    true,
+						Choices:     settingChoices(true),
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "value",
+						Description: `New value, or "default" to clear the override`,
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "channel",
+				Description: "Change a setting for one channel",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "setting",
+						Description: "Setting to change",
+						Required:    true,
+						Choices:     settingChoices(false),
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "value",
+						Description: `New value, or "default" to use the server setting`,
+						Required:    true,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "Channel to change (defaults to this one)",
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+					},
+				},
+			},
+		},
+	},
+	Permissions: discordgo.PermissionManageGuild,
+	Handler:     (*CommandHandlers).handleSettingsCommand,
+}
+
+type settingsKey struct{}
+
+// withSettings returns ctx carrying the effective settings of an interaction.
+func withSettings(ctx context.Context, settings services.Settings) context.Context {
+	return context.WithValue(ctx, settingsKey{}, settings)
+}
+
+// settingsFrom returns the settings carried by ctx, or the defaults.
+func settingsFrom(ctx context.Context) services.Settings {
+	if settings, ok := ctx.Value(settingsKey{}).(services.Settings); ok {
+		return settings
+	}
+	return services.DefaultSettings()
+}
+
+// resolveSettings returns the effective settings for the channel an
+// interaction was made in. Lookup failures fall back to the defaults, so a
+// database outage never blocks commands.
+func (h *CommandHandlers) resolveSettings(i *discordgo.InteractionCreate) services.Settings {
+	if h.settings == nil {
+		return services.DefaultSettings()
+	}
+	settings, err := h.settings.Resolve(i.GuildID, i.ChannelID)
+	if err != nil {
+		h.logger.Warn("failed to resolve settings, using defaults", "error", err, "guild_id", i.GuildID, "channel_id", i.ChannelID)
+	}
+	return settings
+}
+
+func (h *CommandHandlers) handleSettingsCommand(_ context.Context, r Responder, i *discordgo.InteractionCreate) string {
+	lang := replyLanguage(i, "")
+	reply := func(content string) {
+		r.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+	}
+
+	if h.settings == nil {
+		reply(i18n.T(lang, i18n.MsgSettingsUnavailable))
+		return outcomeError
+	}
+	if i.GuildID == "" || i.Member == nil || i.Member.User == nil {
+		reply(i18n.T(lang, i18n.MsgSettingsServerOnly))
+		return outcomeInvalid
+	}
+
+	data := i.ApplicationCommandData()
+	if len(data.Options) == 0 {
+		reply(i18n.T(lang, i18n.MsgSettingsUnknownSubcommand))
+		return outcomeInvalid
+	}
+	sub := data.Options[0]
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(sub.Options))
+	for _, opt := range sub.Options {
+		options[opt.Name] = opt
+	}
+	channelID := i.ChannelID
+	if opt, ok := options["channel"]; ok {
+		channelID = opt.ChannelValue(nil).ID
+	}
+
+	switch sub.Name {
+	case "show":
+		settings, sources, err := h.settings.Explain(i.GuildID, channelID)
+		if err != nil {
+			h.logger.Error("failed to load settings", "error", err, "guild_id", i.GuildID, "channel_id", channelID)
+			reply(i18n.T(lang, i18n.MsgSettingsLoadFailed))
+			return outcomeError
+		}
+
+		embed := &discordgo.MessageEmbed{
+			Title:       i18n.T(lang, i18n.MsgSettingsShowTitle),
+			Description: i18n.T(lang, i18n.MsgSettingsShowDescription, channelID),
+			Color:       0x2D6A4F, // Forest green from brand palette
+		}
+		for _, key := range services.SettingKeys {
+			value := "`" + settings.Value(key) + "`"
+			if key == services.SettingWelcomeChannel && settings.WelcomeChannelID != "" {
+				value = "<#" + settings.WelcomeChannelID + ">"
+			}
+			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+				Name:   string(key),
+				Value:  fmt.Sprintf("%s\n*%s*", value, i18n.T(lang, sourceMessage(sources[key]))),
+				Inline: true,
+			})
+		}
+		r.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Embeds: []*discordgo.MessageEmbed{embed},
+				Flags:  discordgo.MessageFlagsEphemeral,
+			},
+		})
+		return outcomeSuccess
+
+	case "guild", "channel":
+		var key services.SettingKey
+		var value string
+		if opt, ok := options["setting"]; ok {
+			key = services.SettingKey(opt.StringValue())
+		}
+		if opt, ok := options["value"]; ok {
+			value = opt.StringValue()
+		}
+
+		var err error
+		if sub.Name == "guild" {
+			err = h.settings.SetGuild(i.GuildID, key, value, i.Member.User.ID)
+		} else {
+			err = h.settings.SetChannel(i.GuildID, channelID, key, value, i.Member.User.ID)
+		}
+		switch {
+		case errors.Is(err, services.ErrGuildOnly):
+			reply(i18n.T(lang, i18n.MsgSettingsGuildOnly, key))
+			return outcomeInvalid
+		case errors.Is(err, services.ErrInvalidSetting), errors.Is(err, services.ErrUnknownSetting):
+			reply(i18n.T(lang, i18n.MsgSettingsInvalidValue, value, key))
+			return outcomeInvalid
+		case err != nil:
+			h.logger.Error("failed to save setting", "error", err, "setting", key, "guild_id", i.GuildID)
+			reply(i18n.T(lang, i18n.MsgSettingsSaveFailed))
+			return outcomeError
+		}
+
+		// Echo the stored form, so "yes" reads back as "on"
+		layer, source := channelID, services.SourceChannel
+		if sub.Name == "guild" {
+			layer, source = "", services.SourceGuild
+		}
+		if settings, sources, err := h.settings.Explain(i.GuildID, layer); err == nil {
+			value = settings.Value(key)
+			if sources[key] != source {
+				value = "default"
+			}
+		}
+		if sub.Name == "guild" {
+			reply(i18n.T(lang, i18n.MsgSettingsSavedGuild, key, value))
+		} else {
+			reply(i18n.T(lang, i18n.MsgSettingsSavedChannel, key, value, channelID))
+		}
+		return outcomeSuccess
+
+	default:
+		reply(i18n.T(lang, i18n.MsgSettingsUnknownSubcommand))
+		return outcomeInvalid
+	}
+}
+
+func sourceMessage(source services.SettingSource) i18n.MessageID {
+	switch source {
+	case services.SourceGuild:
+		return i18n.MsgSettingsSourceGuild
+	case services.SourceChannel:
+		return i18n.MsgSettingsSourceChannel
+	default:
+		return i18n.MsgSettingsSourceDefault
+	}
+}
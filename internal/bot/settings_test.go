@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"living-lands-bot/internal/i18n"
+	"living-lands-bot/internal/services"
+	"living-lands-bot/internal/testutil"
+	"living-lands-bot/pkg/language"
+)
+
+func TestSettingsCommandChangesAsk(t *testing.T) {
+	h, ollamaServer := newTestHandlers(t)
+	ollamaServer.Script(testutil.Completion{Response: "Hunger and thirst drain over time, traveler."})
+	rec := testutil.NewInteractionRecorder()
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/settings_guild_ephemeral.json"))
+	require.Len(t, rec.Responses(), 1)
+	assert.Equal(t, discordgo.MessageFlagsEphemeral, rec.Responses()[0].Data.Flags)
+	assert.Equal(t, "**ephemeral** set to `on` for this server.", rec.Responses()[0].Data.Content)
+
+	// Answers are now only shown to the asker
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/ask_knowledge.json"))
+	require.Len(t, rec.Responses(), 2)
+	assert.Equal(t, discordgo.InteractionResponseDeferredChannelMessageWithSource, rec.Responses()[1].Type)
+	assert.Equal(t, discordgo.MessageFlagsEphemeral, rec.Responses()[1].Data.Flags)
+	require.Len(t, rec.Followups(), 1)
+	assert.Equal(t, discordgo.MessageFlagsEphemeral, rec.Followups()[0].Flags)
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/settings_channel_ask_off.json"))
+	require.Len(t, rec.Responses(), 3)
+	assert.Equal(t, "**ask** set to `off` in <#1000000000000000100>.", rec.Responses()[2].Data.Content)
+
+	// /ask is now closed in the channel
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/ask_knowledge.json"))
+	require.Len(t, rec.Responses(), 4)
+	assert.Equal(t, discordgo.MessageFlagsEphemeral, rec.Responses()[3].Data.Flags)
+	assert.Equal(t, i18n.T(language.English, i18n.MsgAccessDeniedChannel), rec.Responses()[3].Data.Content)
+	assert.Len(t, ollamaServer.ChatRequests(), 1, "a closed channel must not reach the LLM")
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/settings_show.json"))
+	require.Len(t, rec.Responses(), 5)
+	require.Len(t, rec.Responses()[4].Data.Embeds, 1)
+	fields := make(map[string]string)
+	for _, f := range rec.Responses()[4].Data.Embeds[0].Fields {
+		fields[f.Name] = f.Value
+	}
+	assert.Equal(t, "`off`\n*set for the channel*", fields["ask"])
+	assert.Equal(t, "`on`\n*set for the server*", fields["ephemeral"])
+	assert.Equal(t, "`default`\n*default*", fields["welcome_channel"])
+}
+
+func TestSettingsCommandInvalidValue(t *testing.T) {
+	h, _ := newTestHandlers(t)
+	rec := testutil.NewInteractionRecorder()
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/settings_channel_invalid.json"))
+
+	responses := rec.Responses()
+	require.Len(t, responses, 1)
+	assert.Equal(t, discordgo.MessageFlagsEphemeral, responses[0].Data.Flags)
+	assert.Equal(t, i18n.T(language.English, i18n.MsgSettingsInvalidValue, "lots", "rate_limit"), responses[0].Data.Content)
+
+	row, err := h.settings.Channel("1000000000000000101")
+	require.NoError(t, err)
+	assert.Nil(t, row)
+}
+
+func TestAskCommandUsesChannelCollection(t *testing.T) {
+	h, ollamaServer := newTestHandlers(t)
+	ollamaServer.Script(testutil.Completion{Response: "The modding docs say nothing of hunger."})
+	require.NoError(t, h.settings.SetChannel("1000000000000000000", "1000000000000000100", services.SettingRAGCollection, "modding_docs", "900000000000000002"))
+	rec := testutil.NewInteractionRecorder()
+
+	h.Dispatch(rec, testutil.LoadInteraction(t, "testdata/ask_knowledge.json"))
+
+	require.Len(t, rec.Followups(), 1)
+	reqs := ollamaServer.ChatRequests()
+	require.Len(t, reqs, 1)
+	for _, msg := range reqs[0].Messages {
+		assert.NotContains(t, msg.Content, "metabolism system tracks hunger", "the default collection must not be queried")
+	}
+}
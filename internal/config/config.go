@@ -9,8 +9,13 @@ import (
 
 type Config struct {
 	Discord struct {
-		Token   string `envconfig:"DISCORD_TOKEN" required:"true"`
-		GuildID string `envconfig:"DISCORD_GUILD_ID" required:"true"`
+		Token string `envconfig:"DISCORD_TOKEN" required:"true"`
+		// Primary guild: rows from before multi-guild support are backfilled
+		// to it, and `./bot commands` targets it by default
+		GuildID string `envconfig:"DISCORD_GUILD_ID"`
+		// Guilds the bot serves, comma-separated, in addition to GuildID.
+		// Empty serves every guild it joins
+		GuildIDs []string `envconfig:"DISCORD_GUILD_IDS"`
 	}
 
 	HTTP struct {
@@ -44,7 +49,19 @@ type Config struct {
 		RequestTimeout int `envconfig:"OLLAMA_TIMEOUT" default:"60"`
 	}
 
+	// OpenAI-compatible backend (llama.cpp server, vLLM, LM Studio).
+	// Only used when LLM_PROVIDER=openai; LLM_MODEL, EMBEDDING_MODEL and
+	// OLLAMA_TIMEOUT apply to this backend as well.
+	OpenAI struct {
+		// Base URL including the version prefix, e.g. http://llamacpp:8080/v1
+		URL    string `envconfig:"OPENAI_BASE_URL"`
+		APIKey string `envconfig:"OPENAI_API_KEY"`
+	}
+
 	LLM struct {
+		// Provider selects the generation/embedding backend: "ollama" or "openai"
+		Provider string `envconfig:"LLM_PROVIDER" default:"ollama"`
+
 		// Fast mode settings (conversational queries)
 		FastMaxTokens   int     `envconfig:"LLM_FAST_MAX_TOKENS" default:"60"`
 		FastTemperature float64 `envconfig:"LLM_FAST_TEMPERATURE" default:"0.5"`
@@ -56,6 +73,78 @@ type Config struct {
 		// Deep mode settings (technical questions with RAG)
 		DeepMaxTokens   int     `envconfig:"LLM_DEEP_MAX_TOKENS" default:"180"`
 		DeepTemperature float64 `envconfig:"LLM_DEEP_TEMPERATURE" default:"0.7"`
+
+		// Context window (tokens) shared by the prompt and the answer; RAG
+		// documentation fills what the persona, question and answer leave
+		ContextWindow int `envconfig:"LLM_CONTEXT_WINDOW" default:"2048"`
+		// Largest window a request may grow to when its prompt and answer
+		// don't fit LLM_CONTEXT_WINDOW (0 = never grow)
+		ContextWindowMax int `envconfig:"LLM_CONTEXT_WINDOW_MAX" default:"8192"`
+
+		// Fallback chain tried in order after LLM_MODEL, comma-separated.
+		// Each entry is "model" (same server) or "model@url" (another server
+		// of the same provider), e.g. "qwen2.5:3b,mistral:7b-instruct@http://ollama-2:11434"
+		Fallbacks []string `envconfig:"LLM_FALLBACKS"`
+
+		// Circuit breaker: skip a backend after this many consecutive
+		// failures or slow responses, for the cooldown (seconds)
+		BreakerFailures int `envconfig:"LLM_BREAKER_FAILURES" default:"3"`
+		BreakerCooldown int `envconfig:"LLM_BREAKER_COOLDOWN" default:"30"`
+		// Responses slower than this (seconds) count as failures
+		SlowThreshold int `envconfig:"LLM_SLOW_THRESHOLD" default:"20"`
+		// Per-backend timeout (seconds) while a fallback remains
+		AttemptTimeout int `envconfig:"LLM_ATTEMPT_TIMEOUT" default:"20"`
+
+		// Work queue: concurrent generations overall and per guild (0 = no
+		// per-guild limit), and how many /ask requests may wait before new
+		// ones are turned away
+		MaxConcurrent int `envconfig:"LLM_MAX_CONCURRENT" default:"2"`
+		MaxPerGuild   int `envconfig:"LLM_MAX_PER_GUILD" default:"0"`
+		MaxQueueDepth int `envconfig:"LLM_MAX_QUEUE" default:"10"`
+	}
+
+	// Tracing exports OpenTelemetry spans for each interaction
+	Tracing struct {
+		// Exporter: "none", "stdout" (spans as JSON on stdout) or "otlp" (OTLP/HTTP)
+		Exporter string `envconfig:"TRACING_EXPORTER" default:"none"`
+		// Collector host:port; empty falls back to OTEL_EXPORTER_OTLP_ENDPOINT
+		OTLPEndpoint string  `envconfig:"TRACING_OTLP_ENDPOINT"`
+		OTLPInsecure bool    `envconfig:"TRACING_OTLP_INSECURE" default:"true"`
+		SampleRatio  float64 `envconfig:"TRACING_SAMPLE_RATIO" default:"1"`
+	}
+
+	// Gaps controls the documentation-gap digest
+	Gaps struct {
+		// Staff channel for the digest of unanswered questions; empty disables it
+		DigestChannelID string `envconfig:"GAPS_DIGEST_CHANNEL_ID"`
+		// Hours between digests
+		DigestInterval int `envconfig:"GAPS_DIGEST_INTERVAL" default:"168"`
+		// Topics listed in each digest
+		DigestTopics int `envconfig:"GAPS_DIGEST_TOPICS" default:"10"`
+		// Minimum cosine similarity for questions to share a topic
+		Similarity float64 `envconfig:"GAPS_SIMILARITY" default:"0.8"`
+	}
+
+	// FAQ controls matching of /ask questions against curated answers
+	FAQ struct {
+		// Minimum cosine similarity between a question and an FAQ variant
+		Similarity float64 `envconfig:"FAQ_SIMILARITY" default:"0.9"`
+	}
+
+	// Guard screens questions before they reach the LLM and generated
+	// answers before they reach Discord
+	Guard struct {
+		// Prompt-injection scores at which /ask questions are flagged for
+		// review and refused
+		InputWarnScore   float64 `envconfig:"INPUT_GUARD_WARN_SCORE" default:"0.3"`
+		InputRefuseScore float64 `envconfig:"INPUT_GUARD_REFUSE_SCORE" default:"0.7"`
+		// Local model that judges questions for prompt injection; empty skips it
+		ClassifierModel string `envconfig:"INPUT_GUARD_CLASSIFIER_MODEL"`
+
+		// Allowed link hosts, blocked words and blocked patterns
+		OutputRulesFile string `envconfig:"OUTPUT_GUARD_FILE" default:"configs/output_guard.yaml"`
+		// Local model that judges answers for toxicity; empty skips the check
+		ToxicityModel string `envconfig:"OUTPUT_GUARD_TOXICITY_MODEL"`
 	}
 
 	Hytale struct {
@@ -67,6 +156,17 @@ type Config struct {
 		RateLimitPerMin int    `envconfig:"RATE_LIMIT_PER_MINUTE" default:"5"`
 		LogLevel        string `envconfig:"LOG_LEVEL" default:"info"`
 		PersonalityFile string `envconfig:"PERSONALITY_FILE" default:"configs/personality.yaml"`
+		// More personalities, one YAML file each, selectable per channel with
+		// /settings or active during their season
+		PersonalityDir string `envconfig:"PERSONALITY_DIR" default:"configs/personalities"`
+		// Seconds between checks for edited personality files (0 = only reload on SIGHUP)
+		PersonalityReloadInterval int `envconfig:"PERSONALITY_RELOAD_INTERVAL" default:"10"`
+		// Labelled example questions for the /ask intent classifier
+		IntentExamplesFile string `envconfig:"INTENT_EXAMPLES_FILE" default:"configs/intents.yaml"`
+		// Minimum similarity to the closest example; below it keyword rules decide
+		IntentMinConfidence float64 `envconfig:"INTENT_MIN_CONFIDENCE" default:"0.6"`
+		// Who may use which slash commands (roles, permissions, channels, linked accounts)
+		AccessPolicyFile string `envconfig:"ACCESS_POLICY_FILE" default:"configs/access.yaml"`
 	}
 }
 
@@ -98,12 +198,31 @@ func Load() (*Config, error) {
 	return &cfg, nil
 }
 
+// AllowsGuild reports whether the bot serves a guild. Without
+// DISCORD_GUILD_IDS every guild is served.
+func (c *Config) AllowsGuild(guildID string) bool {
+	if len(c.Discord.GuildIDs) == 0 {
+		return true
+	}
+	if guildID == c.Discord.GuildID {
+		return true
+	}
+	for _, id := range c.Discord.GuildIDs {
+		if strings.TrimSpace(id) == guildID {
+			return true
+		}
+	}
+	return false
+}
+
 // Validate checks if all configuration values are valid.
 // Returns a detailed error message if any validation fails.
 func (c *Config) Validate() error {
 	// Validate Discord config
-	if c.Discord.GuildID == "" {
-		return fmt.Errorf("DISCORD_GUILD_ID is required and cannot be empty")
+	for _, id := range c.Discord.GuildIDs {
+		if strings.TrimSpace(id) == "" {
+			return fmt.Errorf("DISCORD_GUILD_IDS must not contain empty entries")
+		}
 	}
 
 	// Validate Database config
@@ -153,6 +272,15 @@ func (c *Config) Validate() error {
 	}
 
 	// Validate LLM config
+	switch c.LLM.Provider {
+	case "ollama":
+	case "openai":
+		if c.OpenAI.URL == "" {
+			return fmt.Errorf("OPENAI_BASE_URL is required when LLM_PROVIDER=openai")
+		}
+	default:
+		return fmt.Errorf("LLM_PROVIDER must be \"ollama\" or \"openai\", got %q", c.LLM.Provider)
+	}
 	if c.LLM.FastMaxTokens < 1 || c.LLM.FastMaxTokens > 1000 {
 		return fmt.Errorf("LLM_FAST_MAX_TOKENS must be between 1 and 1000, got %d", c.LLM.FastMaxTokens)
 	}
@@ -162,6 +290,12 @@ func (c *Config) Validate() error {
 	if c.LLM.DeepMaxTokens < 1 || c.LLM.DeepMaxTokens > 1000 {
 		return fmt.Errorf("LLM_DEEP_MAX_TOKENS must be between 1 and 1000, got %d", c.LLM.DeepMaxTokens)
 	}
+	if c.LLM.ContextWindow < 512 || c.LLM.ContextWindow > 131072 {
+		return fmt.Errorf("LLM_CONTEXT_WINDOW must be between 512 and 131072, got %d", c.LLM.ContextWindow)
+	}
+	if c.LLM.ContextWindowMax != 0 && (c.LLM.ContextWindowMax < c.LLM.ContextWindow || c.LLM.ContextWindowMax > 131072) {
+		return fmt.Errorf("LLM_CONTEXT_WINDOW_MAX must be 0 or between LLM_CONTEXT_WINDOW (%d) and 131072, got %d", c.LLM.ContextWindow, c.LLM.ContextWindowMax)
+	}
 	if c.LLM.FastTemperature < 0 || c.LLM.FastTemperature > 2 {
 		return fmt.Errorf("LLM_FAST_TEMPERATURE must be between 0 and 2, got %f", c.LLM.FastTemperature)
 	}
@@ -171,6 +305,69 @@ func (c *Config) Validate() error {
 	if c.LLM.DeepTemperature < 0 || c.LLM.DeepTemperature > 2 {
 		return fmt.Errorf("LLM_DEEP_TEMPERATURE must be between 0 and 2, got %f", c.LLM.DeepTemperature)
 	}
+	for _, f := range c.LLM.Fallbacks {
+		if model, _, _ := strings.Cut(strings.TrimSpace(f), "@"); model == "" {
+			return fmt.Errorf("LLM_FALLBACKS entries must be \"model\" or \"model@url\", got %q", f)
+		}
+	}
+	if c.LLM.BreakerFailures < 1 || c.LLM.BreakerFailures > 100 {
+		return fmt.Errorf("LLM_BREAKER_FAILURES must be between 1 and 100, got %d", c.LLM.BreakerFailures)
+	}
+	if c.LLM.BreakerCooldown < 1 || c.LLM.BreakerCooldown > 3600 {
+		return fmt.Errorf("LLM_BREAKER_COOLDOWN must be between 1 and 3600 seconds, got %d", c.LLM.BreakerCooldown)
+	}
+	if c.LLM.SlowThreshold < 0 || c.LLM.SlowThreshold > 600 {
+		return fmt.Errorf("LLM_SLOW_THRESHOLD must be between 0 and 600 seconds, got %d", c.LLM.SlowThreshold)
+	}
+	if c.LLM.AttemptTimeout < 0 || c.LLM.AttemptTimeout > 600 {
+		return fmt.Errorf("LLM_ATTEMPT_TIMEOUT must be between 0 and 600 seconds, got %d", c.LLM.AttemptTimeout)
+	}
+	if c.LLM.MaxConcurrent < 1 || c.LLM.MaxConcurrent > 64 {
+		return fmt.Errorf("LLM_MAX_CONCURRENT must be between 1 and 64, got %d", c.LLM.MaxConcurrent)
+	}
+	if c.LLM.MaxPerGuild < 0 || c.LLM.MaxPerGuild > c.LLM.MaxConcurrent {
+		return fmt.Errorf("LLM_MAX_PER_GUILD must be between 0 and LLM_MAX_CONCURRENT, got %d", c.LLM.MaxPerGuild)
+	}
+	if c.LLM.MaxQueueDepth < 0 || c.LLM.MaxQueueDepth > 1000 {
+		return fmt.Errorf("LLM_MAX_QUEUE must be between 0 and 1000, got %d", c.LLM.MaxQueueDepth)
+	}
+
+	// Validate Tracing config
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		return fmt.Errorf("TRACING_EXPORTER must be \"none\", \"stdout\" or \"otlp\", got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		return fmt.Errorf("TRACING_SAMPLE_RATIO must be between 0 and 1, got %f", c.Tracing.SampleRatio)
+	}
+
+	// Validate Gaps config
+	if c.Gaps.DigestInterval < 1 || c.Gaps.DigestInterval > 24*31 {
+		return fmt.Errorf("GAPS_DIGEST_INTERVAL must be between 1 and 744 hours, got %d", c.Gaps.DigestInterval)
+	}
+	if c.Gaps.DigestTopics < 1 || c.Gaps.DigestTopics > 25 {
+		return fmt.Errorf("GAPS_DIGEST_TOPICS must be between 1 and 25, got %d", c.Gaps.DigestTopics)
+	}
+	if c.Gaps.Similarity <= 0 || c.Gaps.Similarity > 1 {
+		return fmt.Errorf("GAPS_SIMILARITY must be greater than 0 and at most 1, got %f", c.Gaps.Similarity)
+	}
+
+	// Validate FAQ config
+	if c.FAQ.Similarity <= 0 || c.FAQ.Similarity > 1 {
+		return fmt.Errorf("FAQ_SIMILARITY must be greater than 0 and at most 1, got %f", c.FAQ.Similarity)
+	}
+
+	// Validate Guard config
+	if c.Guard.InputWarnScore <= 0 || c.Guard.InputWarnScore > c.Guard.InputRefuseScore {
+		return fmt.Errorf("INPUT_GUARD_WARN_SCORE must be greater than 0 and at most INPUT_GUARD_REFUSE_SCORE, got %f", c.Guard.InputWarnScore)
+	}
+	if c.Guard.InputRefuseScore > 10 {
+		return fmt.Errorf("INPUT_GUARD_REFUSE_SCORE must be at most 10, got %f", c.Guard.InputRefuseScore)
+	}
+	if c.Guard.OutputRulesFile == "" {
+		return fmt.Errorf("OUTPUT_GUARD_FILE is required")
+	}
 
 	// Validate Hytale config
 	if c.Hytale.APISecret == "" {
@@ -190,6 +387,18 @@ func (c *Config) Validate() error {
 	if c.Bot.PersonalityFile == "" {
 		return fmt.Errorf("PERSONALITY_FILE is required")
 	}
+	if c.Bot.PersonalityReloadInterval < 0 || c.Bot.PersonalityReloadInterval > 3600 {
+		return fmt.Errorf("PERSONALITY_RELOAD_INTERVAL must be between 0 and 3600 seconds, got %d", c.Bot.PersonalityReloadInterval)
+	}
+	if c.Bot.IntentExamplesFile == "" {
+		return fmt.Errorf("INTENT_EXAMPLES_FILE is required")
+	}
+	if c.Bot.IntentMinConfidence < 0 || c.Bot.IntentMinConfidence > 1 {
+		return fmt.Errorf("INTENT_MIN_CONFIDENCE must be between 0 and 1, got %f", c.Bot.IntentMinConfidence)
+	}
+	if c.Bot.AccessPolicyFile == "" {
+		return fmt.Errorf("ACCESS_POLICY_FILE is required")
+	}
 
 	return nil
 }
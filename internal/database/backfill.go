@@ -0,0 +1,36 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// guildScopedTables are the tables that gained a guild_id column when the bot
+// started serving several guilds.
+var guildScopedTables = []string{"users", "channel_routes", "welcome_templates", "faq_entries"}
+
+// BackfillGuildID assigns rows without a guild to guildID, the guild the bot
+// served before it was multi-guild, and returns how many rows each table
+// updated. It is idempotent.
+func BackfillGuildID(db *gorm.DB, guildID string) (map[string]int64, error) {
+	if guildID == "" {
+		return nil, fmt.Errorf("guild ID is required for backfill")
+	}
+
+	updated := make(map[string]int64, len(guildScopedTables))
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range guildScopedTables {
+			res := tx.Table(table).Where("guild_id = ?", "").Update("guild_id", guildID)
+			if res.Error != nil {
+				return fmt.Errorf("failed to backfill %s: %w", table, res.Error)
+			}
+			updated[table] = res.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
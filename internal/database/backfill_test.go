@@ -0,0 +1,41 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"living-lands-bot/internal/database/models"
+	"living-lands-bot/internal/testutil"
+)
+
+func TestBackfillGuildID(t *testing.T) {
+	db := testutil.NewTestDB(t, &models.User{}, &models.ChannelRoute{}, &models.WelcomeTemplate{}, &models.FAQEntry{})
+	const mainGuild, devGuild = "1000000000000000000", "1000000000000000001"
+
+	// Rows from before guild scoping, and one already scoped to another guild
+	require.NoError(t, db.Create(&models.User{DiscordID: "111", DiscordUsername: "wanderer"}).Error)
+	require.NoError(t, db.Create(&models.User{GuildID: devGuild, DiscordID: "111", DiscordUsername: "wanderer"}).Error)
+	require.NoError(t, db.Create(&models.ChannelRoute{Keyword: "bugs", ChannelID: "200"}).Error)
+	require.NoError(t, db.Create(&models.WelcomeTemplate{Message: "Welcome, {username}!", Weight: 1, Active: true}).Error)
+	require.NoError(t, db.Create(&models.WelcomeTemplate{Message: "Hail, {username}!", Weight: 1, Active: true}).Error)
+	require.NoError(t, db.Create(&models.FAQEntry{Question: "Where can I download the mod?", Answer: "From CurseForge."}).Error)
+
+	updated, err := BackfillGuildID(db, mainGuild)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"users": 1, "channel_routes": 1, "welcome_templates": 2, "faq_entries": 1}, updated)
+
+	var users []models.User
+	require.NoError(t, db.Order("guild_id").Find(&users).Error)
+	require.Len(t, users, 2)
+	assert.Equal(t, mainGuild, users[0].GuildID)
+	assert.Equal(t, devGuild, users[1].GuildID, "scoped rows are left alone")
+
+	updated, err = BackfillGuildID(db, mainGuild)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"users": 0, "channel_routes": 0, "welcome_templates": 0, "faq_entries": 0}, updated)
+
+	_, err = BackfillGuildID(db, "")
+	assert.Error(t, err)
+}
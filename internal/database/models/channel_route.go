@@ -2,9 +2,11 @@ package models
 
 import "time"
 
+// ChannelRoute points a /guide keyword at a channel of a guild.
 type ChannelRoute struct {
 	ID          uint   `gorm:"primaryKey"`
-	Keyword     string `gorm:"uniqueIndex;not null"`
+	GuildID     string `gorm:"uniqueIndex:idx_channel_routes_guild_keyword;not null;default:'';type:varchar(20)"`
+	Keyword     string `gorm:"uniqueIndex:idx_channel_routes_guild_keyword;not null"`
 	ChannelID   string `gorm:"not null"`
 	Description string
 	Emoji       string
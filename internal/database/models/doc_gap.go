@@ -0,0 +1,65 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Reasons a question is recorded as a documentation gap.
+const (
+	GapNoContext = "no_context" // RAG filtered out every document
+	GapNoAnswer  = "no_answer"  // The model said the archives do not cover it
+)
+
+// Embedding is a question embedding stored as a JSON array.
+type Embedding []float32
+
+// Value implements driver.Valuer. An empty embedding is stored as NULL.
+func (e Embedding) Value() (driver.Value, error) {
+	if len(e) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal([]float32(e))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner.
+func (e *Embedding) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*e = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type for Embedding: %T", value)
+	}
+	return json.Unmarshal(data, (*[]float32)(e))
+}
+
+// DocGap is an /ask question the archives could not answer.
+type DocGap struct {
+	ID            uint      `gorm:"primaryKey"`
+	InteractionID string    `gorm:"type:varchar(32)"`
+	DiscordID     string    `gorm:"type:varchar(20)"`
+	GuildID       string    `gorm:"index;type:varchar(20)"`
+	Question      string    `gorm:"not null"`
+	Reason        string    `gorm:"not null;type:varchar(16)"`
+	Embedding     Embedding `gorm:"type:jsonb"`
+	CreatedAt     time.Time `gorm:"index"`
+}
+
+// GapDigest records when a gap digest was posted to a staff channel.
+type GapDigest struct {
+	ID        uint      `gorm:"primaryKey"`
+	ChannelID string    `gorm:"not null;type:varchar(20)"`
+	PostedAt  time.Time `gorm:"not null"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// FAQEntry is a curated answer returned verbatim when an /ask question
+// matches one of its variants.
+type FAQEntry struct {
+	ID        uint   `gorm:"primaryKey"`
+	GuildID   string `gorm:"not null;type:varchar(20);index"`
+	Question  string `gorm:"not null"` // Canonical question, also stored as a variant
+	Answer    string `gorm:"not null"`
+	CreatedBy string `gorm:"type:varchar(20)"`
+	Variants  []FAQVariant
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// FAQVariant is one phrasing of an FAQ question with its embedding.
+type FAQVariant struct {
+	ID         uint      `gorm:"primaryKey"`
+	FAQEntryID uint      `gorm:"not null;index"`
+	Text       string    `gorm:"not null"`
+	Embedding  Embedding `gorm:"type:jsonb"`
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RetrievedChunk is a RAG document that was used as context for an answer.
+type RetrievedChunk struct {
+	ID       string  `json:"id"`
+	Distance float32 `json:"distance"`
+	Source   string  `json:"source,omitempty"`
+}
+
+// RetrievedChunks is stored as a JSON array.
+type RetrievedChunks []RetrievedChunk
+
+// Value implements driver.Valuer.
+func (c RetrievedChunks) Value() (driver.Value, error) {
+	if c == nil {
+		c = RetrievedChunks{}
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner.
+func (c *RetrievedChunks) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type for RetrievedChunks: %T", value)
+	}
+	return json.Unmarshal(data, c)
+}
+
+// QAInteraction is a logged /ask question and the answer the bot gave.
+type QAInteraction struct {
+	ID              uint   `gorm:"primaryKey"`
+	InteractionID   string `gorm:"type:varchar(32)"`
+	DiscordID       string `gorm:"index;type:varchar(20)"`
+	DiscordUsername string `gorm:"type:varchar(64)"`
+	GuildID         string `gorm:"index;type:varchar(20)"`
+	ChannelID       string `gorm:"type:varchar(20)"`
+	Question        string `gorm:"not null"`
+	Intent          string `gorm:"not null;type:varchar(32)"`
+	Mode            string `gorm:"not null;type:varchar(16)"`
+	Retrieved       RetrievedChunks
+	Answer          string `gorm:"not null"`
+	Model           string `gorm:"type:varchar(128)"`
+	LatencyMs       int64  `gorm:"not null;default:0"`
+	CreatedAt       time.Time
+}
+
+// Feedback ratings accepted by the feedback buttons. RatingUp and
+// RatingDown are stored as a QAFeedback vote, RatingReport as a QAReport.
+const (
+	RatingUp     = "up"
+	RatingDown   = "down"
+	RatingReport = "report"
+)
+
+// QAFeedback is a user's vote on a logged answer; a user has at most one
+// vote per answer.
+type QAFeedback struct {
+	ID              uint   `gorm:"primaryKey"`
+	QAInteractionID uint   `gorm:"not null;uniqueIndex:idx_qa_feedback_vote"`
+	DiscordID       string `gorm:"not null;type:varchar(20);uniqueIndex:idx_qa_feedback_vote"`
+	Rating          string `gorm:"not null;type:varchar(16)"`
+	CreatedAt       time.Time
+}
+
+// TableName keeps the singular table name used by the migration.
+func (QAFeedback) TableName() string {
+	return "qa_feedback"
+}
+
+// QAReport is a user's report of a wrong answer for moderators to review.
+type QAReport struct {
+	ID              uint   `gorm:"primaryKey"`
+	QAInteractionID uint   `gorm:"not null;uniqueIndex:idx_qa_reports_unique"`
+	DiscordID       string `gorm:"not null;type:varchar(20);uniqueIndex:idx_qa_reports_unique"`
+	CreatedAt       time.Time
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// SettingOverrides are the settings a guild or channel can override. Nil
+// fields inherit: a channel from its guild, a guild from the global config.
+type SettingOverrides struct {
+	AskEnabled       *bool   // Whether /ask may be used
+	EphemeralAnswers *bool   // Whether /ask answers are only shown to the asker
+	Personality      *string `gorm:"type:varchar(100)"`                       // Personality name
+	RAGCollection    *string `gorm:"column:rag_collection;type:varchar(100)"` // ChromaDB collection for /ask
+	RateLimitPerMin  *int    // /ask requests per user per minute
+}
+
+// GuildSettings holds a guild's overrides of the global configuration.
+type GuildSettings struct {
+	GuildID          string           `gorm:"primaryKey;type:varchar(20)"`
+	Overrides        SettingOverrides `gorm:"embedded"`
+	WelcomeChannelID *string          `gorm:"type:varchar(20)"`
+	UpdatedBy        string           `gorm:"type:varchar(20)"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// TableName keeps the table name used by the migration.
+func (GuildSettings) TableName() string {
+	return "guild_settings"
+}
+
+// ChannelSettings holds a channel's overrides of its guild's settings.
+type ChannelSettings struct {
+	ChannelID string           `gorm:"primaryKey;type:varchar(20)"`
+	GuildID   string           `gorm:"not null;index;type:varchar(20)"`
+	Overrides SettingOverrides `gorm:"embedded"`
+	UpdatedBy string           `gorm:"type:varchar(20)"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName keeps the table name used by the migration.
+func (ChannelSettings) TableName() string {
+	return "channel_settings"
+}
@@ -2,10 +2,12 @@ package models
 
 import "time"
 
-// User represents a Discord user account linked to a Hytale account.
+// User represents a Discord user account linked to a Hytale account. Links
+// are per guild, so each server the bot serves keeps its own.
 type User struct {
 	ID               uint   `gorm:"primaryKey"`
-	DiscordID        string `gorm:"uniqueIndex;not null;type:varchar(20)"` // Discord IDs as strings (native discordgo format)
+	GuildID          string `gorm:"uniqueIndex:idx_users_guild_discord;not null;default:'';type:varchar(20)"`
+	DiscordID        string `gorm:"uniqueIndex:idx_users_guild_discord;not null;type:varchar(20)"` // Discord IDs as strings (native discordgo format)
 	DiscordUsername  string `gorm:"not null"`
 	HytaleUsername   string `gorm:"index"`
 	HytaleUUID       string `gorm:"index"`
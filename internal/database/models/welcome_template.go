@@ -2,8 +2,10 @@ package models
 
 import "time"
 
+// WelcomeTemplate is a welcome message a guild picks from at random.
 type WelcomeTemplate struct {
 	ID        uint   `gorm:"primaryKey"`
+	GuildID   string `gorm:"index;not null;default:'';type:varchar(20)"`
 	Message   string `gorm:"not null"`
 	Weight    int    `gorm:"default:1"`
 	Active    bool   `gorm:"default:true"`
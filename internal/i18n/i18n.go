@@ -0,0 +1,281 @@
+// Package i18n holds the catalogue of user-facing bot messages and picks the
+// language a reply is written in. Catalogues live in locales/<code>.yaml and
+// are embedded in the binary; a message missing from a language falls back
+// to English.
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"gopkg.in/yaml.v3"
+
+	"living-lands-bot/pkg/language"
+)
+
+// MessageID identifies a message in the catalogue.
+type MessageID string
+
+// Message IDs. Every ID must be defined in locales/en.yaml. Slash command
+// names and descriptions live under command.* and are looked up by path with
+// CommandLocalizations instead.
+const (
+	MsgUnknownUser MessageID = "account.unknown_user"
+
+	MsgAccessDeniedPermission MessageID = "access.denied_permission"
+	MsgAccessDeniedRole       MessageID = "access.denied_role"
+	MsgAccessDeniedChannel    MessageID = "access.denied_channel"
+	MsgAccessDeniedLinked     MessageID = "access.denied_linked"
+	MsgAccessCheckFailed      MessageID = "access.check_failed"
+
+	MsgLinkCodeFailed MessageID = "link.code_failed"
+	MsgLinkCode       MessageID = "link.code"
+
+	MsgGuideTitle       MessageID = "guide.title"
+	MsgGuideDescription MessageID = "guide.description"
+	MsgGuideBugs        MessageID = "guide.bugs"
+	MsgGuideChangelog   MessageID = "guide.changelog"
+	MsgGuideWiki        MessageID = "guide.wiki"
+	MsgGuideSupport     MessageID = "guide.support"
+	MsgGuideComingSoon  MessageID = "guide.coming_soon"
+
+	MsgAskRateLimited MessageID = "ask.rate_limited"
+	MsgAskNoQuestion  MessageID = "ask.no_question"
+	MsgAskNavigation  MessageID = "ask.navigation"
+	MsgAskAccountHelp MessageID = "ask.account_help"
+	MsgAskCurated     MessageID = "ask.curated"
+	MsgAskQueued      MessageID = "ask.queued"
+	MsgAskShed        MessageID = "ask.shed"
+	MsgAskTimeout     MessageID = "ask.timeout"
+	MsgAskError       MessageID = "ask.error"
+	MsgAskAttached    MessageID = "ask.attached"
+	MsgAskWithheld    MessageID = "ask.withheld"
+	MsgAskRefused     MessageID = "ask.refused"
+	MsgAskNoAnswer    MessageID = "ask.no_answer"
+
+	MsgFeedbackReport      MessageID = "feedback.report"
+	MsgFeedbackUnavailable MessageID = "feedback.unavailable"
+	MsgFeedbackNotFound    MessageID = "feedback.not_found"
+	MsgFeedbackFailed      MessageID = "feedback.failed"
+	MsgFeedbackReported    MessageID = "feedback.reported"
+	MsgFeedbackThanks      MessageID = "feedback.thanks"
+
+	MsgFAQUnavailable       MessageID = "faq.unavailable"
+	MsgFAQNoSubcommand      MessageID = "faq.no_subcommand"
+	MsgFAQUnknownSubcommand MessageID = "faq.unknown_subcommand"
+	MsgFAQListFailed        MessageID = "faq.list_failed"
+	MsgFAQListEmpty         MessageID = "faq.list_empty"
+	MsgFAQListTitle         MessageID = "faq.list_title"
+	MsgFAQListTruncated     MessageID = "faq.list_truncated"
+	MsgFAQListVariants      MessageID = "faq.list_variants"
+	MsgFAQRemoveNoID        MessageID = "faq.remove_no_id"
+	MsgFAQRemoveNotFound    MessageID = "faq.remove_not_found"
+	MsgFAQRemoveFailed      MessageID = "faq.remove_failed"
+	MsgFAQRemoved           MessageID = "faq.removed"
+	MsgFAQModalTitle        MessageID = "faq.modal_title"
+	MsgFAQModalQuestion     MessageID = "faq.modal_question"
+	MsgFAQModalVariants     MessageID = "faq.modal_variants"
+	MsgFAQModalAnswer       MessageID = "faq.modal_answer"
+	MsgFAQSaveFailed        MessageID = "faq.save_failed"
+	MsgFAQSaved             MessageID = "faq.saved"
+
+	MsgSettingsUnavailable       MessageID = "settings.unavailable"
+	MsgSettingsServerOnly        MessageID = "settings.server_only"
+	MsgSettingsUnknownSubcommand MessageID = "settings.unknown_subcommand"
+	MsgSettingsLoadFailed        MessageID = "settings.load_failed"
+	MsgSettingsSaveFailed        MessageID = "settings.save_failed"
+	MsgSettingsInvalidValue      MessageID = "settings.invalid_value"
+	MsgSettingsGuildOnly         MessageID = "settings.guild_only"
+	MsgSettingsSavedGuild        MessageID = "settings.saved_guild"
+	MsgSettingsSavedChannel      MessageID = "settings.saved_channel"
+	MsgSettingsShowTitle         MessageID = "settings.show_title"
+	MsgSettingsShowDescription   MessageID = "settings.show_description"
+	MsgSettingsSourceDefault     MessageID = "settings.source_default"
+	MsgSettingsSourceGuild       MessageID = "settings.source_guild"
+	MsgSettingsSourceChannel     MessageID = "settings.source_channel"
+)
+
+// DetectionOverride is the language detector confidence at which a
+// question's own language wins over the client locale. Below it the locale
+// decides, since short or mixed text is easily misdetected.
+const DetectionOverride = 80
+
+// codes maps catalogue file names (ISO 639-1) to languages.
+var codes = map[string]language.Language{
+	"en": language.English,
+	"de": language.German,
+	"fr": language.French,
+	"es": language.Spanish,
+	"it": language.Italian,
+	"nl": language.Dutch,
+	"ru": language.Russian,
+	"ja": language.Japanese,
+	"zh": language.Chinese,
+	"ko": language.Korean,
+}
+
+// discordLocales maps catalogue languages to the Discord client locales they
+// translate. English is the default name and description of every command,
+// so it has none.
+var discordLocales = map[language.Language][]discordgo.Locale{
+	language.German:   {discordgo.German},
+	language.French:   {discordgo.French},
+	language.Spanish:  {discordgo.SpanishES, discordgo.SpanishLATAM},
+	language.Italian:  {discordgo.Italian},
+	language.Dutch:    {discordgo.Dutch},
+	language.Russian:  {discordgo.Russian},
+	language.Japanese: {discordgo.Japanese},
+	language.Chinese:  {discordgo.ChineseCN, discordgo.ChineseTW},
+	language.Korean:   {discordgo.Korean},
+}
+
+//go:embed locales/*.yaml
+var localeFiles embed.FS
+
+// catalogue holds every message by language, loaded from localeFiles.
+var catalogue = mustLoadCatalogue()
+
+func mustLoadCatalogue() map[language.Language]map[MessageID]string {
+	c, err := loadCatalogue()
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func loadCatalogue() (map[language.Language]map[MessageID]string, error) {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read locales: %w", err)
+	}
+
+	c := make(map[language.Language]map[MessageID]string, len(entries))
+	for _, entry := range entries {
+		code := strings.TrimSuffix(entry.Name(), ".yaml")
+		lang, ok := codes[code]
+		if !ok {
+			return nil, fmt.Errorf("unknown locale file %s", entry.Name())
+		}
+
+		data, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read locale %s: %w", code, err)
+		}
+		var messages map[MessageID]string
+		if err := yaml.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("failed to parse locale %s: %w", code, err)
+		}
+		c[lang] = messages
+	}
+
+	if len(c[language.English]) == 0 {
+		return nil, fmt.Errorf("english catalogue is missing")
+	}
+	return c, nil
+}
+
+// T returns message id in lang, formatted with args like fmt.Sprintf.
+// Messages missing from lang fall back to English, and unknown IDs are
+// returned as is so a typo shows up in the reply rather than as a blank.
+func T(lang language.Language, id MessageID, args ...any) string {
+	msg, ok := catalogue[lang][id]
+	if !ok {
+		msg, ok = catalogue[language.English][id]
+	}
+	if !ok {
+		return string(id)
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// Languages returns every language with a catalogue, English first.
+func Languages() []language.Language {
+	langs := make([]language.Language, 0, len(catalogue))
+	for lang := range catalogue {
+		if lang != language.English {
+			langs = append(langs, lang)
+		}
+	}
+	sort.Slice(langs, func(a, b int) bool { return langs[a] < langs[b] })
+	return append([]language.Language{language.English}, langs...)
+}
+
+// Supported reports whether lang has a catalogue.
+func Supported(lang language.Language) bool {
+	_, ok := catalogue[lang]
+	return ok
+}
+
+// FromDiscordLocale returns the catalogue language for a Discord client
+// locale such as "de" or "es-419", or language.Unknown if there is none.
+func FromDiscordLocale(locale discordgo.Locale) language.Language {
+	code, _, _ := strings.Cut(strings.ToLower(string(locale)), "-")
+	if lang, ok := codes[code]; ok && Supported(lang) {
+		return lang
+	}
+	return language.Unknown
+}
+
+// DiscordLocales returns the Discord client locales served by lang's
+// catalogue, or nil for English and unsupported languages.
+func DiscordLocales(lang language.Language) []discordgo.Locale {
+	return discordLocales[lang]
+}
+
+// CommandLocalizations returns the translated name and description of the
+// slash command or option at path, such as "ask" or "faq.remove.id", for
+// every Discord locale whose catalogue defines them. Translations are read
+// from the catalogue keys command.<path>.name and command.<path>.description;
+// English is left out since it is the command's own name and description.
+func CommandLocalizations(path string) (names, descriptions map[discordgo.Locale]string) {
+	names = make(map[discordgo.Locale]string)
+	descriptions = make(map[discordgo.Locale]string)
+	nameID := MessageID("command." + path + ".name")
+	descriptionID := MessageID("command." + path + ".description")
+
+	for lang, locales := range discordLocales {
+		name, hasName := catalogue[lang][nameID]
+		description, hasDescription := catalogue[lang][descriptionID]
+		for _, locale := range locales {
+			if hasName {
+				names[locale] = name
+			}
+			if hasDescription {
+				descriptions[locale] = description
+			}
+		}
+	}
+	return names, descriptions
+}
+
+// ResolveLanguage picks the language to reply in. The Discord client locale
+// is the stronger signal: the language detected in text only wins when the
+// detector is confident (see DetectionOverride), or when the locale is
+// unsupported and the detection is better than a guess. English is the last
+// resort.
+func ResolveLanguage(locale discordgo.Locale, text string) language.Language {
+	fromLocale := FromDiscordLocale(locale)
+
+	if strings.TrimSpace(text) != "" {
+		detected, confidence := language.Detect(text)
+		threshold := DetectionOverride
+		if fromLocale == language.Unknown {
+			threshold = language.MinConfidence
+		}
+		if Supported(detected) && confidence >= threshold {
+			return detected
+		}
+	}
+
+	if fromLocale != language.Unknown {
+		return fromLocale
+	}
+	return language.English
+}
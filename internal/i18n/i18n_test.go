@@ -0,0 +1,164 @@
+package i18n
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"living-lands-bot/pkg/language"
+)
+
+// verbPattern matches fmt verbs; %% is not a verb.
+var verbPattern = regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]+)?[a-zA-Z%]`)
+
+func verbs(msg string) []string {
+	var out []string
+	for _, v := range verbPattern.FindAllString(msg, -1) {
+		if v != "%%" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func TestCataloguesMatchEnglish(t *testing.T) {
+	english := catalogue[language.English]
+
+	for _, lang := range Languages() {
+		messages := catalogue[lang]
+		for id, msg := range english {
+			translated, ok := messages[id]
+			if !assert.True(t, ok, "%s is missing %s", lang, id) {
+				continue
+			}
+			assert.NotEmpty(t, translated, "%s %s", lang, id)
+			assert.Equal(t, verbs(msg), verbs(translated), "%s %s must use the same fmt verbs as English", lang, id)
+		}
+		for id := range messages {
+			_, ok := english[id]
+			assert.True(t, ok, "%s defines %s, which English does not", lang, id)
+		}
+	}
+}
+
+func TestMessageIDsDefined(t *testing.T) {
+	ids := []MessageID{
+		MsgUnknownUser, MsgLinkCodeFailed, MsgLinkCode,
+		MsgAccessDeniedPermission, MsgAccessDeniedRole, MsgAccessDeniedChannel, MsgAccessDeniedLinked, MsgAccessCheckFailed,
+		MsgGuideTitle, MsgGuideDescription, MsgGuideBugs, MsgGuideChangelog, MsgGuideWiki, MsgGuideSupport, MsgGuideComingSoon,
+		MsgAskRateLimited, MsgAskNoQuestion, MsgAskNavigation, MsgAskAccountHelp, MsgAskCurated, MsgAskQueued, MsgAskShed, MsgAskTimeout, MsgAskError, MsgAskAttached, MsgAskWithheld, MsgAskRefused, MsgAskNoAnswer,
+		MsgFeedbackReport, MsgFeedbackUnavailable, MsgFeedbackNotFound, MsgFeedbackFailed, MsgFeedbackReported, MsgFeedbackThanks,
+		MsgFAQUnavailable, MsgFAQNoSubcommand, MsgFAQUnknownSubcommand, MsgFAQListFailed, MsgFAQListEmpty,
+		MsgFAQListTitle, MsgFAQListTruncated, MsgFAQListVariants, MsgFAQRemoveNoID, MsgFAQRemoveNotFound, MsgFAQRemoveFailed,
+		MsgFAQRemoved, MsgFAQModalTitle, MsgFAQModalQuestion, MsgFAQModalVariants, MsgFAQModalAnswer, MsgFAQSaveFailed, MsgFAQSaved,
+		MsgSettingsUnavailable, MsgSettingsServerOnly, MsgSettingsUnknownSubcommand, MsgSettingsLoadFailed, MsgSettingsSaveFailed,
+		MsgSettingsInvalidValue, MsgSettingsGuildOnly, MsgSettingsSavedGuild, MsgSettingsSavedChannel, MsgSettingsShowTitle,
+		MsgSettingsShowDescription, MsgSettingsSourceDefault, MsgSettingsSourceGuild, MsgSettingsSourceChannel,
+	}
+	for _, id := range ids {
+		_, ok := catalogue[language.English][id]
+		assert.True(t, ok, "%s is not in the English catalogue", id)
+	}
+
+	var messages int
+	for id := range catalogue[language.English] {
+		if !strings.HasPrefix(string(id), "command.") {
+			messages++
+		}
+	}
+	assert.Equal(t, len(ids), messages, "every English message outside command.* should have an ID constant")
+}
+
+func TestT(t *testing.T) {
+	assert.Equal(t, "Please provide a question.", T(language.English, MsgAskNoQuestion))
+	assert.Equal(t, "Bitte stelle eine Frage.", T(language.German, MsgAskNoQuestion))
+	assert.Equal(t, "Curated answer #3 removed.", T(language.English, MsgFAQRemoved, 3))
+	assert.Equal(t, "Kuratierte Antwort #3 entfernt.", T(language.German, MsgFAQRemoved, 3))
+
+	// Languages without a catalogue fall back to English
+	assert.Equal(t, "Please provide a question.", T(language.Unknown, MsgAskNoQuestion))
+	assert.Equal(t, "no.such_message", T(language.German, "no.such_message"))
+}
+
+func TestLanguages(t *testing.T) {
+	langs := Languages()
+	require.NotEmpty(t, langs)
+	assert.Equal(t, language.English, langs[0])
+	assert.Len(t, langs, len(codes))
+}
+
+func TestFromDiscordLocale(t *testing.T) {
+	tests := []struct {
+		locale discordgo.Locale
+		want   language.Language
+	}{
+		{discordgo.EnglishUS, language.English},
+		{discordgo.EnglishGB, language.English},
+		{discordgo.German, language.German},
+		{discordgo.SpanishES, language.Spanish},
+		{"es-419", language.Spanish},
+		{discordgo.ChineseCN, language.Chinese},
+		{discordgo.ChineseTW, language.Chinese},
+		{discordgo.Japanese, language.Japanese},
+		{discordgo.Swedish, language.Unknown},
+		{"", language.Unknown},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, FromDiscordLocale(tt.locale), string(tt.locale))
+	}
+}
+
+func TestDiscordLocales(t *testing.T) {
+	assert.Empty(t, DiscordLocales(language.English))
+	assert.Equal(t, []discordgo.Locale{discordgo.German}, DiscordLocales(language.German))
+
+	// Every non-English catalogue serves at least one Discord locale, and each
+	// locale maps back to its catalogue
+	for _, lang := range Languages()[1:] {
+		locales := DiscordLocales(lang)
+		assert.NotEmpty(t, locales, lang)
+		for _, locale := range locales {
+			assert.Equal(t, lang, FromDiscordLocale(locale), string(locale))
+		}
+	}
+}
+
+func TestCommandLocalizations(t *testing.T) {
+	names, descriptions := CommandLocalizations("ask.question")
+	assert.Equal(t, "frage", names[discordgo.German])
+	assert.Equal(t, "pregunta", names[discordgo.SpanishES])
+	assert.Equal(t, "pregunta", names[discordgo.SpanishLATAM])
+	assert.Equal(t, "Deine Frage zur Mod", descriptions[discordgo.German])
+	assert.NotContains(t, names, discordgo.EnglishUS)
+
+	names, descriptions = CommandLocalizations("no.such.command")
+	assert.Empty(t, names)
+	assert.Empty(t, descriptions)
+}
+
+func TestResolveLanguage(t *testing.T) {
+	tests := []struct {
+		name   string
+		locale discordgo.Locale
+		text   string
+		want   language.Language
+	}{
+		{"locale wins over weak detection", discordgo.German, "hytale server ip", language.German},
+		{"confident english wins over locale", discordgo.German, "how does the metabolism system work", language.English},
+		{"locale without text", discordgo.French, "", language.French},
+		{"confident detection wins over locale", discordgo.EnglishUS, "как работает метаболизм", language.Russian},
+		{"detection when locale unsupported", discordgo.Swedish, "wie funktioniert der Stoffwechsel und die Kreaturen", language.German},
+		{"detection without locale", "", "こんにちは", language.Japanese},
+		{"english fallback", discordgo.Swedish, "", language.English},
+		{"english fallback for weak detection", discordgo.Swedish, "hytale server ip", language.English},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ResolveLanguage(tt.locale, tt.text))
+		})
+	}
+}
@@ -0,0 +1,208 @@
+// Package metrics defines the Prometheus collectors for the bot and the
+// handler that serves them on /metrics. Collectors live in a dedicated
+// registry so tests and the HTTP server see exactly what the bot records.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "livinglands"
+
+// Registry holds every collector exported by the bot.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	commandsTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "commands_total",
+		Help:      "Slash commands handled, by command name and outcome.",
+	}, []string{"command", "outcome"})
+
+	commandDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "command_duration_seconds",
+		Help:      "Time from receiving a slash command to sending the final response.",
+		Buckets:   []float64{0.1, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 90},
+	}, []string{"command"})
+
+	intentsTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "intents_total",
+		Help:      "/ask questions by classified intent and how it was decided (rule, embedding or keyword).",
+	}, []string{"intent", "method"})
+
+	ragQueryDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rag_query_duration_seconds",
+		Help:      "RAG query latency (embedding plus vector search), by outcome.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5},
+	}, []string{"outcome"})
+
+	ragDocumentsTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rag_documents_total",
+		Help:      "Documents returned by the vector store, by whether they passed the relevance threshold.",
+	}, []string{"result"})
+
+	ragDistance = factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rag_document_distance",
+		Help:      "Cosine distance of documents returned by the vector store (0 = identical).",
+		Buckets:   []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 1, 1.5, 2},
+	})
+
+	llmDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "llm_generation_duration_seconds",
+		Help:      "LLM generation latency, by response mode and model.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 90},
+	}, []string{"mode", "model"})
+
+	llmTokensTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_tokens_total",
+		Help:      "Tokens processed by the LLM, by response mode, model and kind (prompt or completion).",
+	}, []string{"mode", "model", "kind"})
+
+	llmFailuresTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_failures_total",
+		Help:      "LLM generations that failed on every backend, by response mode.",
+	}, []string{"mode"})
+
+	rateLimitRejectionsTotal = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_rejections_total",
+		Help:      "/ask requests rejected by the per-user rate limiter.",
+	})
+
+	verificationsTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "verifications_total",
+		Help:      "Account verification attempts from the game server, by outcome.",
+	}, []string{"outcome"})
+
+	docGapsTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "doc_gaps_total",
+		Help:      "/ask questions the documentation could not answer, by reason.",
+	}, []string{"reason"})
+
+	outputGuardTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "output_guard_total",
+		Help:      "Generated answers blocked and links filtered by the output guard, by action.",
+	}, []string{"action"})
+
+	inputGuardTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "input_guard_total",
+		Help:      "/ask questions screened by the input guard, by action (allow, warn, refuse) and reason.",
+	}, []string{"action", "reason"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// CommandCompleted records a handled slash command.
+func CommandCompleted(command, outcome string, elapsed time.Duration) {
+	commandsTotal.WithLabelValues(command, outcome).Inc()
+	commandDuration.WithLabelValues(command).Observe(elapsed.Seconds())
+}
+
+// IntentClassified records the intent assigned to an /ask question.
+func IntentClassified(intent, method string) {
+	intentsTotal.WithLabelValues(intent, method).Inc()
+}
+
+// RAGQueryCompleted records the latency of a RAG query.
+func RAGQueryCompleted(elapsed time.Duration, err error) {
+	ragQueryDuration.WithLabelValues(outcome(err)).Observe(elapsed.Seconds())
+}
+
+// RAGDocumentScored records a document returned by the vector store and
+// whether it was kept as context.
+func RAGDocumentScored(distance float32, accepted bool) {
+	result := "filtered"
+	if accepted {
+		result = "accepted"
+	}
+	ragDocumentsTotal.WithLabelValues(result).Inc()
+	ragDistance.Observe(float64(distance))
+}
+
+// LLMGenerationCompleted records a successful generation.
+func LLMGenerationCompleted(mode, model string, elapsed time.Duration, promptTokens, completionTokens int) {
+	llmDuration.WithLabelValues(mode, model).Observe(elapsed.Seconds())
+	llmTokensTotal.WithLabelValues(mode, model, "prompt").Add(float64(promptTokens))
+	llmTokensTotal.WithLabelValues(mode, model, "completion").Add(float64(completionTokens))
+}
+
+// LLMGenerationFailed records a generation that no backend could serve.
+func LLMGenerationFailed(mode string) {
+	llmFailuresTotal.WithLabelValues(mode).Inc()
+}
+
+// RateLimitRejected records an /ask request turned away by the rate limiter.
+func RateLimitRejected() {
+	rateLimitRejectionsTotal.Inc()
+}
+
+// VerificationAttempted records the outcome of a /api/v1/verify call.
+func VerificationAttempted(outcome string) {
+	verificationsTotal.WithLabelValues(outcome).Inc()
+}
+
+// DocGapRecorded records a question captured as a documentation gap.
+func DocGapRecorded(reason string) {
+	docGapsTotal.WithLabelValues(reason).Inc()
+}
+
+// OutputGuarded records an answer blocked by the output guard, by reason.
+func OutputGuarded(reason string) {
+	outputGuardTotal.WithLabelValues(reason).Inc()
+}
+
+// OutputGuardedLinks records links the output guard defanged and invites
+// it removed from an answer.
+func OutputGuardedLinks(defanged, invitesRemoved int) {
+	outputGuardTotal.WithLabelValues("link_defanged").Add(float64(defanged))
+	outputGuardTotal.WithLabelValues("invite_removed").Add(float64(invitesRemoved))
+}
+
+// InputGuarded records the input guard's decision on a question, once per
+// reason, or with reason "none" if no detector fired.
+func InputGuarded(action string, reasons []string) {
+	if len(reasons) == 0 {
+		inputGuardTotal.WithLabelValues(action, "none").Inc()
+		return
+	}
+	for _, reason := range reasons {
+		inputGuardTotal.WithLabelValues(action, reason).Inc()
+	}
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
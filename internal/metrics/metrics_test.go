@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectorsRecord(t *testing.T) {
+	before := testutil.ToFloat64(commandsTotal.WithLabelValues("ask", "success"))
+	CommandCompleted("ask", "success", 2*time.Second)
+	assert.Equal(t, before+1, testutil.ToFloat64(commandsTotal.WithLabelValues("ask", "success")))
+
+	before = testutil.ToFloat64(ragDocumentsTotal.WithLabelValues("filtered"))
+	RAGDocumentScored(0.9, false)
+	assert.Equal(t, before+1, testutil.ToFloat64(ragDocumentsTotal.WithLabelValues("filtered")))
+
+	before = testutil.ToFloat64(llmTokensTotal.WithLabelValues("deep", "mistral", "completion"))
+	LLMGenerationCompleted("deep", "mistral", time.Second, 120, 40)
+	assert.Equal(t, before+40, testutil.ToFloat64(llmTokensTotal.WithLabelValues("deep", "mistral", "completion")))
+
+	before = testutil.ToFloat64(rateLimitRejectionsTotal)
+	RateLimitRejected()
+	assert.Equal(t, before+1, testutil.ToFloat64(rateLimitRejectionsTotal))
+}
+
+func TestHandlerExposesMetrics(t *testing.T) {
+	IntentClassified("knowledge", "embedding")
+	RAGQueryCompleted(100*time.Millisecond, errors.New("chroma down"))
+	VerificationAttempted("expired")
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, 200, rec.Code)
+
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	for _, want := range []string{
+		`livinglands_intents_total{intent="knowledge",method="embedding"}`,
+		`livinglands_rag_query_duration_seconds_count{outcome="error"}`,
+		`livinglands_verifications_total{outcome="expired"}`,
+		"go_goroutines",
+	} {
+		assert.Contains(t, string(body), want)
+	}
+}
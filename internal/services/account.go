@@ -3,6 +3,7 @@ package services
 import (
 	"crypto/rand"
 	"encoding/base32"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -29,7 +30,8 @@ func NewAccountService(db *gorm.DB, expirySeconds int, logger *slog.Logger) *Acc
 
 // GenerateVerificationCode creates a new 8-char code for Discord user
 // discordID should be the Discord user ID as a string (from discordgo)
-func (s *AccountService) GenerateVerificationCode(discordID string, discordUsername string) (string, error) {
+// Links are per guild, so guildID is the guild the code was requested in
+func (s *AccountService) GenerateVerificationCode(guildID, discordID string, discordUsername string) (string, error) {
 	if discordID == "" {
 		return "", fmt.Errorf("discord_id cannot be empty")
 	}
@@ -37,12 +39,13 @@ func (s *AccountService) GenerateVerificationCode(discordID string, discordUsern
 	code := generateCode(8)
 
 	user := &models.User{
+		GuildID:          guildID,
 		DiscordID:        discordID,
 		DiscordUsername:  discordUsername,
 		VerificationCode: code,
 	}
 
-	err := s.db.Where("discord_id = ?", discordID).
+	err := s.db.Where("guild_id = ? AND discord_id = ?", guildID, discordID).
 		Assign(user).
 		FirstOrCreate(user).Error
 
@@ -50,22 +53,28 @@ func (s *AccountService) GenerateVerificationCode(discordID string, discordUsern
 		return "", fmt.Errorf("failed to generate verification code for user %s: %w", discordID, err)
 	}
 
-	s.logger.Info("verification code generated", "discord_id", discordID, "code", code)
+	s.logger.Info("verification code generated", "guild_id", guildID, "discord_id", discordID, "code", code)
 	return code, nil
 }
 
+// Errors returned by VerifyLink for codes that can't be used.
+var (
+	ErrInvalidCode = errors.New("invalid verification code")
+	ErrCodeExpired = errors.New("verification code expired")
+)
+
 // VerifyLink validates code from Hytale and links accounts
 func (s *AccountService) VerifyLink(code, hytaleUsername, hytaleUUID string) error {
 	var user models.User
 
 	err := s.db.Where("verification_code = ?", code).First(&user).Error
 	if err != nil {
-		return fmt.Errorf("invalid verification code")
+		return ErrInvalidCode
 	}
 
 	// Check expiry (code valid for configured duration)
 	if time.Since(user.UpdatedAt) > s.expiry {
-		return fmt.Errorf("verification code expired")
+		return ErrCodeExpired
 	}
 
 	// Update with Hytale info
@@ -80,6 +89,7 @@ func (s *AccountService) VerifyLink(code, hytaleUsername, hytaleUUID string) err
 	}
 
 	s.logger.Info("account linked",
+		"guild_id", user.GuildID,
 		"discord_id", user.DiscordID,
 		"hytale_username", hytaleUsername,
 		"hytale_uuid", hytaleUUID,
@@ -88,6 +98,19 @@ func (s *AccountService) VerifyLink(code, hytaleUsername, hytaleUUID string) err
 	return nil
 }
 
+// IsLinked reports whether the Discord user has linked a Hytale account in
+// the guild.
+func (s *AccountService) IsLinked(guildID, discordID string) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.User{}).
+		Where("guild_id = ? AND discord_id = ? AND verified_at IS NOT NULL", guildID, discordID).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to check link status for user %s: %w", discordID, err)
+	}
+	return count > 0, nil
+}
+
 func generateCode(length int) string {
 	b := make([]byte, length)
 	rand.Read(b)
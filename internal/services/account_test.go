@@ -4,6 +4,12 @@ import (
 	"regexp"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"living-lands-bot/internal/database/models"
+	"living-lands-bot/internal/testutil"
 )
 
 func TestGenerateVerificationCode(t *testing.T) {
@@ -105,3 +111,34 @@ func TestVerificationCodeFormat(t *testing.T) {
 
 	t.Logf("Code format valid: %s", code)
 }
+
+func TestIsLinked(t *testing.T) {
+	db := testutil.NewTestDB(t, &models.User{})
+	svc := NewAccountService(db, 600, getTestLogger())
+
+	linked, err := svc.IsLinked(testGuild, "111")
+	require.NoError(t, err)
+	assert.False(t, linked, "unknown users are not linked")
+
+	code, err := svc.GenerateVerificationCode(testGuild, "111", "player")
+	require.NoError(t, err)
+	linked, err = svc.IsLinked(testGuild, "111")
+	require.NoError(t, err)
+	assert.False(t, linked, "a pending code does not link the account")
+
+	require.NoError(t, svc.VerifyLink(code, "HytalePlayer", "uuid-111"))
+	linked, err = svc.IsLinked(testGuild, "111")
+	require.NoError(t, err)
+	assert.True(t, linked)
+
+	// Links are per guild
+	linked, err = svc.IsLinked("1000000000000000001", "111")
+	require.NoError(t, err)
+	assert.False(t, linked, "a link in one guild does not carry over to another")
+
+	_, err = svc.GenerateVerificationCode("1000000000000000001", "111", "player")
+	require.NoError(t, err)
+	var count int64
+	require.NoError(t, db.Model(&models.User{}).Where("discord_id = ?", "111").Count(&count).Error)
+	assert.Equal(t, int64(2), count, "each guild gets its own row")
+}
@@ -20,17 +20,19 @@ func NewChannelService(db *gorm.DB, logger *slog.Logger) *ChannelService {
 	}
 }
 
-func (s *ChannelService) GetAllRoutes() ([]models.ChannelRoute, error) {
+// GetAllRoutes returns the channel routes of a guild.
+func (s *ChannelService) GetAllRoutes(guildID string) ([]models.ChannelRoute, error) {
 	var routes []models.ChannelRoute
-	if err := s.db.Find(&routes).Error; err != nil {
+	if err := s.db.Where("guild_id = ?", guildID).Find(&routes).Error; err != nil {
 		return nil, err
 	}
 	return routes, nil
 }
 
-func (s *ChannelService) GetRouteByKeyword(keyword string) (*models.ChannelRoute, error) {
+// GetRouteByKeyword returns a guild's route for keyword.
+func (s *ChannelService) GetRouteByKeyword(guildID, keyword string) (*models.ChannelRoute, error) {
 	var route models.ChannelRoute
-	if err := s.db.Where("keyword = ?", keyword).First(&route).Error; err != nil {
+	if err := s.db.Where("guild_id = ? AND keyword = ?", guildID, keyword).First(&route).Error; err != nil {
 		return nil, err
 	}
 	return &route, nil
@@ -5,7 +5,11 @@ import (
 	"os"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"living-lands-bot/internal/database/models"
+	"living-lands-bot/internal/testutil"
 )
 
 func TestChannelServiceInitialization(t *testing.T) {
@@ -171,3 +175,30 @@ func TestChannelRouteDuplicateKeywords(t *testing.T) {
 
 	t.Log("Duplicate keyword handling verified")
 }
+
+func TestChannelRoutesScopedByGuild(t *testing.T) {
+	db := testutil.NewTestDB(t, &models.ChannelRoute{})
+	s := NewChannelService(db, getTestLogger())
+	const otherGuild = "1000000000000000001"
+
+	// The same keyword may point at a different channel in each guild
+	require.NoError(t, db.Create(&[]models.ChannelRoute{
+		{GuildID: testGuild, Keyword: "bugs", ChannelID: "123"},
+		{GuildID: testGuild, Keyword: "wiki", ChannelID: "789"},
+		{GuildID: otherGuild, Keyword: "bugs", ChannelID: "456"},
+	}).Error)
+
+	routes, err := s.GetAllRoutes(testGuild)
+	require.NoError(t, err)
+	assert.Len(t, routes, 2)
+
+	route, err := s.GetRouteByKeyword(otherGuild, "bugs")
+	require.NoError(t, err)
+	assert.Equal(t, "456", route.ChannelID)
+
+	_, err = s.GetRouteByKeyword(otherGuild, "wiki")
+	assert.Error(t, err, "routes of other guilds are not visible")
+
+	err = db.Create(&models.ChannelRoute{GuildID: testGuild, Keyword: "bugs", ChannelID: "999"}).Error
+	assert.Error(t, err, "keywords are unique per guild")
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+const (
+	// BreakerClosed - backend is healthy, requests flow normally
+	BreakerClosed BreakerState = iota
+	// BreakerOpen - backend is failing, requests are rejected until the cooldown elapses
+	BreakerOpen
+	// BreakerHalfOpen - cooldown elapsed, a single probe request is allowed through
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half_open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerSnapshot is a point-in-time view of a breaker for health reporting.
+type BreakerSnapshot struct {
+	Name                string       `json:"name"`
+	State               BreakerState `json:"-"`
+	StateName           string       `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	OpenedAt            *time.Time   `json:"opened_at,omitempty"`
+	LastError           string       `json:"last_error,omitempty"`
+}
+
+// CircuitBreaker trips after a run of consecutive failures or slow responses
+// so callers can skip an overloaded backend instead of waiting out its timeout.
+// Thread-safe.
+type CircuitBreaker struct {
+	name             string
+	failureThreshold int
+	cooldown         time.Duration
+	slowThreshold    time.Duration
+	now              func() time.Time
+
+	mu                  sync.Mutex
+	state               BreakerState
+	consecutiveFailures int
+	openedAt            time.Time
+	probeInFlight       bool
+	lastError           string
+}
+
+// NewCircuitBreaker creates a closed breaker. A response slower than
+// slowThreshold counts as a failure even if it succeeded (0 disables this).
+func NewCircuitBreaker(name string, failureThreshold int, cooldown, slowThreshold time.Duration) *CircuitBreaker {
+	if failureThreshold < 1 {
+		failureThreshold = 1
+	}
+	return &CircuitBreaker{
+		name:             name,
+		failureThreshold: failureThreshold,
+		cooldown:         cooldown,
+		slowThreshold:    slowThreshold,
+		now:              time.Now,
+	}
+}
+
+// Allow reports whether a request may be sent to the backend.
+// An open breaker moves to half-open once the cooldown has elapsed and lets
+// exactly one probe through; its outcome decides whether the breaker closes.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerClosed:
+		return true
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.probeInFlight = true
+		return true
+	case BreakerHalfOpen:
+		if b.probeInFlight {
+			return false
+		}
+		b.probeInFlight = true
+		return true
+	default:
+		return false
+	}
+}
+
+// Record reports the outcome of a request that Allow let through.
+func (b *CircuitBreaker) Record(duration time.Duration, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probeInFlight = false
+
+	failed := err != nil
+	if err != nil {
+		b.lastError = err.Error()
+	} else if b.slowThreshold > 0 && duration > b.slowThreshold {
+		failed = true
+		b.lastError = "slow response: " + duration.Round(time.Millisecond).String()
+	}
+
+	if !failed {
+		b.state = BreakerClosed
+		b.consecutiveFailures = 0
+		return
+	}
+
+	b.consecutiveFailures++
+	if b.state == BreakerHalfOpen || b.consecutiveFailures >= b.failureThreshold {
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// Release ends a request that Allow let through without an outcome, e.g.
+// because the caller gave up. It counts neither as a success nor a failure;
+// a half-open breaker lets the next request probe instead.
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probeInFlight = false
+}
+
+// State returns the current breaker state.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Snapshot returns the breaker's current state for health reporting.
+func (b *CircuitBreaker) Snapshot() BreakerSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	snap := BreakerSnapshot{
+		Name:                b.name,
+		State:               b.state,
+		StateName:           b.state.String(),
+		ConsecutiveFailures: b.consecutiveFailures,
+		LastError:           b.lastError,
+	}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		snap.OpenedAt = &openedAt
+	}
+	return snap
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerTripsAfterConsecutiveFailures(t *testing.T) {
+	b := NewCircuitBreaker("test", 3, time.Minute, 0)
+	errBoom := errors.New("boom")
+
+	for i := 0; i < 2; i++ {
+		assert.True(t, b.Allow())
+		b.Record(time.Millisecond, errBoom)
+	}
+	assert.Equal(t, BreakerClosed, b.State())
+
+	// A success resets the run of failures
+	b.Record(time.Millisecond, nil)
+	assert.Equal(t, 0, b.Snapshot().ConsecutiveFailures)
+
+	for i := 0; i < 3; i++ {
+		b.Record(time.Millisecond, errBoom)
+	}
+	assert.Equal(t, BreakerOpen, b.State())
+	assert.False(t, b.Allow())
+
+	snap := b.Snapshot()
+	assert.Equal(t, "open", snap.StateName)
+	assert.Equal(t, "boom", snap.LastError)
+	assert.NotNil(t, snap.OpenedAt)
+}
+
+func TestCircuitBreakerSlowResponsesCountAsFailures(t *testing.T) {
+	b := NewCircuitBreaker("test", 2, time.Minute, time.Second)
+
+	b.Record(2*time.Second, nil)
+	b.Record(3*time.Second, nil)
+
+	assert.Equal(t, BreakerOpen, b.State())
+	assert.Contains(t, b.Snapshot().LastError, "slow response")
+}
+
+func TestCircuitBreakerHalfOpenProbe(t *testing.T) {
+	now := time.Now()
+	b := NewCircuitBreaker("test", 1, time.Minute, 0)
+	b.now = func() time.Time { return now }
+
+	b.Record(time.Millisecond, errors.New("boom"))
+	assert.False(t, b.Allow())
+
+	// After the cooldown exactly one probe is let through
+	now = now.Add(2 * time.Minute)
+	assert.True(t, b.Allow())
+	assert.Equal(t, BreakerHalfOpen, b.State())
+	assert.False(t, b.Allow())
+
+	// A failed probe reopens immediately
+	b.Record(time.Millisecond, errors.New("still down"))
+	assert.Equal(t, BreakerOpen, b.State())
+
+	// A successful probe closes the breaker
+	now = now.Add(2 * time.Minute)
+	assert.True(t, b.Allow())
+	b.Record(time.Millisecond, nil)
+	assert.Equal(t, BreakerClosed, b.State())
+	assert.True(t, b.Allow())
+}
+
+func TestCircuitBreakerReleaseProbe(t *testing.T) {
+	now := time.Now()
+	b := NewCircuitBreaker("test", 1, time.Minute, 0)
+	b.now = func() time.Time { return now }
+
+	b.Record(time.Millisecond, errors.New("boom"))
+	now = now.Add(2 * time.Minute)
+	assert.True(t, b.Allow())
+
+	// A released probe leaves the breaker half-open for the next request
+	b.Release()
+	assert.Equal(t, BreakerHalfOpen, b.State())
+	assert.Equal(t, 1, b.Snapshot().ConsecutiveFailures)
+	assert.True(t, b.Allow())
+}
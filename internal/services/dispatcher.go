@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+)
+
+// ErrQueueFull is returned by Acquire when too many requests are already
+// waiting; callers should shed the request rather than wait.
+var ErrQueueFull = errors.New("llm queue is full")
+
+// Priority orders waiting requests. Lower values are served first.
+type Priority int
+
+const (
+	// PriorityHigh - cheap requests (fast mode) that should not wait behind deep answers
+	PriorityHigh Priority = iota
+	// PriorityNormal - standard and deep mode generations
+	PriorityNormal
+)
+
+// PriorityForMode maps a response mode to its queue priority.
+func PriorityForMode(mode ResponseMode) Priority {
+	if mode == ModeFast {
+		return PriorityHigh
+	}
+	return PriorityNormal
+}
+
+// DispatcherConfig bounds concurrent LLM generations.
+type DispatcherConfig struct {
+	MaxConcurrent int // Generations running at once across all guilds
+	MaxPerGuild   int // Generations running at once for a single guild (0 = no limit)
+	MaxQueueDepth int // Requests allowed to wait; beyond this Acquire fails with ErrQueueFull
+}
+
+// dispatchWaiter is a request waiting for a generation slot.
+type dispatchWaiter struct {
+	guildID  string
+	priority Priority
+	seq      uint64
+	ready    chan struct{}
+	granted  bool
+}
+
+// LLMDispatcher is a bounded priority queue in front of the LLM backends.
+// Requests are granted slots in (priority, arrival) order, skipping any whose
+// guild is already at its per-guild limit. Thread-safe.
+type LLMDispatcher struct {
+	config DispatcherConfig
+	logger *slog.Logger
+
+	mu       sync.Mutex
+	active   int
+	perGuild map[string]int
+	queue    []*dispatchWaiter // sorted by priority, then seq
+	seq      uint64
+}
+
+// NewLLMDispatcher creates a dispatcher. MaxConcurrent is clamped to at least 1.
+func NewLLMDispatcher(config DispatcherConfig, logger *slog.Logger) *LLMDispatcher {
+	if config.MaxConcurrent < 1 {
+		config.MaxConcurrent = 1
+	}
+	return &LLMDispatcher{
+		config:   config,
+		logger:   logger,
+		perGuild: make(map[string]int),
+	}
+}
+
+// Acquire waits for a generation slot and returns a function that releases it.
+// If the request has to wait, onQueued (when non-nil) is called once with its
+// 1-based position in line before blocking. Acquire returns ErrQueueFull when
+// the queue is already at MaxQueueDepth, or ctx.Err() if ctx ends first.
+func (d *LLMDispatcher) Acquire(ctx context.Context, guildID string, priority Priority, onQueued func(position int)) (func(), error) {
+	d.mu.Lock()
+	d.seq++
+	w := &dispatchWaiter{
+		guildID:  guildID,
+		priority: priority,
+		seq:      d.seq,
+		ready:    make(chan struct{}),
+	}
+	d.insertLocked(w)
+	d.dispatchLocked()
+
+	if w.granted {
+		d.mu.Unlock()
+		return d.releaseFunc(guildID), nil
+	}
+
+	if len(d.queue) > d.config.MaxQueueDepth {
+		d.removeLocked(w)
+		depth := len(d.queue)
+		d.mu.Unlock()
+		d.logger.Warn("llm queue full, shedding request", "guild_id", guildID, "queue_depth", depth)
+		return nil, ErrQueueFull
+	}
+
+	position := d.positionLocked(w)
+	d.mu.Unlock()
+
+	d.logger.Debug("llm request queued", "guild_id", guildID, "priority", priority, "position", position)
+	if onQueued != nil {
+		onQueued(position)
+	}
+
+	select {
+	case <-w.ready:
+		return d.releaseFunc(guildID), nil
+	case <-ctx.Done():
+		d.mu.Lock()
+		if w.granted {
+			// Granted while we were giving up; hand the slot to the next waiter
+			d.releaseLocked(guildID)
+		} else {
+			d.removeLocked(w)
+		}
+		d.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// QueueDepth returns the number of requests waiting for a slot.
+func (d *LLMDispatcher) QueueDepth() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.queue)
+}
+
+// Active returns the number of generations currently holding a slot.
+func (d *LLMDispatcher) Active() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.active
+}
+
+func (d *LLMDispatcher) releaseFunc(guildID string) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			d.mu.Lock()
+			d.releaseLocked(guildID)
+			d.mu.Unlock()
+		})
+	}
+}
+
+func (d *LLMDispatcher) releaseLocked(guildID string) {
+	d.active--
+	d.perGuild[guildID]--
+	if d.perGuild[guildID] <= 0 {
+		delete(d.perGuild, guildID)
+	}
+	d.dispatchLocked()
+}
+
+// dispatchLocked grants free slots to waiters in queue order.
+func (d *LLMDispatcher) dispatchLocked() {
+	for i := 0; i < len(d.queue) && d.active < d.config.MaxConcurrent; {
+		w := d.queue[i]
+		if d.config.MaxPerGuild > 0 && d.perGuild[w.guildID] >= d.config.MaxPerGuild {
+			i++
+			continue
+		}
+
+		d.queue = append(d.queue[:i], d.queue[i+1:]...)
+		d.active++
+		d.perGuild[w.guildID]++
+		w.granted = true
+		close(w.ready)
+	}
+}
+
+func (d *LLMDispatcher) insertLocked(w *dispatchWaiter) {
+	i := sort.Search(len(d.queue), func(i int) bool {
+		q := d.queue[i]
+		return q.priority > w.priority || (q.priority == w.priority && q.seq > w.seq)
+	})
+	d.queue = append(d.queue, nil)
+	copy(d.queue[i+1:], d.queue[i:])
+	d.queue[i] = w
+}
+
+func (d *LLMDispatcher) removeLocked(w *dispatchWaiter) {
+	for i, q := range d.queue {
+		if q == w {
+			d.queue = append(d.queue[:i], d.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+func (d *LLMDispatcher) positionLocked(w *dispatchWaiter) int {
+	for i, q := range d.queue {
+		if q == w {
+			return i + 1
+		}
+	}
+	return 0
+}
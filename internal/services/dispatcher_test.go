@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// acquireAsync starts an Acquire in the background and returns a channel that
+// receives its release func once granted, plus the reported queue position.
+func acquireAsync(t *testing.T, d *LLMDispatcher, ctx context.Context, guildID string, priority Priority) (<-chan func(), int) {
+	t.Helper()
+
+	granted := make(chan func(), 1)
+	queuedAt := make(chan int, 1)
+	go func() {
+		release, err := d.Acquire(ctx, guildID, priority, func(position int) { queuedAt <- position })
+		if err == nil {
+			granted <- release
+		}
+	}()
+
+	select {
+	case pos := <-queuedAt:
+		return granted, pos
+	case <-time.After(time.Second):
+		t.Fatal("request was not queued")
+		return nil, 0
+	}
+}
+
+func TestDispatcherGrantsUpToMaxConcurrent(t *testing.T) {
+	d := NewLLMDispatcher(DispatcherConfig{MaxConcurrent: 2, MaxQueueDepth: 5}, getTestLogger())
+	ctx := context.Background()
+
+	r1, err := d.Acquire(ctx, "g1", PriorityNormal, nil)
+	require.NoError(t, err)
+	_, err = d.Acquire(ctx, "g1", PriorityNormal, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, d.Active())
+
+	granted, pos := acquireAsync(t, d, ctx, "g1", PriorityNormal)
+	assert.Equal(t, 1, pos)
+	assert.Equal(t, 1, d.QueueDepth())
+
+	r1()
+	r1() // releasing twice is a no-op
+	select {
+	case <-granted:
+	case <-time.After(time.Second):
+		t.Fatal("waiter not granted after release")
+	}
+	assert.Equal(t, 2, d.Active())
+	assert.Equal(t, 0, d.QueueDepth())
+}
+
+func TestDispatcherHighPriorityJumpsQueue(t *testing.T) {
+	d := NewLLMDispatcher(DispatcherConfig{MaxConcurrent: 1, MaxQueueDepth: 5}, getTestLogger())
+	ctx := context.Background()
+
+	hold, err := d.Acquire(ctx, "g1", PriorityNormal, nil)
+	require.NoError(t, err)
+
+	normal, pos := acquireAsync(t, d, ctx, "g1", PriorityNormal)
+	assert.Equal(t, 1, pos)
+	fast, pos := acquireAsync(t, d, ctx, "g1", PriorityHigh)
+	assert.Equal(t, 1, pos, "fast request should be first in line")
+
+	hold()
+	select {
+	case release := <-fast:
+		release()
+	case <-normal:
+		t.Fatal("normal request served before fast request")
+	case <-time.After(time.Second):
+		t.Fatal("no waiter granted")
+	}
+
+	select {
+	case <-normal:
+	case <-time.After(time.Second):
+		t.Fatal("normal request never granted")
+	}
+}
+
+func TestDispatcherPerGuildLimit(t *testing.T) {
+	d := NewLLMDispatcher(DispatcherConfig{MaxConcurrent: 2, MaxPerGuild: 1, MaxQueueDepth: 5}, getTestLogger())
+	ctx := context.Background()
+
+	_, err := d.Acquire(ctx, "busy", PriorityNormal, nil)
+	require.NoError(t, err)
+
+	// Same guild waits even though a global slot is free
+	_, pos := acquireAsync(t, d, ctx, "busy", PriorityNormal)
+	assert.Equal(t, 1, pos)
+
+	// Another guild gets the free slot straight away
+	_, err = d.Acquire(ctx, "quiet", PriorityNormal, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, d.Active())
+}
+
+func TestDispatcherShedsWhenQueueFull(t *testing.T) {
+	d := NewLLMDispatcher(DispatcherConfig{MaxConcurrent: 1, MaxQueueDepth: 1}, getTestLogger())
+	ctx := context.Background()
+
+	_, err := d.Acquire(ctx, "g1", PriorityNormal, nil)
+	require.NoError(t, err)
+	acquireAsync(t, d, ctx, "g1", PriorityNormal)
+
+	_, err = d.Acquire(ctx, "g1", PriorityHigh, func(int) { t.Error("shed request must not be queued") })
+	assert.True(t, errors.Is(err, ErrQueueFull))
+	assert.Equal(t, 1, d.QueueDepth())
+}
+
+func TestDispatcherCancelledWaiterLeavesQueue(t *testing.T) {
+	d := NewLLMDispatcher(DispatcherConfig{MaxConcurrent: 1, MaxQueueDepth: 5}, getTestLogger())
+
+	hold, err := d.Acquire(context.Background(), "g1", PriorityNormal, nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = d.Acquire(ctx, "g1", PriorityNormal, nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 0, d.QueueDepth())
+
+	hold()
+	assert.Equal(t, 0, d.Active())
+}
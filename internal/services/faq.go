@@ -0,0 +1,282 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+
+	"living-lands-bot/internal/database/models"
+	"living-lands-bot/pkg/llm"
+)
+
+// DefaultFAQSimilarity is the minimum cosine similarity between an /ask
+// question and an FAQ variant for the curated answer to be used. It is
+// deliberately strict: a wrong curated answer is worse than a generated one.
+const DefaultFAQSimilarity = 0.9
+
+// ErrFAQEntryNotFound is returned when an FAQ entry does not exist.
+var ErrFAQEntryNotFound = errors.New("faq entry not found")
+
+// FAQMatch is the curated answer for an /ask question.
+type FAQMatch struct {
+	EntryID    uint
+	Answer     string
+	Variant    string  // The variant the question matched
+	Similarity float32 // Cosine similarity to that variant
+}
+
+// faqVariant is an indexed variant held in memory for matching.
+type faqVariant struct {
+	guildID   string
+	entryID   uint
+	text      string
+	key       string
+	embedding []float32
+}
+
+// FAQService manages curated FAQ answers and matches questions against them.
+// Each guild curates its own answers. Variants are kept in memory; the table
+// is small and changes only through the admin commands. Thread-safe.
+type FAQService struct {
+	db         *gorm.DB
+	embedder   llm.Embedder
+	embedModel string
+	similarity float32
+	logger     *slog.Logger
+
+	mu       sync.RWMutex
+	answers  map[uint]string
+	variants []faqVariant
+}
+
+func NewFAQService(db *gorm.DB, embedder llm.Embedder, embedModel string, logger *slog.Logger) *FAQService {
+	return &FAQService{
+		db:         db,
+		embedder:   embedder,
+		embedModel: embedModel,
+		similarity: DefaultFAQSimilarity,
+		logger:     logger,
+		answers:    make(map[uint]string),
+	}
+}
+
+// SetSimilarity sets the minimum cosine similarity for a match.
+func (s *FAQService) SetSimilarity(similarity float32) {
+	s.similarity = similarity
+}
+
+// Load reads all entries into memory, embedding any variant stored without
+// an embedding (e.g. after the embedding model changed and they were cleared).
+// A variant that fails to embed is still indexed for exact matches; Load
+// returns an error counting those variants so the caller can retry them.
+func (s *FAQService) Load(ctx context.Context) error {
+	var entries []models.FAQEntry
+	if err := s.db.Preload("Variants").Order("id ASC").Find(&entries).Error; err != nil {
+		return fmt.Errorf("failed to load faq entries: %w", err)
+	}
+
+	var pending int
+	var embedErr error
+	for i := range entries {
+		for j := range entries[i].Variants {
+			v := &entries[i].Variants[j]
+			if len(v.Embedding) > 0 {
+				continue
+			}
+			embedding, err := s.embedder.Embed(ctx, s.embedModel, v.Text)
+			if err != nil {
+				s.logger.Debug("faq variant embedding failed", "variant_id", v.ID, "error", err)
+				pending++
+				embedErr = err
+				continue
+			}
+			v.Embedding = embedding
+			if err := s.db.Model(v).Update("embedding", v.Embedding).Error; err != nil {
+				return fmt.Errorf("failed to store faq variant embedding: %w", err)
+			}
+		}
+	}
+
+	var lastID uint
+	if len(entries) > 0 {
+		lastID = entries[len(entries)-1].ID
+	}
+
+	s.mu.Lock()
+	// Entries added while Load was embedding are newer than anything it read
+	// and would otherwise drop out of the index until the next load.
+	added := make(map[uint]string)
+	var addedVariants []faqVariant
+	for _, v := range s.variants {
+		if v.entryID > lastID {
+			added[v.entryID] = s.answers[v.entryID]
+			addedVariants = append(addedVariants, v)
+		}
+	}
+	s.answers = make(map[uint]string, len(entries)+len(added))
+	s.variants = nil
+	for _, e := range entries {
+		s.indexLocked(e)
+	}
+	for id, answer := range added {
+		s.answers[id] = answer
+	}
+	s.variants = append(s.variants, addedVariants...)
+	count := len(s.variants)
+	s.mu.Unlock()
+
+	s.logger.Info("faq entries loaded", "entries", len(entries), "variants", count, "pending", pending)
+	if pending > 0 {
+		return fmt.Errorf("%d faq variants not embedded: %w", pending, embedErr)
+	}
+	return nil
+}
+
+// Match returns the guild's curated answer for a question, or nil if no
+// variant is similar enough. A question identical to a variant (ignoring case
+// and trailing punctuation) matches without embedding.
+func (s *FAQService) Match(ctx context.Context, guildID, question string) (*FAQMatch, error) {
+	key := normalizeQuestion(question)
+
+	s.mu.RLock()
+	empty := true
+	for _, v := range s.variants {
+		if v.guildID != guildID {
+			continue
+		}
+		empty = false
+		if v.key == key {
+			match := &FAQMatch{EntryID: v.entryID, Answer: s.answers[v.entryID], Variant: v.text, Similarity: 1}
+			s.mu.RUnlock()
+			return match, nil
+		}
+	}
+	s.mu.RUnlock()
+
+	if empty {
+		return nil, nil
+	}
+
+	embedding, err := s.embedder.Embed(ctx, s.embedModel, question)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed question: %w", err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var best *faqVariant
+	var bestScore float32
+	for idx := range s.variants {
+		v := &s.variants[idx]
+		if v.guildID != guildID {
+			continue
+		}
+		if score := cosineSimilarity(embedding, v.embedding); score >= s.similarity && score > bestScore {
+			best, bestScore = v, score
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	return &FAQMatch{EntryID: best.entryID, Answer: s.answers[best.entryID], Variant: best.text, Similarity: bestScore}, nil
+}
+
+// Add stores a curated answer for the guild. The canonical question is stored
+// as the first variant; blank and duplicate variants are skipped.
+func (s *FAQService) Add(ctx context.Context, guildID, question string, variants []string, answer, createdBy string) (*models.FAQEntry, error) {
+	question = strings.TrimSpace(question)
+	answer = strings.TrimSpace(answer)
+	if question == "" || answer == "" {
+		return nil, fmt.Errorf("faq question and answer are required")
+	}
+
+	entry := &models.FAQEntry{GuildID: guildID, Question: question, Answer: answer, CreatedBy: createdBy}
+	seen := make(map[string]bool)
+	for _, text := range append([]string{question}, variants...) {
+		text = strings.TrimSpace(text)
+		key := normalizeQuestion(text)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		embedding, err := s.embedder.Embed(ctx, s.embedModel, text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed faq variant: %w", err)
+		}
+		entry.Variants = append(entry.Variants, models.FAQVariant{Text: text, Embedding: embedding})
+	}
+
+	if err := s.db.Create(entry).Error; err != nil {
+		return nil, fmt.Errorf("failed to create faq entry: %w", err)
+	}
+
+	s.mu.Lock()
+	s.indexLocked(*entry)
+	s.mu.Unlock()
+
+	s.logger.Info("faq entry added", "faq_id", entry.ID, "guild_id", guildID, "variants", len(entry.Variants), "created_by", createdBy)
+	return entry, nil
+}
+
+// Remove deletes one of the guild's entries and its variants.
+func (s *FAQService) Remove(guildID string, id uint) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND guild_id = ?", id, guildID).Delete(&models.FAQEntry{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrFAQEntryNotFound
+		}
+		return tx.Where("faq_entry_id = ?", id).Delete(&models.FAQVariant{}).Error
+	})
+	if errors.Is(err, ErrFAQEntryNotFound) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove faq entry: %w", err)
+	}
+
+	s.mu.Lock()
+	delete(s.answers, id)
+	kept := s.variants[:0]
+	for _, v := range s.variants {
+		if v.entryID != id {
+			kept = append(kept, v)
+		}
+	}
+	s.variants = kept
+	s.mu.Unlock()
+
+	s.logger.Info("faq entry removed", "faq_id", id, "guild_id", guildID)
+	return nil
+}
+
+// List returns the guild's entries with their variants, oldest first.
+func (s *FAQService) List(guildID string) ([]models.FAQEntry, error) {
+	var entries []models.FAQEntry
+	if err := s.db.Preload("Variants").Where("guild_id = ?", guildID).Order("id ASC").Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to list faq entries: %w", err)
+	}
+	return entries, nil
+}
+
+func (s *FAQService) indexLocked(entry models.FAQEntry) {
+	s.answers[entry.ID] = entry.Answer
+	for _, v := range entry.Variants {
+		s.variants = append(s.variants, faqVariant{
+			guildID:   entry.GuildID,
+			entryID:   entry.ID,
+			text:      v.Text,
+			key:       normalizeQuestion(v.Text),
+			embedding: v.Embedding,
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"living-lands-bot/internal/database/models"
+	"living-lands-bot/internal/testutil"
+	"living-lands-bot/pkg/ollama"
+)
+
+func newTestFAQService(t *testing.T) (*FAQService, *testutil.OllamaServer) {
+	t.Helper()
+	db := testutil.NewTestDB(t, &models.FAQEntry{}, &models.FAQVariant{})
+	ollamaServer := testutil.NewOllamaServer(t)
+	faq := NewFAQService(db, ollama.NewClient(ollamaServer.URL), "nomic-embed-text", getTestLogger())
+	return faq, ollamaServer
+}
+
+func TestFAQServiceMatch(t *testing.T) {
+	faq, ollamaServer := newTestFAQService(t)
+	ctx := context.Background()
+
+	match, err := faq.Match(ctx, testGuild, "where can I download the mod")
+	require.NoError(t, err)
+	assert.Nil(t, match)
+	assert.Empty(t, ollamaServer.EmbedRequests(), "an empty FAQ should not embed questions")
+
+	entry, err := faq.Add(ctx, testGuild, "Where can I download the mod?", []string{"download link", " ", "where can i download the mod"}, "From CurseForge.", "admin")
+	require.NoError(t, err)
+	require.Len(t, entry.Variants, 2, "blank and duplicate variants are skipped")
+
+	// Exact phrasing (ignoring case and punctuation) matches without embedding
+	embeds := len(ollamaServer.EmbedRequests())
+	match, err = faq.Match(ctx, testGuild, "WHERE can I download the mod")
+	require.NoError(t, err)
+	require.NotNil(t, match)
+	assert.Equal(t, "From CurseForge.", match.Answer)
+	assert.Equal(t, float32(1), match.Similarity)
+	assert.Len(t, ollamaServer.EmbedRequests(), embeds)
+
+	// Close phrasings match by embedding once they clear the threshold
+	match, err = faq.Match(ctx, testGuild, "the download link")
+	require.NoError(t, err)
+	assert.Nil(t, match, "below the similarity threshold")
+
+	faq.SetSimilarity(0.5)
+	match, err = faq.Match(ctx, testGuild, "the download link")
+	require.NoError(t, err)
+	require.NotNil(t, match)
+	assert.Equal(t, entry.ID, match.EntryID)
+	assert.Equal(t, "download link", match.Variant)
+}
+
+func TestFAQServiceRemoveAndLoad(t *testing.T) {
+	faq, ollamaServer := newTestFAQService(t)
+	ctx := context.Background()
+
+	keep, err := faq.Add(ctx, testGuild, "What Hytale version is supported?", nil, "Hytale 1.0.", "admin")
+	require.NoError(t, err)
+	drop, err := faq.Add(ctx, testGuild, "How do I install the mod?", nil, "Copy it into your mods folder.", "admin")
+	require.NoError(t, err)
+
+	require.NoError(t, faq.Remove(testGuild, drop.ID))
+	assert.ErrorIs(t, faq.Remove(testGuild, drop.ID), ErrFAQEntryNotFound)
+
+	match, err := faq.Match(ctx, testGuild, "How do I install the mod?")
+	require.NoError(t, err)
+	assert.Nil(t, match)
+
+	// A fresh service loads the remaining entry and backfills missing embeddings
+	require.NoError(t, faq.db.Model(&models.FAQVariant{}).Where("faq_entry_id = ?", keep.ID).Update("embedding", nil).Error)
+	reloaded := NewFAQService(faq.db, ollama.NewClient(ollamaServer.URL), "nomic-embed-text", getTestLogger())
+	require.NoError(t, reloaded.Load(ctx))
+
+	entries, err := reloaded.List(testGuild)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.NotEmpty(t, entries[0].Variants[0].Embedding)
+
+	match, err = reloaded.Match(ctx, testGuild, "what hytale version is supported")
+	require.NoError(t, err)
+	require.NotNil(t, match)
+	assert.Equal(t, "Hytale 1.0.", match.Answer)
+}
+
+func TestFAQServiceLoadSkipsFailedEmbeddings(t *testing.T) {
+	faq, ollamaServer := newTestFAQService(t)
+	ctx := context.Background()
+
+	embedded, err := faq.Add(ctx, testGuild, "What Hytale version is supported?", nil, "Hytale 1.0.", "admin")
+	require.NoError(t, err)
+	cleared, err := faq.Add(ctx, testGuild, "How do I install the mod?", nil, "Copy it into your mods folder.", "admin")
+	require.NoError(t, err)
+	require.NoError(t, faq.db.Model(&models.FAQVariant{}).Where("faq_entry_id = ?", cleared.ID).Update("embedding", nil).Error)
+
+	// The embedding model is down: the variant that still needs one is
+	// counted as pending, and everything else is indexed
+	ollamaServer.FailEmbeddings(http.StatusServiceUnavailable)
+	reloaded := NewFAQService(faq.db, ollama.NewClient(ollamaServer.URL), "nomic-embed-text", getTestLogger())
+	assert.ErrorContains(t, reloaded.Load(ctx), "1 faq variants not embedded")
+
+	for _, question := range []string{"What Hytale version is supported?", "How do I install the mod?"} {
+		match, err := reloaded.Match(ctx, testGuild, question)
+		require.NoError(t, err)
+		require.NotNil(t, match, question)
+	}
+	assert.NotEmpty(t, reloaded.variants[0].embedding)
+	assert.Equal(t, embedded.ID, reloaded.variants[0].entryID)
+
+	// A retry once the model is back embeds the rest
+	ollamaServer.FailEmbeddings(0)
+	require.NoError(t, reloaded.Load(ctx))
+
+	for _, v := range reloaded.variants {
+		assert.NotEmpty(t, v.embedding, "entry %d", v.entryID)
+	}
+}
+
+func TestFAQServiceScopesByGuild(t *testing.T) {
+	faq, _ := newTestFAQService(t)
+	ctx := context.Background()
+	const otherGuild = "1000000000000000001"
+
+	entry, err := faq.Add(ctx, testGuild, "Where can I download the mod?", nil, "From CurseForge.", "admin")
+	require.NoError(t, err)
+
+	match, err := faq.Match(ctx, otherGuild, "where can I download the mod")
+	require.NoError(t, err)
+	assert.Nil(t, match, "another guild's curated answers are not used")
+
+	entries, err := faq.List(otherGuild)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	assert.ErrorIs(t, faq.Remove(otherGuild, entry.ID), ErrFAQEntryNotFound)
+	entries, err = faq.List(testGuild)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "another guild cannot remove the entry")
+}
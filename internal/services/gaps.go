@@ -0,0 +1,284 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"living-lands-bot/internal/database/models"
+	"living-lands-bot/internal/metrics"
+	"living-lands-bot/pkg/llm"
+)
+
+// DefaultGapSimilarity is the minimum cosine similarity for two unanswered
+// questions to be grouped into the same topic.
+const DefaultGapSimilarity = 0.8
+
+// maxGapExamples caps the example questions kept per topic.
+const maxGapExamples = 3
+
+// NoAnswerMarker is what deep-mode prompts ask the model to start an answer
+// with when the documentation does not cover the question. Unlike the wording
+// that follows it, the marker is the same in every reply language.
+const NoAnswerMarker = "[NO_ANSWER]"
+
+// noAnswerMarker also matches the marker with the case or spacing a model
+// sometimes gets wrong.
+var noAnswerMarker = regexp.MustCompile(`(?i)\[\s*no[_ ]answer\s*\]`)
+
+// StripNoAnswer removes the no-answer marker from an answer and reports
+// whether it was there.
+func StripNoAnswer(answer string) (string, bool) {
+	if !noAnswerMarker.MatchString(answer) {
+		return answer, false
+	}
+	return strings.TrimSpace(noAnswerMarker.ReplaceAllString(answer, "")), true
+}
+
+// GapCluster is a group of similar unanswered questions.
+type GapCluster struct {
+	Topic     string   // Most-asked question in the group
+	Count     int      // Number of times any question in the group was asked
+	Examples  []string // Distinct questions, most-asked first
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// GapService records questions the archives could not answer and groups them
+// by embedding similarity so the wiki writers know what is missing.
+type GapService struct {
+	db         *gorm.DB
+	embedder   llm.Embedder
+	embedModel string
+	similarity float32
+	logger     *slog.Logger
+}
+
+func NewGapService(db *gorm.DB, embedder llm.Embedder, embedModel string, logger *slog.Logger) *GapService {
+	return &GapService{
+		db:         db,
+		embedder:   embedder,
+		embedModel: embedModel,
+		similarity: DefaultGapSimilarity,
+		logger:     logger,
+	}
+}
+
+// SetSimilarity sets the minimum cosine similarity for grouping questions.
+func (s *GapService) SetSimilarity(similarity float32) {
+	s.similarity = similarity
+}
+
+// Record stores an unanswered question. The question is embedded for
+// clustering; if embedding fails the gap is still stored and embedded again
+// when a report is built.
+func (s *GapService) Record(ctx context.Context, gap *models.DocGap) error {
+	if len(gap.Embedding) == 0 {
+		embedding, err := s.embed(ctx, gap.Question)
+		if err != nil {
+			s.logger.Warn("failed to embed gap question", "error", err)
+		}
+		gap.Embedding = embedding
+	}
+
+	if err := s.db.Create(gap).Error; err != nil {
+		return fmt.Errorf("failed to record doc gap: %w", err)
+	}
+
+	metrics.DocGapRecorded(gap.Reason)
+	s.logger.Info("documentation gap recorded", "gap_id", gap.ID, "reason", gap.Reason)
+	return nil
+}
+
+// Report groups the gaps recorded in a guild since the given time and returns
+// the largest groups first. An empty guildID reports every guild. A limit of 0
+// returns every group.
+func (s *GapService) Report(ctx context.Context, guildID string, since time.Time, limit int) ([]GapCluster, error) {
+	query := s.db.Where("created_at >= ?", since)
+	if guildID != "" {
+		query = query.Where("guild_id = ?", guildID)
+	}
+	var gaps []models.DocGap
+	if err := query.Order("created_at ASC").Find(&gaps).Error; err != nil {
+		return nil, fmt.Errorf("failed to load doc gaps: %w", err)
+	}
+
+	// Backfill embeddings that failed at capture time
+	for i := range gaps {
+		if len(gaps[i].Embedding) > 0 {
+			continue
+		}
+		embedding, err := s.embed(ctx, gaps[i].Question)
+		if err != nil {
+			s.logger.Warn("failed to embed gap question", "error", err, "gap_id", gaps[i].ID)
+			continue
+		}
+		gaps[i].Embedding = embedding
+		if err := s.db.Model(&gaps[i]).Update("embedding", gaps[i].Embedding).Error; err != nil {
+			s.logger.Warn("failed to store gap embedding", "error", err, "gap_id", gaps[i].ID)
+		}
+	}
+
+	clusters := clusterGaps(gaps, s.similarity)
+	if limit > 0 && len(clusters) > limit {
+		clusters = clusters[:limit]
+	}
+	return clusters, nil
+}
+
+// LastDigest returns when a digest was last posted to the channel, or the
+// zero time if none has been.
+func (s *GapService) LastDigest(channelID string) (time.Time, error) {
+	var digest models.GapDigest
+	err := s.db.Where("channel_id = ?", channelID).Order("posted_at DESC").First(&digest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to load last gap digest: %w", err)
+	}
+	return digest.PostedAt, nil
+}
+
+// RecordDigest notes that a digest was posted to the channel.
+func (s *GapService) RecordDigest(channelID string, postedAt time.Time) error {
+	if err := s.db.Create(&models.GapDigest{ChannelID: channelID, PostedAt: postedAt}).Error; err != nil {
+		return fmt.Errorf("failed to record gap digest: %w", err)
+	}
+	return nil
+}
+
+func (s *GapService) embed(ctx context.Context, question string) ([]float32, error) {
+	if s.embedder == nil {
+		return nil, nil
+	}
+	return s.embedder.Embed(ctx, s.embedModel, question)
+}
+
+// gapGroup accumulates a cluster while grouping.
+type gapGroup struct {
+	centroid  []float32 // Sum of member embeddings; cosine ignores the scale
+	questions map[string]*gapQuestion
+	cluster   GapCluster
+}
+
+type gapQuestion struct {
+	text  string
+	count int
+	first time.Time
+}
+
+// clusterGaps greedily assigns each gap, oldest first, to the group whose
+// centroid is most similar, or to a group already holding the same question.
+// Gaps without an embedding only group with identical questions.
+func clusterGaps(gaps []models.DocGap, similarity float32) []GapCluster {
+	var groups []*gapGroup
+
+	for _, gap := range gaps {
+		key := normalizeQuestion(gap.Question)
+
+		var best *gapGroup
+		var bestScore float32
+		for _, g := range groups {
+			if _, ok := g.questions[key]; ok {
+				best = g
+				break
+			}
+			if len(gap.Embedding) == 0 || len(g.centroid) == 0 {
+				continue
+			}
+			if score := cosineSimilarity(gap.Embedding, g.centroid); score >= similarity && score > bestScore {
+				best, bestScore = g, score
+			}
+		}
+
+		if best == nil {
+			best = &gapGroup{
+				questions: make(map[string]*gapQuestion),
+				cluster:   GapCluster{FirstSeen: gap.CreatedAt},
+			}
+			groups = append(groups, best)
+		}
+
+		if len(gap.Embedding) > 0 {
+			if len(best.centroid) == 0 {
+				best.centroid = make([]float32, len(gap.Embedding))
+			}
+			if len(best.centroid) == len(gap.Embedding) {
+				for i, v := range gap.Embedding {
+					best.centroid[i] += v
+				}
+			}
+		}
+
+		q, ok := best.questions[key]
+		if !ok {
+			q = &gapQuestion{text: strings.TrimSpace(gap.Question), first: gap.CreatedAt}
+			best.questions[key] = q
+		}
+		q.count++
+		best.cluster.Count++
+		best.cluster.LastSeen = gap.CreatedAt
+	}
+
+	clusters := make([]GapCluster, len(groups))
+	for i, g := range groups {
+		questions := make([]*gapQuestion, 0, len(g.questions))
+		for _, q := range g.questions {
+			questions = append(questions, q)
+		}
+		sort.Slice(questions, func(a, b int) bool {
+			if questions[a].count != questions[b].count {
+				return questions[a].count > questions[b].count
+			}
+			return questions[a].first.Before(questions[b].first)
+		})
+
+		c := g.cluster
+		c.Topic = questions[0].text
+		for j := 0; j < len(questions) && j < maxGapExamples; j++ {
+			c.Examples = append(c.Examples, questions[j].text)
+		}
+		clusters[i] = c
+	}
+
+	sort.SliceStable(clusters, func(a, b int) bool {
+		if clusters[a].Count != clusters[b].Count {
+			return clusters[a].Count > clusters[b].Count
+		}
+		return clusters[a].LastSeen.After(clusters[b].LastSeen)
+	})
+	return clusters
+}
+
+// normalizeQuestion folds case, whitespace and trailing punctuation so
+// trivially different phrasings count as the same question.
+func normalizeQuestion(q string) string {
+	return strings.Join(strings.Fields(strings.ToLower(strings.TrimRight(strings.TrimSpace(q), "?!. "))), " ")
+}
+
+// cosineSimilarity returns the cosine of the angle between two vectors, or 0
+// if they differ in length or either is zero.
+func cosineSimilarity(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"living-lands-bot/internal/database/models"
+	"living-lands-bot/internal/testutil"
+	"living-lands-bot/pkg/ollama"
+)
+
+func newTestGapService(t *testing.T) (*GapService, *testutil.OllamaServer) {
+	t.Helper()
+	db := testutil.NewTestDB(t, &models.DocGap{}, &models.GapDigest{})
+	ollamaServer := testutil.NewOllamaServer(t)
+	gaps := NewGapService(db, ollama.NewClient(ollamaServer.URL), "nomic-embed-text", getTestLogger())
+	return gaps, ollamaServer
+}
+
+func TestStripNoAnswer(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   string
+		found  bool
+	}{
+		{"[NO_ANSWER] I don't have information about that in my archives.", "I don't have information about that in my archives.", true},
+		{"[NO_ANSWER]\nDazu habe ich keine Informationen in meinen Archiven.", "Dazu habe ich keine Informationen in meinen Archiven.", true},
+		{"[ no answer ] 我的档案中没有这方面的信息。", "我的档案中没有这方面的信息。", true},
+		{"[NO_ANSWER]", "", true},
+		{"I don't have information about that in my archives.", "I don't have information about that in my archives.", false},
+		{"Hunger and thirst drain over time, traveler.", "Hunger and thirst drain over time, traveler.", false},
+	}
+
+	for _, tt := range tests {
+		got, found := StripNoAnswer(tt.answer)
+		assert.Equal(t, tt.want, got, tt.answer)
+		assert.Equal(t, tt.found, found, tt.answer)
+	}
+}
+
+func TestClusterGaps(t *testing.T) {
+	base := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	gap := func(q string, offset time.Duration) models.DocGap {
+		return models.DocGap{
+			Question:  q,
+			Embedding: testutil.HashEmbedding(q, testutil.DefaultEmbeddingDim),
+			CreatedAt: base.Add(offset),
+		}
+	}
+
+	clusters := clusterGaps([]models.DocGap{
+		gap("how do I craft a backpack", 0),
+		gap("where is the server IP", time.Hour),
+		gap("how do I craft a backpack", 2*time.Hour),
+		gap("How do I craft a backpack?", 3*time.Hour),
+		gap("how do I craft a leather backpack", 4*time.Hour),
+		{Question: "where is the server IP", CreatedAt: base.Add(5 * time.Hour)}, // no embedding
+	}, 0.7)
+
+	require.Len(t, clusters, 2)
+
+	assert.Equal(t, "how do I craft a backpack", clusters[0].Topic)
+	assert.Equal(t, 4, clusters[0].Count)
+	assert.Equal(t, []string{"how do I craft a backpack", "how do I craft a leather backpack"}, clusters[0].Examples)
+	assert.Equal(t, base, clusters[0].FirstSeen)
+	assert.Equal(t, base.Add(4*time.Hour), clusters[0].LastSeen)
+
+	assert.Equal(t, "where is the server IP", clusters[1].Topic)
+	assert.Equal(t, 2, clusters[1].Count)
+}
+
+func TestGapServiceRecordAndReport(t *testing.T) {
+	gaps, ollamaServer := newTestGapService(t)
+	ctx := context.Background()
+
+	require.NoError(t, gaps.Record(ctx, &models.DocGap{Question: "how do I tame a wolf", Reason: models.GapNoContext}))
+	require.Len(t, ollamaServer.EmbedRequests(), 1)
+
+	// A gap whose embedding failed is stored anyway and embedded for the report
+	ollamaServer.FailEmbeddings(http.StatusInternalServerError)
+	failed := &models.DocGap{Question: "how do I tame a wolf", Reason: models.GapNoAnswer}
+	require.NoError(t, gaps.Record(ctx, failed))
+	assert.Empty(t, failed.Embedding)
+	ollamaServer.FailEmbeddings(0)
+
+	require.NoError(t, gaps.Record(ctx, &models.DocGap{Question: "can I ride horses", Reason: models.GapNoContext}))
+
+	clusters, err := gaps.Report(ctx, "", time.Now().Add(-time.Hour), 1)
+	require.NoError(t, err)
+	require.Len(t, clusters, 1)
+	assert.Equal(t, "how do I tame a wolf", clusters[0].Topic)
+	assert.Equal(t, 2, clusters[0].Count)
+
+	var stored models.DocGap
+	require.NoError(t, gaps.db.First(&stored, failed.ID).Error)
+	assert.NotEmpty(t, stored.Embedding, "report should backfill missing embeddings")
+
+	clusters, err = gaps.Report(ctx, "", time.Now().Add(time.Hour), 0)
+	require.NoError(t, err)
+	assert.Empty(t, clusters)
+}
+
+func TestGapServiceReportByGuild(t *testing.T) {
+	gaps, _ := newTestGapService(t)
+	ctx := context.Background()
+	const otherGuild = "1000000000000000001"
+
+	require.NoError(t, gaps.Record(ctx, &models.DocGap{GuildID: testGuild, Question: "how do I tame a wolf", Reason: models.GapNoContext}))
+	require.NoError(t, gaps.Record(ctx, &models.DocGap{GuildID: otherGuild, Question: "can I ride horses", Reason: models.GapNoContext}))
+
+	clusters, err := gaps.Report(ctx, testGuild, time.Now().Add(-time.Hour), 0)
+	require.NoError(t, err)
+	require.Len(t, clusters, 1)
+	assert.Equal(t, "how do I tame a wolf", clusters[0].Topic)
+
+	clusters, err = gaps.Report(ctx, "", time.Now().Add(-time.Hour), 0)
+	require.NoError(t, err)
+	assert.Len(t, clusters, 2, "an empty guild reports every guild")
+}
+
+func TestGapServiceDigestTracking(t *testing.T) {
+	gaps, _ := newTestGapService(t)
+
+	last, err := gaps.LastDigest("staff")
+	require.NoError(t, err)
+	assert.True(t, last.IsZero())
+
+	postedAt := time.Date(2026, 10, 5, 9, 0, 0, 0, time.UTC)
+	require.NoError(t, gaps.RecordDigest("staff", postedAt.Add(-7*24*time.Hour)))
+	require.NoError(t, gaps.RecordDigest("staff", postedAt))
+
+	last, err = gaps.LastDigest("staff")
+	require.NoError(t, err)
+	assert.True(t, postedAt.Equal(last))
+}
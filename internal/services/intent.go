@@ -1,8 +1,19 @@
 package services
 
 import (
-	"regexp"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
 	"strings"
+	"sync"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+
+	"living-lands-bot/pkg/language"
+	"living-lands-bot/pkg/llm"
 )
 
 // QueryIntent represents the type of user query.
@@ -39,6 +50,19 @@ func (i QueryIntent) String() string {
 	}
 }
 
+// ParseIntent returns the intent with the given String() name.
+func ParseIntent(name string) (QueryIntent, error) {
+	for _, intent := range allIntents {
+		if intent.String() == name {
+			return intent, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown intent %q", name)
+}
+
+// allIntents lists every intent, in declaration order.
+var allIntents = []QueryIntent{IntentKnowledge, IntentConversational, IntentNavigation, IntentAccountHelp, IntentIdentity}
+
 // NeedsRAG returns true if this intent requires RAG context.
 func (i QueryIntent) NeedsRAG() bool {
 	return i == IntentKnowledge
@@ -107,59 +131,213 @@ var knowledgeKeywords = []string{
 	"install", "download", "setup", "curseforge",
 }
 
-// ClassifyIntent analyzes a user query and determines its intent.
+// questionWords start a question.
+var questionWords = []string{
+	"what", "how", "why", "when", "where", "who", "which", "can", "does",
+	"is", "are", "do", "will", "would", "could", "should",
+}
+
+// intentKeywords are the keyword rules for one language.
+type intentKeywords struct {
+	conversational []string // Small talk anywhere in the query (lowest priority)
+	exact          []string // Small talk that must be the whole query
+	navigation     []string
+	account        []string
+	identity       []string
+	knowledge      []string
+	questionWords  []string // Words that start a question
+}
+
+// keywordSets holds the keyword rules per language. A query is checked
+// against English, since mod terms are usually written in English, and the
+// language detected in it.
+var keywordSets = map[language.Language]intentKeywords{
+	language.English: {
+		conversational: conversationalPatterns,
+		exact:          exactConversationalMatches,
+		navigation:     navigationKeywords,
+		account:        accountKeywords,
+		identity:       identityKeywords,
+		knowledge:      knowledgeKeywords,
+		questionWords:  questionWords,
+	},
+	language.German: {
+		conversational: []string{"hallo", "servus", "moin", "guten morgen", "guten tag", "guten abend", "gute nacht", "wie geht", "danke", "tschüss", "bis später"},
+		exact:          []string{"hallo", "moin", "servus", "danke", "danke schön", "tschüss"},
+		navigation:     []string{"kanal"},
+		account:        []string{"konto", "verknüpf", "verifizier", "bestätigungscode", "verbinden"},
+		identity:       []string{"wer bist du", "was bist du", "wo bin ich", "wer bin ich", "erzähl mir von dir"},
+		knowledge:      []string{"wie funktioniert", "wie kann ich", "was ist", "was sind", "gibt es", "installier", "herunterladen", "kreatur", "stoffwechsel", "hunger", "durst", "konfiguration"},
+		questionWords:  []string{"was", "wie", "warum", "wieso", "weshalb", "wann", "wo", "wer", "welche", "welcher", "welches", "kann", "gibt", "ist", "sind"},
+	},
+	language.French: {
+		conversational: []string{"bonjour", "salut", "coucou", "bonsoir", "bonne nuit", "ça va", "comment vas-tu", "merci", "au revoir", "à plus"},
+		exact:          []string{"bonjour", "salut", "coucou", "bonsoir", "merci", "merci beaucoup", "au revoir"},
+		navigation:     []string{"salon", "canal"},
+		account:        []string{"compte", "lier mon", "associer", "vérifi", "code de vérification"},
+		identity:       []string{"qui es-tu", "qui es tu", "où suis-je", "ou suis-je", "qui suis-je", "parle-moi de toi"},
+		knowledge:      []string{"comment fonctionne", "comment marche", "comment faire", "qu'est-ce que", "pourquoi", "y a-t-il", "installer", "télécharger", "créature", "métabolisme", "faim", "soif"},
+		questionWords:  []string{"comment", "pourquoi", "quand", "où", "qui", "quel", "quelle", "quels", "quelles", "est-ce", "que", "quoi", "combien"},
+	},
+	language.Spanish: {
+		conversational: []string{"hola", "buenos días", "buenas tardes", "buenas noches", "qué tal", "cómo estás", "gracias", "adiós", "hasta luego"},
+		exact:          []string{"hola", "buenas", "gracias", "muchas gracias", "adiós", "hasta luego"},
+		navigation:     []string{"canal"},
+		account:        []string{"cuenta", "vincular", "verificar", "verificación", "conectar mi"},
+		identity:       []string{"quién eres", "quien eres", "qué eres", "dónde estoy", "donde estoy", "quién soy", "háblame de ti"},
+		knowledge:      []string{"cómo funciona", "como funciona", "cómo puedo", "como puedo", "qué es", "por qué", "instalar", "descargar", "criatura", "metabolismo", "hambre"},
+		questionWords:  []string{"qué", "que", "cómo", "como", "por", "cuándo", "cuando", "dónde", "donde", "quién", "quien", "cuál", "cual", "puedo", "hay"},
+	},
+	language.Italian: {
+		conversational: []string{"ciao", "buongiorno", "buonasera", "buonanotte", "come stai", "come va", "grazie", "arrivederci", "a dopo"},
+		exact:          []string{"ciao", "salve", "buongiorno", "buonasera", "grazie", "grazie mille", "arrivederci"},
+		navigation:     []string{"canale"},
+		account:        []string{"collegare", "collego", "verifica", "codice di verifica"},
+		identity:       []string{"chi sei", "cosa sei", "dove sono", "chi sono", "parlami di te"},
+		knowledge:      []string{"come funziona", "come posso", "cos'è", "cosa è", "perché", "c'è", "installare", "scaricare", "creatura", "creature", "metabolismo", "fame", "sete"},
+		questionWords:  []string{"come", "cosa", "che", "perché", "quando", "dove", "chi", "quale", "quali", "posso"},
+	},
+	language.Dutch: {
+		conversational: []string{"hallo", "hoi", "goedemorgen", "goedemiddag", "goedenavond", "hoe gaat het", "bedankt", "dank je", "dankjewel", "doei", "tot later"},
+		exact:          []string{"hallo", "hoi", "bedankt", "dank je", "dankjewel", "doei"},
+		navigation:     []string{"kanaal"},
+		account:        []string{"koppel", "verifi", "verbinden"},
+		identity:       []string{"wie ben jij", "wie ben je", "wat ben jij", "wat ben je", "waar ben ik", "wie ben ik", "vertel over jezelf"},
+		knowledge:      []string{"hoe werkt", "hoe kan ik", "wat is", "waarom", "is er", "installeren", "downloaden", "wezen", "metabolisme", "honger", "dorst"},
+		questionWords:  []string{"wat", "hoe", "waarom", "wanneer", "waar", "wie", "welke", "kan", "is", "zijn"},
+	},
+	language.Russian: {
+		conversational: []string{"привет", "здравствуй", "добрый день", "доброе утро", "добрый вечер", "как дела", "спасибо", "пока", "до свидания"},
+		exact:          []string{"привет", "здравствуйте", "спасибо", "пока"},
+		navigation:     []string{"канал"},
+		account:        []string{"аккаунт", "учётн", "учетн", "привяз", "верификац", "код подтверждения"},
+		identity:       []string{"кто ты", "что ты такое", "где я", "кто я", "расскажи о себе"},
+		knowledge:      []string{"как работает", "как устроен", "что такое", "почему", "есть ли", "установ", "скачать", "существ", "метаболизм", "голод", "жажд", "мод"},
+		questionWords:  []string{"что", "как", "почему", "зачем", "когда", "где", "кто", "какой", "какая", "какие", "можно", "есть"},
+	},
+	language.Japanese: {
+		conversational: []string{"こんにちは", "こんばんは", "おはよう", "ありがとう", "よろしく", "さようなら", "またね"},
+		exact:          []string{"こんにちは", "こんばんは", "おはよう", "おはようございます", "ありがとう", "ありがとうございます"},
+		navigation:     []string{"チャンネル"},
+		account:        []string{"アカウント", "連携", "認証", "リンク"},
+		identity:       []string{"あなたは誰", "あなたはだれ", "君は誰", "ここはどこ", "私は誰", "自己紹介"},
+		knowledge:      []string{"どうやって", "仕組み", "とは", "なぜ", "インストール", "ダウンロード", "クリーチャー", "生物", "代謝", "空腹", "設定", "サーバー"},
+		questionWords:  []string{"ですか", "ますか", "のか", "何", "なに", "どう", "どこ", "誰", "だれ", "いつ"},
+	},
+	language.Chinese: {
+		conversational: []string{"你好", "您好", "早上好", "晚上好", "谢谢", "再见", "哈喽"},
+		exact:          []string{"你好", "您好", "谢谢", "再见", "嗨", "早上好"},
+		navigation:     []string{"频道"},
+		account:        []string{"账号", "账户", "绑定", "验证", "关联"},
+		identity:       []string{"你是谁", "你是什么", "我在哪", "我是谁", "介绍一下你自己", "介绍你自己"},
+		knowledge:      []string{"怎么", "如何", "为什么", "是什么", "有没有", "安装", "下载", "生物", "代谢", "饥饿", "口渴", "配置", "服务器", "模组"},
+		questionWords:  []string{"吗", "什么", "怎么", "为什么", "哪", "谁", "如何"},
+	},
+	language.Korean: {
+		conversational: []string{"안녕", "반가워", "좋은 아침", "고마워", "감사합니다", "감사해요", "잘 가", "또 봐"},
+		exact:          []string{"안녕", "안녕하세요", "하이", "고마워", "감사합니다"},
+		navigation:     []string{"채널"},
+		account:        []string{"계정", "연동", "인증", "연결"},
+		identity:       []string{"너는 누구", "넌 누구", "누구세요", "여기는 어디", "여기가 어디", "나는 누구", "자기소개"},
+		knowledge:      []string{"어떻게", "무엇", "뭐야", "있나요", "설치", "다운로드", "생물", "크리처", "배고픔", "갈증", "설정", "서버", "모드"},
+		questionWords:  []string{"어떻게", "왜", "무엇", "뭐", "어디", "누구", "언제", "나요", "까"},
+	},
+}
+
+// questionWordsAnywhere lists languages whose question words are matched
+// anywhere in the query rather than as its first word, because questions are
+// marked by particles or words are not separated by spaces.
+var questionWordsAnywhere = map[language.Language]bool{
+	language.Japanese: true,
+	language.Chinese:  true,
+	language.Korean:   true,
+}
+
+// unspacedLanguages do not separate words with spaces, so word counts mean nothing.
+var unspacedLanguages = map[language.Language]bool{
+	language.Japanese: true,
+	language.Chinese:  true,
+}
+
+// keywordsFor merges the English keyword rules with those of lang.
+func keywordsFor(lang language.Language) intentKeywords {
+	kw := keywordSets[language.English]
+	extra, ok := keywordSets[lang]
+	if !ok || lang == language.English {
+		return kw
+	}
+	return intentKeywords{
+		conversational: append(append([]string{}, kw.conversational...), extra.conversational...),
+		exact:          append(append([]string{}, kw.exact...), extra.exact...),
+		navigation:     append(append([]string{}, kw.navigation...), extra.navigation...),
+		account:        append(append([]string{}, kw.account...), extra.account...),
+		identity:       append(append([]string{}, kw.identity...), extra.identity...),
+		knowledge:      append(append([]string{}, kw.knowledge...), extra.knowledge...),
+		questionWords:  extra.questionWords, // English question words are checked by isQuestion anyway
+	}
+}
+
+// trimQueryPunctuation strips trailing punctuation, including full-width
+// CJK marks, for exact matching.
+func trimQueryPunctuation(s string) string {
+	return strings.TrimRight(s, "?!.,？！。、")
+}
+
+// ClassifyIntent analyzes a user query and determines its intent using the
+// keyword rules for English and the language detected in the query.
 func ClassifyIntent(query string) QueryIntent {
 	normalized := strings.ToLower(strings.TrimSpace(query))
+	lang, confidence := language.Detect(normalized)
+	if confidence < language.MinConfidence {
+		lang = language.English
+	}
+	kw := keywordsFor(lang)
 
 	// Strip trailing punctuation for exact matching
-	normalizedNoPunc := strings.TrimRight(normalized, "?!.,")
+	normalizedNoPunc := trimQueryPunctuation(normalized)
 
 	// Check for exact conversational matches first
-	for _, exact := range exactConversationalMatches {
-		if normalizedNoPunc == exact {
-			return IntentConversational
-		}
+	if isExactConversational(normalizedNoPunc) {
+		return IntentConversational
 	}
 
 	// Check for short queries (likely conversational)
 	wordCount := len(strings.Fields(normalized))
-	if wordCount <= 2 {
+	if wordCount <= 2 && !unspacedLanguages[lang] {
 		// Very short queries are usually conversational unless they contain knowledge keywords
-		if !containsAnyKeyword(normalized, knowledgeKeywords) {
+		if !containsAnyKeyword(normalized, kw.knowledge) {
 			return IntentConversational
 		}
 	}
 
 	// Check for account-related queries first (high priority)
-	if containsAnyKeyword(normalized, accountKeywords) {
+	if containsAnyKeyword(normalized, kw.account) {
 		return IntentAccountHelp
 	}
 
 	// Check for identity/location queries (needs persona response)
-	if containsAnyKeyword(normalized, identityKeywords) {
+	if containsAnyKeyword(normalized, kw.identity) {
 		return IntentIdentity
 	}
 
 	// Check for navigation keywords BEFORE conversational patterns
 	// This ensures "where is the support channel" is navigation, not conversational
-	if containsAnyKeyword(normalized, navigationKeywords) {
+	if containsAnyKeyword(normalized, kw.navigation) {
 		return IntentNavigation
 	}
 
 	// Check for knowledge keywords BEFORE conversational
-	if containsAnyKeyword(normalized, knowledgeKeywords) {
+	if containsAnyKeyword(normalized, kw.knowledge) {
 		return IntentKnowledge
 	}
 
 	// Check for conversational patterns (partial match) - lower priority
-	for _, pattern := range conversationalPatterns {
-		if strings.Contains(normalized, pattern) {
-			return IntentConversational
-		}
+	if containsAnyKeyword(normalized, kw.conversational) {
+		return IntentConversational
 	}
 
 	// Default: if it looks like a question, treat as knowledge query
-	if isQuestion(normalized) {
+	if isQuestion(normalized, lang) {
 		return IntentKnowledge
 	}
 
@@ -167,6 +345,20 @@ func ClassifyIntent(query string) QueryIntent {
 	return IntentConversational
 }
 
+// isExactConversational reports whether a lowercased query without trailing
+// punctuation is one of the exact conversational matches. Every language is
+// checked, since a lone greeting is too short to detect its language.
+func isExactConversational(normalizedNoPunc string) bool {
+	for _, kw := range keywordSets {
+		for _, exact := range kw.exact {
+			if normalizedNoPunc == exact {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // containsAnyKeyword checks if text contains any of the given keywords.
 func containsAnyKeyword(text string, keywords []string) bool {
 	for _, kw := range keywords {
@@ -177,16 +369,210 @@ func containsAnyKeyword(text string, keywords []string) bool {
 	return false
 }
 
-// questionPatterns for detecting questions.
-var questionPattern = regexp.MustCompile(`(?i)^(what|how|why|when|where|who|which|can|does|is|are|do|will|would|could|should)\b`)
-
-// isQuestion checks if the text appears to be a question.
-func isQuestion(text string) bool {
+// isQuestion checks if the lowercased text appears to be a question in
+// English or lang.
+func isQuestion(text string, lang language.Language) bool {
 	// Check for question mark
-	if strings.Contains(text, "?") {
+	if strings.ContainsAny(text, "?？") {
 		return true
 	}
 
+	if questionWordsAnywhere[lang] {
+		return containsAnyKeyword(text, keywordSets[lang].questionWords)
+	}
+
 	// Check for question word patterns
-	return questionPattern.MatchString(text)
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '-'
+	})
+	if len(words) == 0 {
+		return false
+	}
+	for _, set := range []intentKeywords{keywordSets[language.English], keywordSets[lang]} {
+		for _, w := range set.questionWords {
+			if words[0] == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DefaultIntentMinConfidence is the minimum similarity between a query and
+// its closest example for the embedding classifier's answer to be used;
+// below it the keyword rules decide.
+const DefaultIntentMinConfidence = 0.6
+
+// How an intent was decided, reported in IntentClassification.Method.
+const (
+	IntentMethodRule      = "rule"      // Exact conversational match, no embedding needed
+	IntentMethodEmbedding = "embedding" // Closest labelled example
+	IntentMethodKeyword   = "keyword"   // Keyword rules (embedding unavailable or not confident)
+)
+
+// IntentScore is a query's similarity to the closest example of an intent.
+type IntentScore struct {
+	Intent QueryIntent
+	Score  float32
+}
+
+// IntentClassification is the result of IntentClassifier.Classify.
+type IntentClassification struct {
+	Intent     QueryIntent
+	Confidence float32       // Similarity to the closest example; 1 for rules, 0 for keywords
+	Scores     []IntentScore // Per-intent scores, best first (empty unless embedded)
+	Method     string
+}
+
+// intentExample is a labelled example utterance and its embedding.
+type intentExample struct {
+	intent    QueryIntent
+	text      string
+	embedding []float32
+}
+
+// IntentClassifier routes /ask questions by comparing their embedding with
+// labelled example utterances, falling back to the keyword rules in
+// ClassifyIntent when embeddings are unavailable or no intent is close enough.
+// Thread-safe.
+type IntentClassifier struct {
+	embedder      llm.Embedder
+	embedModel    string
+	examples      map[QueryIntent][]string
+	minConfidence float32
+	logger        *slog.Logger
+
+	mu    sync.RWMutex
+	index []intentExample // Embedded examples (protected by mu); empty until Load succeeds
+}
+
+// NewIntentClassifier creates a classifier for the given examples. Call Load
+// before Classify; until then every query uses the keyword rules.
+func NewIntentClassifier(embedder llm.Embedder, embedModel string, examples map[QueryIntent][]string, logger *slog.Logger) *IntentClassifier {
+	return &IntentClassifier{
+		embedder:      embedder,
+		embedModel:    embedModel,
+		examples:      examples,
+		minConfidence: DefaultIntentMinConfidence,
+		logger:        logger,
+	}
+}
+
+// SetMinConfidence sets the minimum similarity for an embedding decision.
+func (c *IntentClassifier) SetMinConfidence(minConfidence float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.minConfidence = minConfidence
+}
+
+// LoadIntentExamples reads labelled example utterances from a YAML file
+// mapping intent names (see QueryIntent.String) to lists of examples.
+func LoadIntentExamples(filePath string) (map[QueryIntent][]string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read intent examples file: %w", err)
+	}
+
+	var raw map[string][]string
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse intent examples yaml: %w", err)
+	}
+
+	examples := make(map[QueryIntent][]string, len(raw))
+	for name, texts := range raw {
+		intent, err := ParseIntent(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid intent examples file: %w", err)
+		}
+		examples[intent] = texts
+	}
+	return examples, nil
+}
+
+// Load embeds every example utterance. Until it succeeds, Classify uses the
+// keyword rules, so a failed Load can simply be retried.
+func (c *IntentClassifier) Load(ctx context.Context) error {
+	var index []intentExample
+	for _, intent := range allIntents {
+		for _, text := range c.examples[intent] {
+			embedding, err := c.embedder.Embed(ctx, c.embedModel, text)
+			if err != nil {
+				return fmt.Errorf("failed to embed intent example %q: %w", text, err)
+			}
+			index = append(index, intentExample{intent: intent, text: text, embedding: embedding})
+		}
+	}
+
+	c.mu.Lock()
+	c.index = index
+	c.mu.Unlock()
+
+	c.logger.Info("intent classifier loaded", "examples", len(index))
+	return nil
+}
+
+// Classify determines a query's intent. Exact greetings are decided by rule;
+// everything else by the closest labelled example, unless it is less similar
+// than the minimum confidence or embedding fails, in which case the keyword
+// rules decide.
+func (c *IntentClassifier) Classify(ctx context.Context, query string) IntentClassification {
+	normalized := trimQueryPunctuation(strings.ToLower(strings.TrimSpace(query)))
+	if isExactConversational(normalized) {
+		return IntentClassification{Intent: IntentConversational, Confidence: 1, Method: IntentMethodRule}
+	}
+
+	keyword := IntentClassification{Intent: ClassifyIntent(query), Method: IntentMethodKeyword}
+	if !c.Loaded() {
+		return keyword
+	}
+
+	embedding, err := c.embedder.Embed(ctx, c.embedModel, query)
+	if err != nil {
+		c.logger.Warn("intent embedding failed, using keyword rules", "error", err)
+		return keyword
+	}
+
+	c.mu.RLock()
+	scores := c.scoreLocked(embedding)
+	minConfidence := c.minConfidence
+	c.mu.RUnlock()
+
+	if scores[0].Score < minConfidence {
+		keyword.Scores = scores
+		return keyword
+	}
+
+	return IntentClassification{
+		Intent:     scores[0].Intent,
+		Confidence: scores[0].Score,
+		Scores:     scores,
+		Method:     IntentMethodEmbedding,
+	}
+}
+
+// Loaded reports whether the examples have been embedded.
+func (c *IntentClassifier) Loaded() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.index) > 0
+}
+
+// scoreLocked returns each intent's similarity to its closest example, best first.
+func (c *IntentClassifier) scoreLocked(embedding []float32) []IntentScore {
+	best := make(map[QueryIntent]float32)
+	for _, ex := range c.index {
+		sim := cosineSimilarity(embedding, ex.embedding)
+		if current, ok := best[ex.intent]; !ok || sim > current {
+			best[ex.intent] = sim
+		}
+	}
+
+	scores := make([]IntentScore, 0, len(best))
+	for _, intent := range allIntents {
+		if score, ok := best[intent]; ok {
+			scores = append(scores, IntentScore{Intent: intent, Score: score})
+		}
+	}
+	sort.SliceStable(scores, func(a, b int) bool { return scores[a].Score > scores[b].Score })
+	return scores
 }
@@ -0,0 +1,29 @@
+//go:build integration
+
+package services
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"living-lands-bot/pkg/ollama"
+)
+
+// TestIntentClassifierCorpusIntegration scores the classifier on the labelled
+// corpus with a real embedding model.
+// Run with: OLLAMA_URL=http://localhost:11434 go test -tags=integration -run IntentClassifierCorpus ./internal/services/...
+func TestIntentClassifierCorpusIntegration(t *testing.T) {
+	ollamaURL := os.Getenv("OLLAMA_URL")
+	if ollamaURL == "" {
+		t.Skip("Skipping integration test: OLLAMA_URL not set")
+	}
+
+	classifier := newTestIntentClassifier(t, ollama.NewClient(ollamaURL))
+
+	for intent, e := range evaluateIntentCorpus(t, classifier) {
+		assert.GreaterOrEqual(t, e.precision, 0.8, "precision for %s", intent)
+		assert.GreaterOrEqual(t, e.recall, 0.8, "recall for %s", intent)
+	}
+}
@@ -1,9 +1,22 @@
 package services
 
 import (
+	"context"
+	"net/http"
+	"os"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"living-lands-bot/internal/testutil"
+	"living-lands-bot/pkg/llm"
+	"living-lands-bot/pkg/ollama"
 )
 
+const testIntentExamplesFile = "../../configs/intents.yaml"
+
 func TestClassifyIntent_Conversational(t *testing.T) {
 	tests := []struct {
 		query    string
@@ -16,12 +29,8 @@ func TestClassifyIntent_Conversational(t *testing.T) {
 		{"Hello!", IntentConversational},
 		{"good morning", IntentConversational},
 
-		// Location/identity questions
-		{"where am i?", IntentConversational},
-		{"who am i", IntentConversational},
-		{"who are you", IntentConversational},
+		// Bare "what is this" is small talk; identity questions are covered below
 		{"what is this", IntentConversational},
-		{"what's this place", IntentConversational},
 
 		// Status questions
 		{"how are you", IntentConversational},
@@ -139,6 +148,190 @@ func TestClassifyIntent_AccountHelp(t *testing.T) {
 	}
 }
 
+func TestClassifyIntent_Identity(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected QueryIntent
+	}{
+		{"where am i?", IntentIdentity},
+		{"who am i", IntentIdentity},
+		{"who are you", IntentIdentity},
+		{"what's this place", IntentIdentity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			result := ClassifyIntent(tt.query)
+			if result != tt.expected {
+				t.Errorf("ClassifyIntent(%q) = %v, want %v", tt.query, result.String(), tt.expected.String())
+			}
+		})
+	}
+}
+
+func TestClassifyIntent_Multilingual(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected QueryIntent
+	}{
+		// Greetings are matched in every language, however short
+		{"Hallo!", IntentConversational},
+		{"merci", IntentConversational},
+		{"привет", IntentConversational},
+		{"こんにちは", IntentConversational},
+		{"안녕하세요", IntentConversational},
+
+		{"Wo ist der Support-Kanal für die Fehler?", IntentNavigation},
+		{"Comment lier mon compte pour le serveur ?", IntentAccountHelp},
+		{"как привязать аккаунт", IntentAccountHelp},
+		{"¿Cómo funciona el metabolismo de las criaturas?", IntentKnowledge},
+		{"Come posso scaricare la mod?", IntentKnowledge},
+		{"Hoe werkt het metabolisme van de wezens?", IntentKnowledge},
+		{"如何安装这个模组", IntentKnowledge},
+		{"代謝の仕組みを教えてください", IntentKnowledge},
+		{"모드는 어떻게 설치하나요", IntentKnowledge},
+		{"你是谁", IntentIdentity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			result := ClassifyIntent(tt.query)
+			if result != tt.expected {
+				t.Errorf("ClassifyIntent(%q) = %v, want %v", tt.query, result.String(), tt.expected.String())
+			}
+		})
+	}
+}
+
+// newTestIntentClassifier loads configs/intents.yaml with the given embedder.
+func newTestIntentClassifier(t *testing.T, embedder llm.Embedder) *IntentClassifier {
+	t.Helper()
+	examples, err := LoadIntentExamples(testIntentExamplesFile)
+	require.NoError(t, err)
+	classifier := NewIntentClassifier(embedder, "nomic-embed-text", examples, getTestLogger())
+	require.NoError(t, classifier.Load(context.Background()))
+	return classifier
+}
+
+type intentEval struct {
+	precision, recall float64
+}
+
+// evaluateIntentCorpus classifies every question in testdata/intent_corpus.yaml
+// and returns precision and recall per intent.
+func evaluateIntentCorpus(t *testing.T, classifier *IntentClassifier) map[QueryIntent]intentEval {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/intent_corpus.yaml")
+	require.NoError(t, err)
+	var corpus map[string][]string
+	require.NoError(t, yaml.Unmarshal(data, &corpus))
+
+	truePos := make(map[QueryIntent]int)
+	predicted := make(map[QueryIntent]int)
+	actual := make(map[QueryIntent]int)
+	for name, queries := range corpus {
+		want, err := ParseIntent(name)
+		require.NoError(t, err)
+		for _, q := range queries {
+			got := classifier.Classify(context.Background(), q)
+			actual[want]++
+			predicted[got.Intent]++
+			if got.Intent == want {
+				truePos[want]++
+			} else {
+				t.Logf("misclassified %q: got %s (%s, %.2f), want %s", q, got.Intent, got.Method, got.Confidence, want)
+			}
+		}
+	}
+
+	results := make(map[QueryIntent]intentEval)
+	for _, intent := range allIntents {
+		var e intentEval
+		if predicted[intent] > 0 {
+			e.precision = float64(truePos[intent]) / float64(predicted[intent])
+		}
+		if actual[intent] > 0 {
+			e.recall = float64(truePos[intent]) / float64(actual[intent])
+		}
+		results[intent] = e
+		t.Logf("%-14s precision=%.2f recall=%.2f (n=%d)", intent, e.precision, e.recall, actual[intent])
+	}
+	return results
+}
+
+func TestIntentClassifierCorpus(t *testing.T) {
+	// The fake embedder is a bag of words, so this measures lexical overlap
+	// with the examples; run the integration test for real embedding quality.
+	ollamaServer := testutil.NewOllamaServer(t)
+	classifier := newTestIntentClassifier(t, ollama.NewClient(ollamaServer.URL))
+	classifier.SetMinConfidence(0.3)
+
+	for intent, e := range evaluateIntentCorpus(t, classifier) {
+		assert.GreaterOrEqual(t, e.precision, 0.7, "precision for %s", intent)
+		assert.GreaterOrEqual(t, e.recall, 0.7, "recall for %s", intent)
+	}
+}
+
+func TestIntentClassifierKeywordPitfalls(t *testing.T) {
+	ollamaServer := testutil.NewOllamaServer(t)
+	classifier := newTestIntentClassifier(t, ollama.NewClient(ollamaServer.URL))
+	classifier.SetMinConfidence(0.3)
+
+	// Both are misrouted by the keyword rules alone
+	for _, q := range []string{"how do I link two creatures together", "how does channeling work in the magic system"} {
+		assert.NotEqual(t, IntentKnowledge, ClassifyIntent(q), "keyword rules should misroute %q", q)
+
+		got := classifier.Classify(context.Background(), q)
+		assert.Equal(t, IntentKnowledge, got.Intent, q)
+		assert.Equal(t, IntentMethodEmbedding, got.Method)
+		require.NotEmpty(t, got.Scores)
+		assert.Equal(t, got.Confidence, got.Scores[0].Score)
+	}
+}
+
+func TestIntentClassifierFallbacks(t *testing.T) {
+	ollamaServer := testutil.NewOllamaServer(t)
+	classifier := newTestIntentClassifier(t, ollama.NewClient(ollamaServer.URL))
+
+	// Exact greetings never need an embedding
+	embeds := len(ollamaServer.EmbedRequests())
+	got := classifier.Classify(context.Background(), "Hello!")
+	assert.Equal(t, IntentConversational, got.Intent)
+	assert.Equal(t, IntentMethodRule, got.Method)
+	assert.Len(t, ollamaServer.EmbedRequests(), embeds)
+
+	// Nothing close enough: keyword rules decide, scores are still reported
+	classifier.SetMinConfidence(0.99)
+	got = classifier.Classify(context.Background(), "where is the support channel for the mod")
+	assert.Equal(t, IntentNavigation, got.Intent)
+	assert.Equal(t, IntentMethodKeyword, got.Method)
+	assert.NotEmpty(t, got.Scores)
+
+	// Embedding failure: keyword rules decide
+	ollamaServer.FailEmbeddings(http.StatusInternalServerError)
+	got = classifier.Classify(context.Background(), "how do i link my account?")
+	assert.Equal(t, IntentAccountHelp, got.Intent)
+	assert.Equal(t, IntentMethodKeyword, got.Method)
+
+	// Not loaded: keyword rules decide without embedding
+	unloaded := NewIntentClassifier(ollama.NewClient(ollamaServer.URL), "nomic-embed-text", nil, getTestLogger())
+	assert.Equal(t, IntentMethodKeyword, unloaded.Classify(context.Background(), "how does metabolism work").Method)
+}
+
+func TestLoadIntentExamples(t *testing.T) {
+	examples, err := LoadIntentExamples(testIntentExamplesFile)
+	require.NoError(t, err)
+	for _, intent := range allIntents {
+		assert.NotEmpty(t, examples[intent], "no examples for %s", intent)
+	}
+
+	path := t.TempDir() + "/intents.yaml"
+	require.NoError(t, os.WriteFile(path, []byte("smalltalk:\n  - hi\n"), 0o600))
+	_, err = LoadIntentExamples(path)
+	assert.ErrorContains(t, err, "unknown intent")
+}
+
 func TestQueryIntent_NeedsRAG(t *testing.T) {
 	tests := []struct {
 		intent   QueryIntent
@@ -169,6 +362,7 @@ func TestQueryIntent_String(t *testing.T) {
 		{IntentConversational, "conversational"},
 		{IntentNavigation, "navigation"},
 		{IntentAccountHelp, "account_help"},
+		{IntentIdentity, "identity"},
 	}
 
 	for _, tt := range tests {
@@ -2,16 +2,20 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
-	"os"
 	"strings"
+	"sync"
 	"time"
 
-	"gopkg.in/yaml.v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
+	"living-lands-bot/internal/metrics"
+	"living-lands-bot/internal/tracing"
 	"living-lands-bot/pkg/language"
-	"living-lands-bot/pkg/ollama"
+	"living-lands-bot/pkg/llm"
 )
 
 // ResponseMode determines the complexity of the LLM response.
@@ -39,18 +43,6 @@ func (m ResponseMode) String() string {
 	}
 }
 
-// Personality represents the LLM's character and behavior.
-type Personality struct {
-	Name               string `yaml:"name"`
-	Role               string `yaml:"role"`
-	Tone               string `yaml:"tone"`
-	Knowledge          string `yaml:"knowledge"`
-	SystemPrompt       string `yaml:"system_prompt"`
-	FastModePrompt     string `yaml:"fast_mode_prompt"`
-	StandardModePrompt string `yaml:"standard_mode_prompt"`
-	DeepModePrompt     string `yaml:"deep_mode_prompt"`
-}
-
 // LLMConfig holds tunable parameters for LLM generation.
 type LLMConfig struct {
 	// Fast mode - for conversational queries (greetings, thanks, etc.)
@@ -74,6 +66,18 @@ type LLMConfig struct {
 	// Common settings
 	RepeatPenalty float64
 	NumContext    int
+	MaxNumContext int // Largest context window a request may grow to (0 = never grow)
+
+	// Fallback chain - a backend is skipped once its breaker trips
+	BreakerFailures int           // Consecutive failures/slow responses before tripping
+	BreakerCooldown time.Duration // How long a tripped backend is skipped before a probe
+	SlowThreshold   time.Duration // Successful responses slower than this count as failures
+	AttemptTimeout  time.Duration // Per-backend limit while a later backend remains (0 = none)
+
+	// Work queue - bounds concurrent generations against the backends
+	MaxConcurrent int // Generations running at once
+	MaxPerGuild   int // Generations running at once per guild (0 = no limit)
+	MaxQueueDepth int // Waiting requests before new ones are shed
 }
 
 // DefaultLLMConfig returns optimized default settings.
@@ -100,26 +104,64 @@ func DefaultLLMConfig() LLMConfig {
 		// Common: encourage diverse responses
 		RepeatPenalty: 1.1,
 		NumContext:    2048, // Reduced context window for speed
+		MaxNumContext: 8192,
+
+		// Fallback: give up on a backend quickly so the next one can answer
+		BreakerFailures: 3,
+		BreakerCooldown: 30 * time.Second,
+		SlowThreshold:   20 * time.Second,
+		AttemptTimeout:  20 * time.Second,
+
+		// Queue: a single local GPU serves a couple of generations well
+		MaxConcurrent: 2,
+		MaxPerGuild:   0,
+		MaxQueueDepth: 10,
 	}
 }
 
+// LLMBackend is one entry in the generation fallback chain: a provider and
+// the model to request from it.
+type LLMBackend struct {
+	// Name identifies the backend in logs and /health (defaults to Model)
+	Name      string
+	Generator llm.Generator
+	Model     string
+}
+
+// llmBackend pairs a configured backend with its circuit breaker.
+type llmBackend struct {
+	LLMBackend
+	breaker *CircuitBreaker
+}
+
 // LLMService handles LLM generation with RAG context.
 type LLMService struct {
-	client      *ollama.Client
-	model       string
-	personality Personality
-	config      LLMConfig
-	logger      *slog.Logger
+	backends      []*llmBackend
+	dispatcher    *LLMDispatcher
+	personalities *PersonalityRegistry
+	guard         *OutputGuard // Screens answers before they are returned; nil allows all
+	tokenizer     Tokenizer    // Estimates prompt sizes for every model in the chain
+	config        LLMConfig
+	logger        *slog.Logger
+
+	// Condensed system prompts for different modes, by personality
+	promptsMu sync.RWMutex
+	prompts   map[string]persona
+}
+
+// persona is a personality with its system prompt for each mode.
+type persona struct {
+	Personality
 
-	// Condensed system prompts for different modes
-	fastSystemPrompt     string
-	standardSystemPrompt string
-	deepSystemPrompt     string
+	fast     string
+	standard string
+	deep     string
 }
 
 // LLMMetrics holds timing and token information for observability.
 type LLMMetrics struct {
 	Mode            ResponseMode
+	Model           string
 	TotalDuration   time.Duration
 	PromptTokens    int
 	GeneratedTokens int
@@ -128,72 +170,144 @@ type LLMMetrics struct {
 	GenerationTime  time.Duration
 }
 
+// Answer is a generated response with the metrics of the call that produced it.
+type Answer struct {
+	Text        string
+	Personality string      // Registry name of the personality that answered
+	Embed       *EmbedStyle // Send as an embed in this style, if set
+	NoAnswer    bool        // The model said the documentation does not cover the question
+	Metrics     LLMMetrics
+}
+
 // NewLLMService initializes an LLM service with personality configuration.
-func NewLLMService(ollamaClient *ollama.Client, model string, personalityFile string, logger *slog.Logger) (*LLMService, error) {
-	return NewLLMServiceWithConfig(ollamaClient, model, personalityFile, DefaultLLMConfig(), logger)
+// The generator may be any llm.Generator (Ollama, OpenAI-compatible server).
+func NewLLMService(generator llm.Generator, model string, personalityFile string, logger *slog.Logger) (*LLMService, error) {
+	return NewLLMServiceWithConfig(generator, model, personalityFile, DefaultLLMConfig(), logger)
 }
 
 // NewLLMServiceWithConfig initializes an LLM service with custom configuration.
-func NewLLMServiceWithConfig(ollamaClient *ollama.Client, model string, personalityFile string, config LLMConfig, logger *slog.Logger) (*LLMService, error) {
-	// Load personality from YAML file
-	personality, err := loadPersonality(personalityFile)
+func NewLLMServiceWithConfig(generator llm.Generator, model string, personalityFile string, config LLMConfig, logger *slog.Logger) (*LLMService, error) {
+	personalities, err := NewPersonalityRegistry(personalityFile, "", logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load personality: %w", err)
 	}
+	return NewLLMServiceWithBackends([]LLMBackend{{Generator: generator, Model: model}}, personalities, config, logger)
+}
+
+// NewLLMServiceWithBackends initializes an LLM service with an ordered
+// fallback chain. Backends are tried in order; one whose circuit breaker has
+// tripped is skipped until its cooldown elapses.
+func NewLLMServiceWithBackends(backends []LLMBackend, personalities *PersonalityRegistry, config LLMConfig, logger *slog.Logger) (*LLMService, error) {
+	if len(backends) == 0 {
+		return nil, fmt.Errorf("at least one llm backend is required")
+	}
+	if personalities == nil {
+		return nil, fmt.Errorf("a personality registry is required")
+	}
 
 	s := &LLMService{
-		client:      ollamaClient,
-		model:       model,
-		personality: personality,
-		config:      config,
-		logger:      logger,
+		dispatcher: NewLLMDispatcher(DispatcherConfig{
+			MaxConcurrent: config.MaxConcurrent,
+			MaxPerGuild:   config.MaxPerGuild,
+			MaxQueueDepth: config.MaxQueueDepth,
+		}, logger),
+		personalities: personalities,
+		config:        config,
+		logger:        logger,
+	}
+
+	names := make([]string, 0, len(backends))
+	for _, b := range backends {
+		if b.Generator == nil || b.Model == "" {
+			return nil, fmt.Errorf("llm backend %q requires a generator and model", b.Name)
+		}
+		if b.Name == "" {
+			b.Name = b.Model
+		}
+		if len(s.backends) == 0 {
+			s.tokenizer = TokenizerFor(b.Model)
+		} else {
+			s.tokenizer = s.tokenizer.conservative(TokenizerFor(b.Model))
+		}
+		s.backends = append(s.backends, &llmBackend{
+			LLMBackend: b,
+			breaker:    NewCircuitBreaker(b.Name, config.BreakerFailures, config.BreakerCooldown, config.SlowThreshold),
+		})
+		names = append(names, b.Name)
 	}
 
 	// Build condensed system prompts for different modes
 	s.buildCondensedPrompts()
 
 	logger.Info("llm service initialized",
-		"model", model,
-		"personality", personality.Name,
-		"role", personality.Role,
+		"model", backends[0].Model,
+		"backends", names,
+		"personalities", personalities.Names(),
+		"tokenizer", s.tokenizer.Family,
+		"context_window", config.NumContext,
+		"context_window_max", config.MaxNumContext,
 		"fast_tokens", config.FastMaxTokens,
 		"standard_tokens", config.StandardMaxTokens,
 		"deep_tokens", config.DeepMaxTokens,
+		"max_concurrent", config.MaxConcurrent,
+		"max_queue_depth", config.MaxQueueDepth,
 	)
 
 	return s, nil
 }
 
-// buildCondensedPrompts creates optimized system prompts for each mode.
+// buildCondensedPrompts creates optimized system prompts for each mode of
+// every registered personality.
 func (s *LLMService) buildCondensedPrompts() {
-	// Fast mode: Minimal prompt for greetings and simple responses
-	s.fastSystemPrompt = s.personality.FastModePrompt
-
-	// Standard mode: Condensed personality for general questions
-	s.standardSystemPrompt = s.personality.StandardModePrompt
+	personalities := s.personalities.snapshot()
+	prompts := make(map[string]persona, len(personalities))
+	for name, p := range personalities {
+		prompts[name] = persona{
+			Personality: p,
+			// Fast mode: Minimal prompt for greetings and simple responses
+			fast: p.FastModePrompt,
+			// Standard mode: Condensed personality for general questions
+			standard: p.StandardModePrompt,
+			// Deep mode: Use full system prompt from the personality file
+			deep: p.DeepModePrompt,
+		}
+	}
 
-	// Deep mode: Use full system prompt from personality.yaml
-	s.deepSystemPrompt = s.personality.DeepModePrompt
+	s.promptsMu.Lock()
+	s.prompts = prompts
+	s.promptsMu.Unlock()
 }
 
-// loadPersonality reads and parses the personality YAML file.
-func loadPersonality(filePath string) (Personality, error) {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return Personality{}, fmt.Errorf("failed to read personality file: %w", err)
+// ReloadPersonalities re-reads the personality files and rebuilds the
+// prompts, without a restart. On error the loaded personalities are kept.
+func (s *LLMService) ReloadPersonalities() error {
+	if err := s.personalities.Reload(); err != nil {
+		return fmt.Errorf("failed to reload personalities: %w", err)
 	}
+	s.buildCondensedPrompts()
+	s.logger.Info("personalities reloaded", "personalities", s.personalities.Names())
+	return nil
+}
 
-	var p Personality
-	if err := yaml.Unmarshal(data, &p); err != nil {
-		return Personality{}, fmt.Errorf("failed to parse personality yaml: %w", err)
-	}
+// WatchPersonalities reloads the personalities whenever their files change,
+// checking every interval until ctx is done.
+func (s *LLMService) WatchPersonalities(ctx context.Context, interval time.Duration) {
+	s.personalities.Watch(ctx, interval, func() {
+		s.buildCondensedPrompts()
+		s.logger.Info("personalities reloaded", "personalities", s.personalities.Names())
+	})
+}
 
-	// Validate required fields
-	if p.SystemPrompt == "" {
-		return Personality{}, fmt.Errorf("personality missing system_prompt")
-	}
+// SetOutputGuard screens every generated answer with guard. Answers it
+// blocks are logged with their prompt and GenerateAnswer returns
+// ErrAnswerBlocked.
+func (s *LLMService) SetOutputGuard(guard *OutputGuard) {
+	s.guard = guard
+}
 
-	return p, nil
+// Personalities returns the registry the service answers from.
+func (s *LLMService) Personalities() *PersonalityRegistry {
+	return s.personalities
 }
 
 // DetermineMode selects the appropriate response mode based on intent.
@@ -222,6 +336,16 @@ func (s *LLMService) GenerateResponse(ctx context.Context, userMessage string, r
 
 // GenerateResponseWithIntent generates a response using the appropriate mode for the intent.
 func (s *LLMService) GenerateResponseWithIntent(ctx context.Context, userMessage string, ragContext []string, intent QueryIntent) (string, error) {
+	answer, err := s.GenerateAnswer(ctx, userMessage, ragContext, intent)
+	if err != nil {
+		return "", err
+	}
+	return answer.Text, nil
+}
+
+// GenerateAnswer is like GenerateResponseWithIntent but also reports the
+// mode and generation metrics, for callers that log answers.
+func (s *LLMService) GenerateAnswer(ctx context.Context, userMessage string, ragContext []string, intent QueryIntent) (_ *Answer, err error) {
 	startTime := time.Now()
 
 	// Sanitize user input to prevent prompt injection
@@ -230,28 +354,58 @@ func (s *LLMService) GenerateResponseWithIntent(ctx context.Context, userMessage
 	// Determine the response mode based on intent
 	mode := DetermineMode(intent, len(ragContext) > 0)
 
-	// Detect the language of the user's message
-	detectedLang, confidence := language.Detect(userMessage)
+	// The channel's personality, else a seasonal one, else the default
+	personality := s.personalities.Select(personalityFrom(ctx))
+
+	ctx, span := tracing.Tracer().Start(ctx, "llm.GenerateResponse", trace.WithAttributes(
+		attribute.String("llm.mode", mode.String()),
+		attribute.String("llm.personality", personality),
+		attribute.String("intent", intent.String()),
+		attribute.Int("rag.context_count", len(ragContext)),
+	))
+	defer func() { tracing.EndSpan(span, err) }()
 
-	// Build prompt with RAG context (if any)
-	prompt := s.buildPrompt(userMessage, ragContext, mode)
+	// Detect the language of the user's message; a guess on a short
+	// question must not switch the answer away from English
+	detectedLang, confidence := language.Detect(userMessage)
+	replyLang := detectedLang
+	if confidence < language.MinConfidence {
+		replyLang = language.English
+	}
 
-	// Get system prompt for this mode
-	systemPrompt := s.getSystemPrompt(mode, detectedLang)
+	// Build the chat transcript: persona, retrieved docs, user turn
+	p := s.persona(personality)
+	messages, budget := s.buildMessages(p, userMessage, ragContext, mode, replyLang)
+	span.SetAttributes(
+		attribute.Int("llm.context_window", budget.Window),
+		attribute.Int("llm.prompt_tokens_estimated", budget.Prompt()),
+		attribute.Int("llm.examples_dropped", budget.ExamplesDropped),
+		attribute.Int("rag.docs_kept", budget.DocsKept),
+		attribute.Int("rag.docs_dropped", budget.DocsDropped),
+	)
+	if budget.Remaining() < 0 {
+		// The server drops the start of an oversized prompt: the persona
+		s.logger.Warn("prompt exceeds context window",
+			"context_window", budget.Window,
+			"estimated_prompt_tokens", budget.Prompt(),
+			"reserved_output_tokens", budget.Output,
+			"examples_dropped", budget.ExamplesDropped,
+			"personality", personality,
+			"mode", mode.String(),
+		)
+	}
 
-	// Get generation options for this mode
+	// Get generation options for this mode, in the window the prompt needs
 	options := s.getOptions(mode)
+	options.NumCtx = budget.Window
 
-	// Call Ollama
-	req := ollama.GenerateRequest{
-		Model:   s.model,
-		Prompt:  prompt,
-		System:  systemPrompt,
-		Stream:  false,
-		Options: options,
+	// Call the first healthy backend in the fallback chain
+	req := llm.CompletionRequest{
+		Messages: messages,
+		Options:  options,
 	}
 
-	resp, err := s.client.Generate(ctx, req)
+	resp, backend, err := s.complete(ctx, req)
 	if err != nil {
 		s.logger.Error("llm generation failed",
 			"error", err,
@@ -259,44 +413,193 @@ func (s *LLMService) GenerateResponseWithIntent(ctx context.Context, userMessage
 			"intent", intent.String(),
 			"duration_ms", time.Since(startTime).Milliseconds(),
 		)
-		return "", fmt.Errorf("llm generation failed: %w", err)
+		metrics.LLMGenerationFailed(mode.String())
+		return nil, fmt.Errorf("llm generation failed: %w", err)
 	}
 
-	// Clean up response
-	answer := strings.TrimSpace(resp.Response)
+	// Clean up response, then apply the personality's rules
+	text, noAnswer := StripNoAnswer(strings.TrimSpace(resp.Text))
+	answer, refused := p.PostProcess(text)
+	if refused {
+		span.AddEvent("answer refused by forbidden phrase")
+		s.logger.Info("answer replaced by forbidden phrase rule", "personality", personality)
+	}
 
-	// Remove trailing prompt template artifacts
-	// Look for double newline followed by "User:" or "User:" at the end
-	patterns := []string{"\n\nUser:", "\nUser:", "\nUser :", "\n\nAssistant:", "\nAssistant:"}
-	for _, pattern := range patterns {
-		if idx := strings.Index(answer, pattern); idx != -1 {
-			answer = strings.TrimSpace(answer[:idx])
+	// Calculate and log metrics
+	genMetrics := s.calculateMetrics(resp, backend.Model, mode, startTime)
+	s.logMetrics(userMessage, detectedLang, confidence, ragContext, answer, genMetrics)
+	s.logger.Debug("context budget",
+		"tokenizer", s.tokenizer.Family,
+		"context_window", budget.Window,
+		"estimated_prompt_tokens", budget.Prompt(),
+		"actual_prompt_tokens", genMetrics.PromptTokens,
+		"system_tokens", budget.System,
+		"history_tokens", budget.History,
+		"examples_dropped", budget.ExamplesDropped,
+		"question_tokens", budget.Question,
+		"docs_tokens", budget.Docs,
+		"docs_kept", budget.DocsKept,
+		"docs_dropped", budget.DocsDropped,
+	)
+	metrics.LLMGenerationCompleted(mode.String(), genMetrics.Model, genMetrics.TotalDuration, genMetrics.PromptTokens, genMetrics.GeneratedTokens)
+
+	// Screen the answer before it can reach Discord
+	if s.guard != nil {
+		verdict := s.guard.Check(ctx, answer)
+		if verdict.Blocked {
+			span.AddEvent("answer blocked by output guard", trace.WithAttributes(
+				attribute.String("guard.reason", verdict.Reason),
+			))
+			metrics.OutputGuarded(verdict.Reason)
+			// Logged in full, with the messages that produced it (system
+			// prompt, few-shot turns, documents and question), so moderators
+			// can review what was withheld
+			chunkIDs, sources := sourcesFrom(ctx)
+			s.logger.Warn("llm output blocked",
+				"reason", verdict.Reason,
+				"match", verdict.Match,
+				"personality", personality,
+				"mode", mode.String(),
+				"model", genMetrics.Model,
+				"prompt", userMessage,
+				"chunk_ids", chunkIDs,
+				"sources", sources,
+				"messages", messages,
+				"output", answer,
+			)
+			return nil, fmt.Errorf("%w: %s", ErrAnswerBlocked, verdict.Reason)
+		}
+		if verdict.LinksDefanged > 0 || verdict.InvitesRemoved > 0 {
+			metrics.OutputGuardedLinks(verdict.LinksDefanged, verdict.InvitesRemoved)
+			s.logger.Info("links filtered from llm output",
+				"links_defanged", verdict.LinksDefanged,
+				"invites_removed", verdict.InvitesRemoved,
+				"prompt", userMessage,
+			)
 		}
+		answer = verdict.Text
 	}
 
-	// Final trim
-	answer = strings.TrimSpace(answer)
+	return &Answer{Text: answer, Personality: personality, Embed: p.Embed, NoAnswer: noAnswer, Metrics: genMetrics}, nil
+}
 
-	// Calculate and log metrics
-	metrics := s.calculateMetrics(resp, mode, startTime)
-	s.logMetrics(userMessage, detectedLang, confidence, ragContext, answer, metrics)
+// Acquire reserves a generation slot for guildID, queueing behind other
+// requests if the backends are busy. Fast-mode requests jump ahead of standard
+// and deep ones. The returned function must be called when generation is done.
+// See LLMDispatcher.Acquire for onQueued and the ErrQueueFull load-shedding error.
+func (s *LLMService) Acquire(ctx context.Context, guildID string, mode ResponseMode, onQueued func(position int)) (func(), error) {
+	return s.dispatcher.Acquire(ctx, guildID, PriorityForMode(mode), onQueued)
+}
 
-	return answer, nil
+// QueueStats returns the number of generations running and waiting.
+func (s *LLMService) QueueStats() (active, waiting int) {
+	return s.dispatcher.Active(), s.dispatcher.QueueDepth()
 }
 
-// getSystemPrompt returns the appropriate system prompt for the mode.
-func (s *LLMService) getSystemPrompt(mode ResponseMode, lang language.Language) string {
+// complete sends req to each backend in order until one succeeds. Backends
+// with an open breaker are skipped; every backend except the last gets
+// AttemptTimeout so a hung server can't consume the caller's whole deadline.
+func (s *LLMService) complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, *llmBackend, error) {
+	var errs []error
+
+	for i, b := range s.backends {
+		if !b.breaker.Allow() {
+			errs = append(errs, fmt.Errorf("%s: circuit open", b.Name))
+			continue
+		}
+
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if s.config.AttemptTimeout > 0 && i < len(s.backends)-1 {
+			attemptCtx, cancel = context.WithTimeout(ctx, s.config.AttemptTimeout)
+		}
+
+		req.Model = b.Model
+		start := time.Now()
+		resp, err := b.Generator.Complete(attemptCtx, req)
+		cancel()
+		if err != nil && ctx.Err() != nil {
+			// The caller's own deadline or cancellation says nothing about
+			// the backend's health
+			b.breaker.Release()
+		} else {
+			b.breaker.Record(time.Since(start), err)
+		}
+
+		if err == nil {
+			if i > 0 {
+				s.logger.Warn("llm served by fallback backend", "backend", b.Name)
+			}
+			trace.SpanFromContext(ctx).SetAttributes(
+				attribute.String("llm.backend", b.Name),
+				attribute.String("llm.model", b.Model),
+			)
+			return resp, b, nil
+		}
+
+		trace.SpanFromContext(ctx).AddEvent("llm backend failed", trace.WithAttributes(
+			attribute.String("llm.backend", b.Name),
+			attribute.String("error", err.Error()),
+		))
+
+		errs = append(errs, fmt.Errorf("%s: %w", b.Name, err))
+
+		// The caller gave up; later backends would fail the same way
+		if ctx.Err() != nil {
+			break
+		}
+
+		s.logger.Warn("llm backend failed",
+			"backend", b.Name,
+			"error", err,
+			"breaker", b.breaker.State().String(),
+		)
+	}
+
+	return nil, nil, errors.Join(errs...)
+}
+
+// BackendHealth returns the breaker state of each backend in fallback order.
+func (s *LLMService) BackendHealth() []BreakerSnapshot {
+	snaps := make([]BreakerSnapshot, 0, len(s.backends))
+	for _, b := range s.backends {
+		snaps = append(snaps, b.breaker.Snapshot())
+	}
+	return snaps
+}
+
+// Degraded reports whether any backend's breaker is not closed.
+func (s *LLMService) Degraded() bool {
+	for _, b := range s.backends {
+		if b.breaker.State() != BreakerClosed {
+			return true
+		}
+	}
+	return false
+}
+
+// persona returns a personality and its prompts, or the default one's.
+func (s *LLMService) persona(name string) persona {
+	s.promptsMu.RLock()
+	defer s.promptsMu.RUnlock()
+	if p, ok := s.prompts[name]; ok {
+		return p
+	}
+	return s.prompts[DefaultPersonality]
+}
+
+// getSystemPrompt returns the personality's system prompt for the mode.
+func (s *LLMService) getSystemPrompt(prompts persona, mode ResponseMode, lang language.Language) string {
 	var systemPrompt string
 
 	switch mode {
 	case ModeFast:
-		systemPrompt = s.fastSystemPrompt
+		systemPrompt = prompts.fast
 	case ModeStandard:
-		systemPrompt = s.standardSystemPrompt
+		systemPrompt = prompts.standard
 	case ModeDeep:
-		systemPrompt = s.deepSystemPrompt
+		systemPrompt = prompts.deep
 	default:
-		systemPrompt = s.standardSystemPrompt
+		systemPrompt = prompts.standard
 	}
 
 	// Add language instruction if non-English
@@ -308,39 +611,39 @@ func (s *LLMService) getSystemPrompt(mode ResponseMode, lang language.Language)
 }
 
 // getOptions returns generation options tuned for the response mode.
-func (s *LLMService) getOptions(mode ResponseMode) ollama.Options {
+func (s *LLMService) getOptions(mode ResponseMode) llm.Options {
 	switch mode {
 	case ModeFast:
-		return ollama.Options{
+		return llm.Options{
 			Temperature:   s.config.FastTemperature,
-			NumPredict:    s.config.FastMaxTokens,
+			MaxTokens:     s.config.FastMaxTokens,
 			TopK:          s.config.FastTopK,
 			TopP:          s.config.FastTopP,
 			RepeatPenalty: s.config.RepeatPenalty,
 			NumCtx:        s.config.NumContext,
 		}
 	case ModeStandard:
-		return ollama.Options{
+		return llm.Options{
 			Temperature:   s.config.StandardTemperature,
-			NumPredict:    s.config.StandardMaxTokens,
+			MaxTokens:     s.config.StandardMaxTokens,
 			TopK:          s.config.StandardTopK,
 			TopP:          s.config.StandardTopP,
 			RepeatPenalty: s.config.RepeatPenalty,
 			NumCtx:        s.config.NumContext,
 		}
 	case ModeDeep:
-		return ollama.Options{
+		return llm.Options{
 			Temperature:   s.config.DeepTemperature,
-			NumPredict:    s.config.DeepMaxTokens,
+			MaxTokens:     s.config.DeepMaxTokens,
 			TopK:          s.config.DeepTopK,
 			TopP:          s.config.DeepTopP,
 			RepeatPenalty: s.config.RepeatPenalty,
 			NumCtx:        s.config.NumContext,
 		}
 	default:
-		return ollama.Options{
+		return llm.Options{
 			Temperature:   s.config.StandardTemperature,
-			NumPredict:    s.config.StandardMaxTokens,
+			MaxTokens:     s.config.StandardMaxTokens,
 			TopK:          s.config.StandardTopK,
 			TopP:          s.config.StandardTopP,
 			RepeatPenalty: s.config.RepeatPenalty,
@@ -349,51 +652,101 @@ func (s *LLMService) getOptions(mode ResponseMode) ollama.Options {
 	}
 }
 
-// buildPrompt constructs the final prompt with RAG context.
-func (s *LLMService) buildPrompt(userMessage string, ragContext []string, mode ResponseMode) string {
-	var prompt strings.Builder
+// buildMessages constructs the chat messages for a request. The persona prompt
+// and any RAG documentation go in separate system messages so retrieved text
+// and user input can never masquerade as instructions. The context window
+// grows to fit the prompt and the answer's reserved tokens, up to
+// MaxNumContext; if the persona, examples and question still don't fit, the
+// few-shot examples are dropped so the server never cuts the persona.
+// Documentation fills whatever room is left. The returned budget reports how
+// the window was spent.
+func (s *LLMService) buildMessages(p persona, userMessage string, ragContext []string, mode ResponseMode, lang language.Language) ([]llm.Message, ContextBudget) {
+	systemPrompt := s.getSystemPrompt(p, mode, lang)
+	budget := ContextBudget{
+		Output:   s.getOptions(mode).MaxTokens,
+		System:   s.tokenizer.Count(systemPrompt) + messageOverhead,
+		Question: s.tokenizer.Count(userMessage) + messageOverhead,
+	}
+	examples := p.Examples.For(mode)
+	for _, ex := range examples {
+		budget.History += s.tokenizer.Count(ex.User) + s.tokenizer.Count(ex.Assistant) + 2*messageOverhead
+	}
 
 	// Only add RAG context for Deep mode with actual context
-	if mode == ModeDeep && len(ragContext) > 0 {
-		prompt.WriteString("Relevant documentation (use only if it answers the question):\n")
-		for i, ctx := range ragContext {
-			// Truncate very long contexts to save tokens
-			truncated := ctx
-			if len(truncated) > 500 {
-				truncated = truncated[:500] + "..."
-			}
-			prompt.WriteString(fmt.Sprintf("%d. %s\n", i+1, truncated))
+	withDocs := mode == ModeDeep && len(ragContext) > 0
+	const docsHeader = "Relevant documentation (use only if it answers the question):\n"
+
+	needed := budget.Prompt() + budget.Output
+	if withDocs {
+		needed += docsTokens(s.tokenizer, docsHeader, ragContext)
+	}
+	budget.Window = s.contextWindow(needed)
+
+	if budget.Remaining() < 0 && len(examples) > 0 {
+		budget.ExamplesDropped = len(examples)
+		budget.History = 0
+		examples = nil
+	}
+
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: systemPrompt},
+	}
+
+	// Few-shot examples, as earlier turns of the conversation
+	for _, ex := range examples {
+		messages = append(messages,
+			llm.Message{Role: llm.RoleUser, Content: ex.User},
+			llm.Message{Role: llm.RoleAssistant, Content: ex.Assistant},
+		)
+	}
+
+	if withDocs {
+		if docs := budget.fitDocs(s.tokenizer, docsHeader, ragContext); docs != "" {
+			messages = append(messages, llm.Message{Role: llm.RoleSystem, Content: docs})
 		}
-		prompt.WriteString("\n---\n\n")
 	}
 
-	// Add the user's question
-	prompt.WriteString(fmt.Sprintf("User: %s\nAssistant:", userMessage))
+	messages = append(messages, llm.Message{Role: llm.RoleUser, Content: userMessage})
 
-	return prompt.String()
+	return messages, budget
+}
+
+// contextWindow returns the context window for a request needing tokens:
+// NumContext, doubled until the request fits, at most MaxNumContext. Ollama
+// reloads the model whenever num_ctx changes, so the window only takes a few
+// distinct sizes.
+func (s *LLMService) contextWindow(tokens int) int {
+	window := s.config.NumContext
+	for window < tokens && window < s.config.MaxNumContext {
+		window = min(window*2, s.config.MaxNumContext)
+	}
+	return window
 }
 
 // calculateMetrics extracts timing and token metrics from the response.
-func (s *LLMService) calculateMetrics(resp *ollama.GenerateResponse, mode ResponseMode, startTime time.Time) LLMMetrics {
+// Providers that don't report generation timings (OpenAI-compatible servers)
+// fall back to wall-clock time for the tokens/sec estimate.
+func (s *LLMService) calculateMetrics(resp *llm.CompletionResponse, model string, mode ResponseMode, startTime time.Time) LLMMetrics {
 	metrics := LLMMetrics{
 		Mode:            mode,
+		Model:           resp.Model,
 		TotalDuration:   time.Since(startTime),
-		PromptTokens:    resp.PromptEvalCount,
-		GeneratedTokens: resp.EvalCount,
+		PromptTokens:    resp.PromptTokens,
+		GeneratedTokens: resp.CompletionTokens,
+		PromptEvalTime:  resp.PromptDuration,
+		GenerationTime:  resp.EvalDuration,
 	}
-
-	// Calculate tokens per second if we have eval data
-	if resp.EvalDuration > 0 && resp.EvalCount > 0 {
-		evalSeconds := float64(resp.EvalDuration) / 1e9
-		metrics.TokensPerSecond = float64(resp.EvalCount) / evalSeconds
+	if metrics.Model == "" {
+		metrics.Model = model
 	}
 
-	// Convert nanoseconds to durations
-	if resp.PromptEvalDuration > 0 {
-		metrics.PromptEvalTime = time.Duration(resp.PromptEvalDuration)
+	// Calculate tokens per second from eval time, or total time if unavailable
+	genTime := resp.EvalDuration
+	if genTime <= 0 {
+		genTime = resp.TotalDuration
 	}
-	if resp.EvalDuration > 0 {
-		metrics.GenerationTime = time.Duration(resp.EvalDuration)
+	if genTime > 0 && resp.CompletionTokens > 0 {
+		metrics.TokensPerSecond = float64(resp.CompletionTokens) / genTime.Seconds()
 	}
 
 	return metrics
@@ -404,6 +757,7 @@ func (s *LLMService) logMetrics(userMessage string, lang language.Language, conf
 	// Log at info level for monitoring
 	s.logger.Info("llm response generated",
 		"mode", metrics.Mode.String(),
+		"model", metrics.Model,
 		"duration_ms", metrics.TotalDuration.Milliseconds(),
 		"prompt_tokens", metrics.PromptTokens,
 		"generated_tokens", metrics.GeneratedTokens,
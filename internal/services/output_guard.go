@@ -0,0 +1,278 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"living-lands-bot/pkg/llm"
+)
+
+type sourcesKey struct{}
+
+// WithSources returns ctx noting the documents retrieved for the question, so
+// an answer the output guard blocks can be traced back to them.
+func WithSources(ctx context.Context, results []RAGResult) context.Context {
+	return context.WithValue(ctx, sourcesKey{}, results)
+}
+
+// sourcesFrom returns the chunk IDs and source files noted in ctx.
+func sourcesFrom(ctx context.Context) (ids, sources []string) {
+	results, _ := ctx.Value(sourcesKey{}).([]RAGResult)
+	for _, r := range results {
+		ids = append(ids, r.ID)
+		sources = append(sources, r.Source)
+	}
+	return ids, sources
+}
+
+// ErrAnswerBlocked is returned by GenerateAnswer when the output guard
+// withholds a generated answer.
+var ErrAnswerBlocked = errors.New("answer blocked by output guard")
+
+// Reasons the output guard blocks an answer.
+const (
+	OutputBlockedWord    = "blocked_word"
+	OutputBlockedPattern = "blocked_pattern"
+	OutputToxic          = "toxic"
+)
+
+// toxicityTimeout bounds the moderation call so a slow model can't hold up
+// an answer that is otherwise ready.
+const toxicityTimeout = 10 * time.Second
+
+// toxicityPrompt asks the moderation model for a one-word verdict.
+const toxicityPrompt = `You are a content moderator for a friendly gaming community's Discord server.
+Decide whether the message you are given is toxic: insults, harassment, hate speech, slurs, threats, sexual content, or encouragement of self-harm.
+Game violence (fighting monsters, hunting, dying of hunger) is not toxic.
+Reply with exactly one word: SAFE or TOXIC.`
+
+// OutputRules configure the output guard. They are read from a YAML file
+// by LoadOutputRules.
+type OutputRules struct {
+	// Links to these hosts, or their subdomains, are kept as they are
+	AllowedHosts []string `yaml:"allowed_hosts"`
+	// Words or phrases, matched whole and ignoring case, that block an answer
+	BlockedWords []string `yaml:"blocked_words"`
+	// Regular expressions (RE2 syntax) that block an answer
+	BlockedPatterns []string `yaml:"blocked_patterns"`
+}
+
+// LoadOutputRules reads output guard rules from a YAML file. Unknown keys
+// are rejected so a typo can't silently disable a rule.
+func LoadOutputRules(filePath string) (OutputRules, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return OutputRules{}, fmt.Errorf("failed to read output guard file: %w", err)
+	}
+
+	var rules OutputRules
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&rules); err != nil && !errors.Is(err, io.EOF) {
+		return OutputRules{}, fmt.Errorf("failed to parse output guard yaml: %w", err)
+	}
+	return rules, nil
+}
+
+// OutputVerdict is the output guard's decision on a generated answer.
+type OutputVerdict struct {
+	Text           string // The answer with disallowed links defanged and invites removed
+	Blocked        bool
+	Reason         string // OutputBlockedWord, OutputBlockedPattern or OutputToxic
+	Match          string // The word or pattern that blocked the answer
+	LinksDefanged  int
+	InvitesRemoved int
+}
+
+// blockedRule is a compiled blocked word or pattern.
+type blockedRule struct {
+	reason string
+	source string
+	re     *regexp.Regexp
+}
+
+// OutputGuard screens generated answers before they reach Discord. Links to
+// hosts that aren't allowlisted are defanged so they can't be clicked,
+// Discord invites are removed, and answers matching a blocked word, a blocked
+// pattern or, if a moderation model is set, judged toxic are withheld.
+type OutputGuard struct {
+	allowedHosts []string
+	rules        []blockedRule
+	logger       *slog.Logger
+
+	// Optional moderation model; nil skips the toxicity check
+	classifier llm.Generator
+	model      string
+}
+
+// NewOutputGuard compiles the rules into a guard.
+func NewOutputGuard(rules OutputRules, logger *slog.Logger) (*OutputGuard, error) {
+	g := &OutputGuard{logger: logger}
+
+	for _, host := range rules.AllowedHosts {
+		host = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(host)), "www.")
+		if host == "" {
+			return nil, fmt.Errorf("invalid output guard rules: empty allowed host")
+		}
+		g.allowedHosts = append(g.allowedHosts, host)
+	}
+
+	for _, word := range rules.BlockedWords {
+		word = strings.TrimSpace(word)
+		if word == "" {
+			return nil, fmt.Errorf("invalid output guard rules: empty blocked word")
+		}
+		// Letters and digits on either side would make it part of another word
+		re := regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}])` + regexp.QuoteMeta(word) + `(?:$|[^\p{L}\p{N}])`)
+		g.rules = append(g.rules, blockedRule{reason: OutputBlockedWord, source: word, re: re})
+	}
+
+	for _, pattern := range rules.BlockedPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output guard rules: blocked pattern %q: %w", pattern, err)
+		}
+		g.rules = append(g.rules, blockedRule{reason: OutputBlockedPattern, source: pattern, re: re})
+	}
+
+	return g, nil
+}
+
+// SetToxicityModel enables the toxicity check, asking model on generator
+// (ideally a small local one) to judge each answer. If the model can't be
+// reached the answer is allowed, since the other rules still apply.
+func (g *OutputGuard) SetToxicityModel(generator llm.Generator, model string) {
+	g.classifier = generator
+	g.model = model
+}
+
+var (
+	// inviteLink matches Discord invites, with or without a scheme
+	inviteLink = regexp.MustCompile(`(?i)(?:https?://)?(?:www\.)?(?:discord(?:app)?\.com/invite|discord\.(?:gg|io|me))/[A-Za-z0-9-]+`)
+	// webLink matches links Discord would make clickable, and www. hosts
+	webLink = regexp.MustCompile(`(?i)(?:https?://|www\.)[^\s<>()\[\]"'` + "`" + `]+`)
+)
+
+// Check screens an answer. Blocked words and patterns are matched against
+// the text as generated; links are rewritten in the text that is returned.
+func (g *OutputGuard) Check(ctx context.Context, answer string) OutputVerdict {
+	for _, rule := range g.rules {
+		if rule.re.MatchString(answer) {
+			return OutputVerdict{Blocked: true, Reason: rule.reason, Match: rule.source}
+		}
+	}
+
+	var verdict OutputVerdict
+	verdict.Text = g.filterLinks(answer, &verdict)
+
+	if g.classifier != nil && g.isToxic(ctx, verdict.Text) {
+		return OutputVerdict{Blocked: true, Reason: OutputToxic, Match: g.model}
+	}
+
+	return verdict
+}
+
+// filterLinks removes invites and defangs links to hosts that aren't
+// allowlisted, counting both in verdict.
+func (g *OutputGuard) filterLinks(text string, verdict *OutputVerdict) string {
+	removed := false
+	text = inviteLink.ReplaceAllStringFunc(text, func(link string) string {
+		if g.allowed(linkHost(link)) {
+			return link
+		}
+		verdict.InvitesRemoved++
+		removed = true
+		return ""
+	})
+
+	text = webLink.ReplaceAllStringFunc(text, func(link string) string {
+		// Sentence punctuation after a link isn't part of it
+		trimmed := strings.TrimRight(link, ".,!?;:")
+		tail := link[len(trimmed):]
+		if g.allowed(linkHost(trimmed)) {
+			return link
+		}
+		verdict.LinksDefanged++
+		return defang(trimmed) + tail
+	})
+
+	if removed {
+		text = tidySpaces(text)
+	}
+	return text
+}
+
+// allowed reports whether host is an allowlisted host or a subdomain of one.
+func (g *OutputGuard) allowed(host string) bool {
+	for _, h := range g.allowedHosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// linkHost returns the lower-case host of a link, without "www.". Browsers
+// treat a backslash as "/", and the host follows the last "@" of any user
+// info, so "https://evil.com\@hytale.com" points at evil.com.
+func linkHost(link string) string {
+	host := link
+	if _, rest, ok := strings.Cut(host, "://"); ok {
+		host = rest
+	}
+	if i := strings.IndexAny(host, "/?#\\"); i >= 0 {
+		host = host[:i]
+	}
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+	host, _, _ = strings.Cut(host, ":")
+	return strings.TrimPrefix(strings.ToLower(host), "www.")
+}
+
+// defang drops a link's scheme and brackets the dots of its host, so it
+// reads as a link but Discord won't make it clickable.
+func defang(link string) string {
+	if _, rest, ok := strings.Cut(link, "://"); ok {
+		link = rest
+	}
+	host, path := link, ""
+	if i := strings.IndexAny(link, "/?#\\"); i >= 0 {
+		host, path = link[:i], link[i:]
+	}
+	return strings.ReplaceAll(host, ".", "[.]") + path
+}
+
+// isToxic asks the moderation model whether text is toxic. Errors are
+// logged and treated as not toxic.
+func (g *OutputGuard) isToxic(ctx context.Context, text string) bool {
+	ctx, cancel := context.WithTimeout(ctx, toxicityTimeout)
+	defer cancel()
+
+	resp, err := g.classifier.Complete(ctx, llm.CompletionRequest{
+		Model: g.model,
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: toxicityPrompt},
+			{Role: llm.RoleUser, Content: text},
+		},
+		Options: llm.Options{Temperature: 0, MaxTokens: 4},
+	})
+	if err != nil {
+		g.logger.Warn("toxicity check failed, allowing answer", "model", g.model, "error", err)
+		return false
+	}
+
+	// Moderation models such as Llama Guard answer "unsafe" rather than "TOXIC"
+	verdict := strings.ToUpper(strings.TrimSpace(resp.Text))
+	return strings.HasPrefix(verdict, "TOXIC") || strings.HasPrefix(verdict, "UNSAFE")
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"living-lands-bot/internal/testutil"
+	"living-lands-bot/pkg/ollama"
+)
+
+func newTestOutputGuard(t *testing.T) *OutputGuard {
+	t.Helper()
+
+	guard, err := NewOutputGuard(OutputRules{
+		AllowedHosts:    []string{"hytale.com", "github.com"},
+		BlockedWords:    []string{"grief", "free nitro"},
+		BlockedPatterns: []string{`(?i)password:\s*\S+`},
+	}, getTestLogger())
+	require.NoError(t, err)
+	return guard
+}
+
+func TestLoadOutputRules(t *testing.T) {
+	rules, err := LoadOutputRules("../../configs/output_guard.yaml")
+	require.NoError(t, err)
+	assert.Contains(t, rules.AllowedHosts, "hytale.com")
+
+	_, err = NewOutputGuard(rules, getTestLogger())
+	require.NoError(t, err, "the shipped patterns compile")
+
+	path := filepath.Join(t.TempDir(), "guard.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("allowed_host:\n  - hytale.com\n"), 0o644))
+	_, err = LoadOutputRules(path)
+	assert.Error(t, err, "unknown keys are rejected")
+}
+
+func TestNewOutputGuardRejectsBadPattern(t *testing.T) {
+	_, err := NewOutputGuard(OutputRules{BlockedPatterns: []string{"(unclosed"}}, getTestLogger())
+	assert.ErrorContains(t, err, "(unclosed")
+}
+
+func TestOutputGuardFiltersLinks(t *testing.T) {
+	guard := newTestOutputGuard(t)
+
+	tests := []struct {
+		name     string
+		in       string
+		want     string
+		defanged int
+		invites  int
+	}{
+		{
+			name: "allowed host and subdomain",
+			in:   "See https://hytale.com/news and https://support.hytale.com/faq.",
+			want: "See https://hytale.com/news and https://support.hytale.com/faq.",
+		},
+		{
+			name:     "other host",
+			in:       "Download it from https://mods.example.com/living-lands.",
+			want:     "Download it from mods[.]example[.]com/living-lands.",
+			defanged: 1,
+		},
+		{
+			name:     "lookalike host",
+			in:       "Log in at https://hytale.com.example.net/login",
+			want:     "Log in at hytale[.]com[.]example[.]net/login",
+			defanged: 1,
+		},
+		{
+			name:     "allowed host as user info",
+			in:       "Get it at https://evil.com\\@hytale.com/download or https://hytale.com@evil.com/download",
+			want:     "Get it at evil[.]com\\@hytale.com/download or hytale[.]com@evil[.]com/download",
+			defanged: 2,
+		},
+		{
+			name:     "masked link",
+			in:       "Read [the wiki](https://evil.example/wiki) first.",
+			want:     "Read [the wiki](evil[.]example/wiki) first.",
+			defanged: 1,
+		},
+		{
+			name:    "invites",
+			in:      "Join discord.gg/abc123 or https://discord.com/invite/xyz for help.",
+			want:    "Join or for help.",
+			invites: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict := guard.Check(context.Background(), tt.in)
+			assert.False(t, verdict.Blocked)
+			assert.Equal(t, tt.want, verdict.Text)
+			assert.Equal(t, tt.defanged, verdict.LinksDefanged)
+			assert.Equal(t, tt.invites, verdict.InvitesRemoved)
+		})
+	}
+}
+
+func TestOutputGuardBlocks(t *testing.T) {
+	guard := newTestOutputGuard(t)
+
+	tests := []struct {
+		in     string
+		reason string
+		match  string
+	}{
+		{"You should grief their base.", OutputBlockedWord, "grief"},
+		{"Claim your FREE NITRO today!", OutputBlockedWord, "free nitro"},
+		{"The admin password: hunter2", OutputBlockedPattern, `(?i)password:\s*\S+`},
+		{"Grieving players recover faster with rest.", "", ""},
+	}
+	for _, tt := range tests {
+		verdict := guard.Check(context.Background(), tt.in)
+		assert.Equal(t, tt.reason != "", verdict.Blocked, tt.in)
+		assert.Equal(t, tt.reason, verdict.Reason, tt.in)
+		assert.Equal(t, tt.match, verdict.Match, tt.in)
+	}
+}
+
+func TestOutputGuardToxicity(t *testing.T) {
+	server := testutil.NewOllamaServer(t)
+	guard := newTestOutputGuard(t)
+	guard.SetToxicityModel(ollama.NewClient(server.URL), "guard-model")
+
+	server.Script(
+		testutil.Completion{Response: " TOXIC"},
+		testutil.Completion{Response: "unsafe\nS10"},
+		testutil.Completion{Response: "SAFE"},
+		testutil.Completion{Status: http.StatusInternalServerError},
+	)
+
+	verdict := guard.Check(context.Background(), "You are all worthless.")
+	assert.True(t, verdict.Blocked)
+	assert.Equal(t, OutputToxic, verdict.Reason)
+	assert.Equal(t, "guard-model", verdict.Match)
+
+	assert.True(t, guard.Check(context.Background(), "Another insult.").Blocked, "Llama Guard style verdicts are understood")
+	assert.False(t, guard.Check(context.Background(), "Wolves hunt at night.").Blocked)
+	assert.False(t, guard.Check(context.Background(), "Rest to recover.").Blocked, "an unreachable model allows the answer")
+
+	reqs := server.ChatRequests()
+	require.Len(t, reqs, 4)
+	assert.Equal(t, "guard-model", reqs[0].Model)
+	assert.Equal(t, "You are all worthless.", reqs[0].Messages[1].Content)
+}
+
+func TestGenerateAnswerBlockedByOutputGuard(t *testing.T) {
+	svc, server := newFakeLLMService(t)
+	svc.SetOutputGuard(newTestOutputGuard(t))
+	server.Script(
+		testutil.Completion{Response: "Just grief the village, traveler."},
+		testutil.Completion{Response: "Patch notes live at https://patches.example.org/latest."},
+	)
+
+	var logs bytes.Buffer
+	svc.logger = slog.New(slog.NewJSONHandler(&logs, nil))
+	ctx := WithSources(context.Background(), []RAGResult{{ID: "pvp-3", Source: "pvp.md"}})
+
+	_, err := svc.GenerateAnswer(ctx, "how do I win?", []string{"Raids are allowed on weekends."}, IntentKnowledge)
+	require.ErrorIs(t, err, ErrAnswerBlocked)
+	assert.ErrorContains(t, err, OutputBlockedWord)
+
+	// The log ties the withheld answer to the prompt and documents behind it
+	var entry struct {
+		Msg         string   `json:"msg"`
+		Personality string   `json:"personality"`
+		Mode        string   `json:"mode"`
+		Prompt      string   `json:"prompt"`
+		ChunkIDs    []string `json:"chunk_ids"`
+		Sources     []string `json:"sources"`
+		Messages    []struct {
+			Role    string
+			Content string
+		} `json:"messages"`
+	}
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		if entry.Msg == "llm output blocked" {
+			break
+		}
+	}
+	assert.Equal(t, "llm output blocked", entry.Msg)
+	assert.Equal(t, DefaultPersonality, entry.Personality)
+	assert.Equal(t, ModeDeep.String(), entry.Mode)
+	assert.Equal(t, "how do I win?", entry.Prompt)
+	assert.Equal(t, []string{"pvp-3"}, entry.ChunkIDs)
+	assert.Equal(t, []string{"pvp.md"}, entry.Sources)
+	sent := server.ChatRequests()[0].Messages
+	require.Len(t, entry.Messages, len(sent), "every message sent to the model is logged")
+	for i, m := range sent {
+		assert.Equal(t, m.Role, entry.Messages[i].Role)
+		assert.Equal(t, m.Content, entry.Messages[i].Content)
+	}
+	assert.Contains(t, entry.Messages[0].Content, "Elder Sage", "the persona prompt is logged")
+
+	answer, err := svc.GenerateAnswer(context.Background(), "where are the patch notes?", nil, IntentKnowledge)
+	require.NoError(t, err)
+	assert.Equal(t, "Patch notes live at patches[.]example[.]org/latest.", answer.Text)
+}
@@ -0,0 +1,548 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPersonality is the registry name of the personality loaded from
+// PERSONALITY_FILE. Channels without a personality setting use it, unless a
+// seasonal personality is active.
+const DefaultPersonality = "default"
+
+// Personality represents the LLM's character and behavior.
+type Personality struct {
+	Name               string `yaml:"name"`
+	Role               string `yaml:"role"`
+	Tone               string `yaml:"tone"`
+	Knowledge          string `yaml:"knowledge"`
+	SystemPrompt       string `yaml:"system_prompt"`
+	FastModePrompt     string `yaml:"fast_mode_prompt"`
+	StandardModePrompt string `yaml:"standard_mode_prompt"`
+	DeepModePrompt     string `yaml:"deep_mode_prompt"`
+
+	// Few-shot example exchanges per mode, sent as chat turns before the question
+	Examples ModeExamples `yaml:"examples,omitempty"`
+	// Phrases the answer must not contain, and what to do when it does
+	Forbidden []ForbiddenRule `yaml:"forbidden,omitempty"`
+	// Longest answer in characters, signature included (0 = MaxAnswerLength)
+	MaxLength int `yaml:"max_length,omitempty"`
+	// Appended to every generated answer on its own line
+	Signature string `yaml:"signature,omitempty"`
+	// Sends answers as an embed in this style instead of plain text
+	Embed *EmbedStyle `yaml:"embed,omitempty"`
+
+	// Season makes this the default personality between two dates each year
+	Season *Season `yaml:"season,omitempty"`
+}
+
+// MaxAnswerLength is the longest a personality's answers may be: three
+// Discord messages of 2000 characters, the most an answer is split into before
+// it is sent as a file. It is also the default max_length.
+const MaxAnswerLength = 6000
+
+// Exchange is one few-shot example: a question and the ideal answer.
+type Exchange struct {
+	User      string `yaml:"user"`
+	Assistant string `yaml:"assistant"`
+}
+
+// ModeExamples are few-shot examples for each response mode.
+type ModeExamples struct {
+	Fast     []Exchange `yaml:"fast,omitempty"`
+	Standard []Exchange `yaml:"standard,omitempty"`
+	Deep     []Exchange `yaml:"deep,omitempty"`
+}
+
+// For returns the examples for a mode.
+func (e ModeExamples) For(mode ResponseMode) []Exchange {
+	switch mode {
+	case ModeFast:
+		return e.Fast
+	case ModeDeep:
+		return e.Deep
+	default:
+		return e.Standard
+	}
+}
+
+// Actions a ForbiddenRule takes when an answer contains one of its phrases.
+const (
+	ForbiddenRemove  = "remove"  // Drop the phrase
+	ForbiddenReplace = "replace" // Swap the phrase for Replacement
+	ForbiddenRefuse  = "refuse"  // Discard the answer and reply with Replacement
+)
+
+// ForbiddenRule matches phrases or topics, case-insensitively, in generated
+// answers.
+type ForbiddenRule struct {
+	Phrases     []string `yaml:"phrases"`
+	Action      string   `yaml:"action"`
+	Replacement string   `yaml:"replacement,omitempty"`
+}
+
+// EmbedStyle is how answers look when sent as an embed.
+type EmbedStyle struct {
+	Title  string `yaml:"title,omitempty"`
+	Color  string `yaml:"color,omitempty"` // Hex, e.g. "#2D6A4F"
+	Footer string `yaml:"footer,omitempty"`
+}
+
+// hexColor matches embed colors.
+var hexColor = regexp.MustCompile(`^#?[0-9A-Fa-f]{6}$`)
+
+// ColorValue returns the embed color as a number, or 0 for Discord's default.
+func (e EmbedStyle) ColorValue() int {
+	v, err := strconv.ParseUint(strings.TrimPrefix(e.Color, "#"), 16, 24)
+	if err != nil {
+		return 0
+	}
+	return int(v)
+}
+
+// Season is a yearly date range, inclusive, as "MM-DD". A range may wrap
+// around the new year, e.g. 12-15 to 01-05.
+type Season struct {
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+}
+
+// seasonLayout parses Season dates.
+const seasonLayout = "01-02"
+
+// Active reports whether t falls within the season.
+func (s Season) Active(t time.Time) bool {
+	start, err1 := time.Parse(seasonLayout, s.Start)
+	end, err2 := time.Parse(seasonLayout, s.End)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	day := func(m time.Month, d int) int { return int(m)*100 + d }
+	today := day(t.Month(), t.Day())
+	from, to := day(start.Month(), start.Day()), day(end.Month(), end.Day())
+	if from <= to {
+		return today >= from && today <= to
+	}
+	return today >= from || today <= to
+}
+
+// validate checks that a personality has everything the modes need.
+func (p Personality) validate() error {
+	var missing []string
+	for _, f := range []struct{ key, value string }{
+		{"name", p.Name},
+		{"system_prompt", p.SystemPrompt},
+		{"fast_mode_prompt", p.FastModePrompt},
+		{"standard_mode_prompt", p.StandardModePrompt},
+		{"deep_mode_prompt", p.DeepModePrompt},
+	} {
+		if strings.TrimSpace(f.value) == "" {
+			missing = append(missing, f.key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("personality missing %s", strings.Join(missing, ", "))
+	}
+	// Documentation gaps are found by the marker, whatever the reply language
+	if !strings.Contains(p.DeepModePrompt, NoAnswerMarker) {
+		return fmt.Errorf("personality deep_mode_prompt must ask for %s when the documentation has no answer", NoAnswerMarker)
+	}
+
+	for _, mode := range []ResponseMode{ModeFast, ModeStandard, ModeDeep} {
+		for i, ex := range p.Examples.For(mode) {
+			if strings.TrimSpace(ex.User) == "" || strings.TrimSpace(ex.Assistant) == "" {
+				return fmt.Errorf("personality examples.%s[%d] needs user and assistant", mode, i)
+			}
+		}
+	}
+
+	for i, rule := range p.Forbidden {
+		if len(rule.Phrases) == 0 {
+			return fmt.Errorf("personality forbidden[%d] has no phrases", i)
+		}
+		for _, phrase := range rule.Phrases {
+			if strings.TrimSpace(phrase) == "" {
+				return fmt.Errorf("personality forbidden[%d] has an empty phrase", i)
+			}
+		}
+		switch rule.Action {
+		case ForbiddenRemove:
+		case ForbiddenReplace, ForbiddenRefuse:
+			if strings.TrimSpace(rule.Replacement) == "" {
+				return fmt.Errorf("personality forbidden[%d] action %q needs a replacement", i, rule.Action)
+			}
+		default:
+			return fmt.Errorf("personality forbidden[%d] action must be remove, replace or refuse, got %q", i, rule.Action)
+		}
+	}
+
+	if p.MaxLength < 0 || p.MaxLength > MaxAnswerLength {
+		return fmt.Errorf("personality max_length must be between 0 and %d, got %d", MaxAnswerLength, p.MaxLength)
+	}
+	if p.Signature != "" && utf8.RuneCountInString(p.Signature) > p.maxLength()/2 {
+		return fmt.Errorf("personality signature must be at most half of max_length")
+	}
+	if p.Embed != nil && p.Embed.Color != "" && !hexColor.MatchString(p.Embed.Color) {
+		return fmt.Errorf("personality embed color must be hex like #2D6A4F, got %q", p.Embed.Color)
+	}
+
+	if p.Season != nil {
+		for _, date := range []string{p.Season.Start, p.Season.End} {
+			if _, err := time.Parse(seasonLayout, date); err != nil {
+				return fmt.Errorf("personality season dates must be MM-DD, got %q", date)
+			}
+		}
+	}
+	return nil
+}
+
+// maxLength returns the longest answer the personality may give.
+func (p Personality) maxLength() int {
+	if p.MaxLength > 0 {
+		return p.MaxLength
+	}
+	return MaxAnswerLength
+}
+
+// PostProcess applies the personality's rules to a generated answer: the
+// forbidden phrases, then the length limit, then the signature. It reports
+// whether a refuse rule replaced the answer.
+func (p Personality) PostProcess(answer string) (string, bool) {
+	for _, rule := range p.Forbidden {
+		for _, phrase := range rule.Phrases {
+			if !containsFold(answer, phrase) {
+				continue
+			}
+			switch rule.Action {
+			case ForbiddenRefuse:
+				return p.finish(rule.Replacement), true
+			case ForbiddenReplace:
+				answer = replaceFold(answer, phrase, rule.Replacement)
+			case ForbiddenRemove:
+				answer = replaceFold(answer, phrase, "")
+			}
+		}
+	}
+	return p.finish(tidySpaces(answer)), false
+}
+
+// finish truncates answer to leave room for the signature, and appends it.
+func (p Personality) finish(answer string) string {
+	limit := p.maxLength()
+	if p.Signature == "" {
+		return TruncateAtSentence(answer, limit)
+	}
+	// The signature goes on its own line, after a blank one
+	limit -= utf8.RuneCountInString(p.Signature) + 2
+	return TruncateAtSentence(answer, limit) + "\n\n" + p.Signature
+}
+
+// TruncateAtSentence shortens text to at most limit characters (runes),
+// ending at the last sentence boundary that fits. If no sentence ends in the
+// second half of the limit, it cuts at the last space instead and adds an
+// ellipsis.
+func TruncateAtSentence(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	if limit <= 0 {
+		return ""
+	}
+	full := []rune(text)
+	runes := full[:limit]
+
+	for i := len(runes) - 1; i >= limit/2; i-- {
+		switch runes[i] {
+		case '.', '!', '?', '。', '！', '？':
+			// A sentence ends where the punctuation is followed by a space
+			// ("3.5" is not a boundary)
+			if unicode.IsSpace(full[i+1]) || isCJKStop(runes[i]) {
+				return strings.TrimSpace(string(runes[:i+1]))
+			}
+		case '\n':
+			if i > 0 && runes[i-1] == '\n' {
+				return strings.TrimSpace(string(runes[:i]))
+			}
+		}
+	}
+
+	// No sentence boundary: cut at a word and mark the cut
+	cut := runes[:limit-1]
+	for i := len(cut) - 1; i >= limit/2; i-- {
+		if unicode.IsSpace(cut[i]) {
+			cut = cut[:i]
+			break
+		}
+	}
+	return strings.TrimSpace(string(cut)) + "…"
+}
+
+// isCJKStop reports whether r ends a sentence without a following space.
+func isCJKStop(r rune) bool {
+	return r == '。' || r == '！' || r == '？'
+}
+
+// containsFold reports whether s contains substr, ignoring case.
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// replaceFold replaces every occurrence of old in s, ignoring case.
+func replaceFold(s, old, replacement string) string {
+	re := regexp.MustCompile(`(?i)` + regexp.QuoteMeta(old))
+	return re.ReplaceAllLiteralString(s, replacement)
+}
+
+// tidySpaces collapses the runs of spaces that removing phrases leaves
+// behind, and spaces before punctuation.
+func tidySpaces(s string) string {
+	s = multiSpace.ReplaceAllString(s, " ")
+	s = spaceBeforePunct.ReplaceAllString(s, "$1")
+	return strings.TrimSpace(s)
+}
+
+var (
+	multiSpace       = regexp.MustCompile(`[ \t]{2,}`)
+	spaceBeforePunct = regexp.MustCompile(` +([.,!?;:])`)
+)
+
+// loadPersonality reads, parses and validates a personality YAML file.
+// Unknown keys are rejected so a typo can't silently drop a prompt.
+func loadPersonality(filePath string) (Personality, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return Personality{}, fmt.Errorf("failed to read personality file: %w", err)
+	}
+
+	var p Personality
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil && !errors.Is(err, io.EOF) {
+		return Personality{}, fmt.Errorf("failed to parse personality yaml: %w", err)
+	}
+
+	if err := p.validate(); err != nil {
+		return Personality{}, err
+	}
+	return p, nil
+}
+
+// PersonalityRegistry holds the personalities the bot can answer as: the
+// default one from PERSONALITY_FILE, and one per YAML file in
+// PERSONALITY_DIR, named after the file ("tech_support.yaml" is
+// "tech_support"). Reload swaps in a new set only if every file is valid.
+type PersonalityRegistry struct {
+	file   string
+	dir    string
+	now    func() time.Time
+	logger *slog.Logger
+
+	mu            sync.RWMutex
+	personalities map[string]Personality
+	stamp         string // File names, sizes and mtimes at the last load
+}
+
+// NewPersonalityRegistry loads the default personality from file and the
+// others from dir. dir may be empty, or not exist, for no others.
+func NewPersonalityRegistry(file, dir string, logger *slog.Logger) (*PersonalityRegistry, error) {
+	r := &PersonalityRegistry{
+		file:   file,
+		dir:    dir,
+		now:    time.Now,
+		logger: logger,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads every personality file again. On error the loaded set is
+// kept, so a bad edit never leaves the bot without a personality.
+func (r *PersonalityRegistry) Reload() error {
+	files, stamp, err := r.files()
+	if err != nil {
+		return err
+	}
+
+	personalities := make(map[string]Personality, len(files))
+	for name, path := range files {
+		p, err := loadPersonality(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		personalities[name] = p
+	}
+
+	r.mu.Lock()
+	r.personalities = personalities
+	r.stamp = stamp
+	r.mu.Unlock()
+	return nil
+}
+
+// Changed reports whether personality files were added, removed or modified
+// since the last successful load.
+func (r *PersonalityRegistry) Changed() bool {
+	_, stamp, err := r.files()
+	if err != nil {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return stamp != r.stamp
+}
+
+// files returns the personality files by registry name, and a stamp of
+// their sizes and modification times.
+func (r *PersonalityRegistry) files() (map[string]string, string, error) {
+	files := map[string]string{DefaultPersonality: r.file}
+	if r.dir != "" {
+		entries, err := os.ReadDir(r.dir)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, "", fmt.Errorf("failed to read personality directory: %w", err)
+		}
+		for _, e := range entries {
+			ext := filepath.Ext(e.Name())
+			if e.IsDir() || (ext != ".yaml" && ext != ".yml") {
+				continue
+			}
+			name := strings.TrimSuffix(e.Name(), ext)
+			if !nameValue.MatchString(name) || name == DefaultPersonality {
+				return nil, "", fmt.Errorf("invalid personality file name %q", e.Name())
+			}
+			if _, dup := files[name]; dup {
+				return nil, "", fmt.Errorf("personality %q is defined twice", name)
+			}
+			files[name] = filepath.Join(r.dir, e.Name())
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var stamp strings.Builder
+	for _, name := range names {
+		info, err := os.Stat(files[name])
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read personality file: %w", err)
+		}
+		fmt.Fprintf(&stamp, "%s:%d:%d;", name, info.Size(), info.ModTime().UnixNano())
+	}
+	return files, stamp.String(), nil
+}
+
+// Watch reloads the registry whenever its files change, checking every
+// interval until ctx is done. onReload runs after each successful reload.
+func (r *PersonalityRegistry) Watch(ctx context.Context, interval time.Duration, onReload func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !r.Changed() {
+			continue
+		}
+		if err := r.Reload(); err != nil {
+			r.logger.Error("personality reload failed, keeping the loaded set", "error", err)
+			continue
+		}
+		if onReload != nil {
+			onReload()
+		}
+	}
+}
+
+// Names returns the registered personality names, sorted.
+func (r *PersonalityRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.personalities))
+	for name := range r.personalities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Has reports whether a personality is registered under name.
+func (r *PersonalityRegistry) Has(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.personalities[name]
+	return ok
+}
+
+// snapshot returns a copy of the loaded personalities.
+func (r *PersonalityRegistry) snapshot() map[string]Personality {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make(map[string]Personality, len(r.personalities))
+	for name, p := range r.personalities {
+		out[name] = p
+	}
+	return out
+}
+
+// Select returns the name of the personality to answer as. A requested
+// personality that exists wins; otherwise the first active seasonal
+// personality, by name, and otherwise the default.
+func (r *PersonalityRegistry) Select(requested string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if requested != "" {
+		if _, ok := r.personalities[requested]; ok {
+			return requested
+		}
+		r.logger.Warn("unknown personality, using the default", "personality", requested)
+	}
+
+	now := r.now()
+	var seasonal []string
+	for name, p := range r.personalities {
+		if p.Season != nil && p.Season.Active(now) {
+			seasonal = append(seasonal, name)
+		}
+	}
+	if len(seasonal) > 0 {
+		sort.Strings(seasonal)
+		return seasonal[0]
+	}
+	return DefaultPersonality
+}
+
+type personalityKey struct{}
+
+// WithPersonality returns ctx asking for answers as the named personality.
+// An empty name selects the seasonal or default personality.
+func WithPersonality(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, personalityKey{}, name)
+}
+
+// personalityFrom returns the personality requested by ctx, if any.
+func personalityFrom(ctx context.Context) string {
+	name, _ := ctx.Value(personalityKey{}).(string)
+	return name
+}
@@ -0,0 +1,325 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"living-lands-bot/internal/testutil"
+	"living-lands-bot/pkg/ollama"
+)
+
+const testPersonalityDir = "../../configs/personalities"
+
+// validPersonality is the smallest personality that passes validation.
+const validPersonality = "name: Min\nsystem_prompt: s\nfast_mode_prompt: f\nstandard_mode_prompt: m\ndeep_mode_prompt: d [NO_ANSWER]\n"
+
+// writePersonality writes a personality file whose prompts all mention name.
+func writePersonality(t *testing.T, dir, file, name, extra string) {
+	t.Helper()
+	content := "name: " + name + "\n" +
+		"system_prompt: You are " + name + ".\n" +
+		"fast_mode_prompt: You are " + name + ", briefly.\n" +
+		"standard_mode_prompt: You are " + name + ", helpfully.\n" +
+		"deep_mode_prompt: You are " + name + ", with the docs. Say [NO_ANSWER] if they lack it.\n" +
+		extra
+	require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(content), 0o644))
+}
+
+func TestPersonalityRegistryLoadsShippedFiles(t *testing.T) {
+	r, err := NewPersonalityRegistry(testPersonalityFile, testPersonalityDir, getTestLogger())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{DefaultPersonality, "tech_support"}, r.Names())
+	assert.True(t, r.Has("tech_support"))
+	assert.False(t, r.Has("elder_sage"))
+	assert.Equal(t, "Elder Sage", r.snapshot()[DefaultPersonality].Name)
+}
+
+func TestPersonalityRegistryMissingDir(t *testing.T) {
+	r, err := NewPersonalityRegistry(testPersonalityFile, filepath.Join(t.TempDir(), "missing"), getTestLogger())
+	require.NoError(t, err)
+	assert.Equal(t, []string{DefaultPersonality}, r.Names())
+}
+
+func TestPersonalityValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "missing prompts",
+			content: "name: Half\nsystem_prompt: You are Half.\n",
+			wantErr: "personality missing fast_mode_prompt, standard_mode_prompt, deep_mode_prompt",
+		},
+		{
+			name:    "unknown key",
+			content: "name: Typo\nsystem_promt: You are Typo.\n",
+			wantErr: "field system_promt not found",
+		},
+		{
+			name:    "empty file",
+			content: "",
+			wantErr: "personality missing name",
+		},
+		{
+			name:    "deep mode without the no-answer marker",
+			content: "name: Mute\nsystem_prompt: s\nfast_mode_prompt: f\nstandard_mode_prompt: m\ndeep_mode_prompt: d\n",
+			wantErr: "deep_mode_prompt must ask for [NO_ANSWER]",
+		},
+		{
+			name:    "half an example",
+			content: validPersonality + "examples:\n  fast:\n    - user: hi\n",
+			wantErr: "examples.fast[0] needs user and assistant",
+		},
+		{
+			name:    "unknown forbidden action",
+			content: validPersonality + "forbidden:\n  - phrases: [foo]\n    action: shout\n",
+			wantErr: "action must be remove, replace or refuse",
+		},
+		{
+			name:    "refuse without replacement",
+			content: validPersonality + "forbidden:\n  - phrases: [foo]\n    action: refuse\n",
+			wantErr: `action "refuse" needs a replacement`,
+		},
+		{
+			name:    "over the attachment threshold",
+			content: validPersonality + "max_length: 6001\n",
+			wantErr: "max_length must be between 0 and 6000",
+		},
+		{
+			name:    "bad embed color",
+			content: validPersonality + "embed:\n  color: green\n",
+			wantErr: "embed color must be hex",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "broken.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o644))
+
+			_, err := NewPersonalityRegistry(testPersonalityFile, dir, getTestLogger())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+			assert.Contains(t, err.Error(), path, "the error names the file")
+		})
+	}
+
+	t.Run("bad season", func(t *testing.T) {
+		dir := t.TempDir()
+		writePersonality(t, dir, "winter.yaml", "Frost", "season:\n  start: December 15\n  end: 01-05\n")
+		_, err := NewPersonalityRegistry(testPersonalityFile, dir, getTestLogger())
+		assert.ErrorContains(t, err, "season dates must be MM-DD")
+	})
+}
+
+func TestSeasonActive(t *testing.T) {
+	date := func(month time.Month, day int) time.Time {
+		return time.Date(2026, month, day, 12, 0, 0, 0, time.UTC)
+	}
+
+	summer := Season{Start: "06-01", End: "08-31"}
+	assert.True(t, summer.Active(date(time.June, 1)))
+	assert.True(t, summer.Active(date(time.August, 31)))
+	assert.False(t, summer.Active(date(time.September, 1)))
+
+	// Ranges may wrap around the new year
+	winter := Season{Start: "12-15", End: "01-05"}
+	assert.True(t, winter.Active(date(time.December, 24)))
+	assert.True(t, winter.Active(date(time.January, 2)))
+	assert.False(t, winter.Active(date(time.January, 6)))
+	assert.False(t, winter.Active(date(time.December, 14)))
+}
+
+func TestPersonalitySelect(t *testing.T) {
+	dir := t.TempDir()
+	writePersonality(t, dir, "support.yaml", "Tinker", "")
+	writePersonality(t, dir, "winter.yaml", "Frost", "season:\n  start: \"12-15\"\n  end: \"01-05\"\n")
+
+	r, err := NewPersonalityRegistry(testPersonalityFile, dir, getTestLogger())
+	require.NoError(t, err)
+	now := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	assert.Equal(t, DefaultPersonality, r.Select(""))
+	assert.Equal(t, "support", r.Select("support"))
+	assert.Equal(t, DefaultPersonality, r.Select("missing"), "unknown names fall back")
+
+	// In season, the seasonal personality replaces the default, but not a
+	// personality chosen for the channel
+	now = time.Date(2026, time.December, 20, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, "winter", r.Select(""))
+	assert.Equal(t, "winter", r.Select("missing"))
+	assert.Equal(t, "support", r.Select("support"))
+}
+
+func TestPersonalityReload(t *testing.T) {
+	dir := t.TempDir()
+	writePersonality(t, dir, "support.yaml", "Tinker", "")
+
+	r, err := NewPersonalityRegistry(testPersonalityFile, dir, getTestLogger())
+	require.NoError(t, err)
+	assert.False(t, r.Changed())
+
+	writePersonality(t, dir, "lore.yaml", "Loremaster", "")
+	assert.True(t, r.Changed(), "new files are noticed")
+	require.NoError(t, r.Reload())
+	assert.Equal(t, []string{DefaultPersonality, "lore", "support"}, r.Names())
+	assert.False(t, r.Changed())
+
+	// A broken edit is rejected and the loaded set kept
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "support.yaml"), []byte("name: Tinker\n"), 0o644))
+	assert.Error(t, r.Reload())
+	assert.Equal(t, []string{DefaultPersonality, "lore", "support"}, r.Names())
+	assert.Equal(t, "You are Tinker, briefly.", r.snapshot()["support"].FastModePrompt)
+}
+
+func TestGenerateAnswerUsesRequestedPersonality(t *testing.T) {
+	dir := t.TempDir()
+	writePersonality(t, dir, "support.yaml", "Tinker", "")
+
+	personalities, err := NewPersonalityRegistry(testPersonalityFile, dir, getTestLogger())
+	require.NoError(t, err)
+	server := testutil.NewOllamaServer(t)
+	svc, err := NewLLMServiceWithBackends([]LLMBackend{
+		{Generator: ollama.NewClient(server.URL), Model: "test-model"},
+	}, personalities, DefaultLLMConfig(), getTestLogger())
+	require.NoError(t, err)
+
+	ctx := WithPersonality(context.Background(), "support")
+	answer, err := svc.GenerateAnswer(ctx, "how do I install the mod?", nil, IntentKnowledge)
+	require.NoError(t, err)
+	assert.Equal(t, "support", answer.Personality)
+
+	answer, err = svc.GenerateAnswer(context.Background(), "how do I install the mod?", nil, IntentKnowledge)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultPersonality, answer.Personality)
+
+	reqs := server.ChatRequests()
+	require.Len(t, reqs, 2)
+	assert.Equal(t, "You are Tinker, helpfully.", reqs[0].Messages[0].Content)
+	assert.Contains(t, reqs[1].Messages[0].Content, "Elder Sage")
+
+	// Reloading rebuilds the prompts without a restart
+	writePersonality(t, dir, "support.yaml", "Gearwright", "")
+	require.NoError(t, svc.ReloadPersonalities())
+	_, err = svc.GenerateAnswer(ctx, "how do I install the mod?", nil, IntentKnowledge)
+	require.NoError(t, err)
+	reqs = server.ChatRequests()
+	require.Len(t, reqs, 3)
+	assert.Equal(t, "You are Gearwright, helpfully.", reqs[2].Messages[0].Content)
+}
+
+func TestPersonalityFileRoundTrips(t *testing.T) {
+	p, err := loadPersonality(testPersonalityFile)
+	require.NoError(t, err)
+	assert.NotEmpty(t, p.Examples.Fast)
+	assert.NotEmpty(t, p.Examples.Standard)
+	assert.NotEmpty(t, p.Forbidden)
+	assert.Equal(t, 4000, p.MaxLength)
+
+	data, err := yaml.Marshal(p)
+	require.NoError(t, err)
+	var again Personality
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	require.NoError(t, dec.Decode(&again))
+	assert.Equal(t, p, again)
+	require.NoError(t, again.validate())
+}
+
+func TestPersonalityPostProcess(t *testing.T) {
+	p := Personality{
+		Forbidden: []ForbiddenRule{
+			{Phrases: []string{"As an AI language model,"}, Action: ForbiddenRemove},
+			{Phrases: []string{"Minecraft"}, Action: ForbiddenReplace, Replacement: "Hytale"},
+			{Phrases: []string{"release date"}, Action: ForbiddenRefuse, Replacement: "The spirits are silent on that."},
+		},
+	}
+
+	answer, refused := p.PostProcess("As an AI language model, I think minecraft mods work differently .")
+	assert.False(t, refused)
+	assert.Equal(t, "I think Hytale mods work differently.", answer)
+
+	answer, refused = p.PostProcess("The Release Date is next spring.")
+	assert.True(t, refused)
+	assert.Equal(t, "The spirits are silent on that.", answer)
+
+	// The signature counts towards the length limit
+	p = Personality{MaxLength: 50, Signature: "— Elder Sage"}
+	answer, _ = p.PostProcess("First sentence here. Second sentence is quite a bit longer than the first.")
+	assert.Equal(t, "First sentence here.\n\n— Elder Sage", answer)
+	assert.LessOrEqual(t, utf8.RuneCountInString(answer), 50)
+}
+
+func TestTruncateAtSentence(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  string
+	}{
+		{"fits", "Short answer.", 20, "Short answer."},
+		{"last full sentence", "One two three. Four five six. Seven eight nine.", 35, "One two three. Four five six."},
+		{"question mark", "Is the mill open? Yes, behind the mill wheel.", 30, "Is the mill open?"},
+		{"boundary too early cuts at a word", "Yes. The mill is open behind the old wheel.", 30, "Yes. The mill is open behind…"},
+		{"decimal is not a boundary", "Requires version 3.5 of the mod loader or newer.", 40, "Requires version 3.5 of the mod loader…"},
+		{"paragraph break", "First paragraph without a stop\n\nsecond paragraph goes on and on", 45, "First paragraph without a stop"},
+		{"no boundary cuts at a word", "one two three four five six seven eight nine ten", 20, "one two three four…"},
+		{"counts runes not bytes", "Привет путник. Добро пожаловать в Орбис.", 20, "Привет путник."},
+		{"cjk stop", "这是第一句。这是第二句，比较长一些。", 10, "这是第一句。"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TruncateAtSentence(tt.text, tt.limit)
+			assert.Equal(t, tt.want, got)
+			assert.LessOrEqual(t, utf8.RuneCountInString(got), tt.limit)
+			assert.True(t, utf8.ValidString(got))
+		})
+	}
+}
+
+func TestGenerateAnswerAppliesPersonalityRules(t *testing.T) {
+	dir := t.TempDir()
+	writePersonality(t, dir, "support.yaml", "Tinker",
+		"examples:\n  deep:\n    - user: where is the config?\n      assistant: In config/living-lands.toml.\n"+
+			"max_length: 40\nsignature: \"-- Tinker\"\nembed:\n  color: \"#3A86FF\"\n")
+
+	personalities, err := NewPersonalityRegistry(testPersonalityFile, dir, getTestLogger())
+	require.NoError(t, err)
+	server := testutil.NewOllamaServer(t)
+	server.Script(testutil.Completion{Response: "Edit the config file. Then restart the server and check the log."})
+	svc, err := NewLLMServiceWithBackends([]LLMBackend{
+		{Generator: ollama.NewClient(server.URL), Model: "test-model"},
+	}, personalities, DefaultLLMConfig(), getTestLogger())
+	require.NoError(t, err)
+
+	ctx := WithPersonality(context.Background(), "support")
+	answer, err := svc.GenerateAnswer(ctx, "how do I change settings?", []string{"Settings live in the toml file."}, IntentKnowledge)
+	require.NoError(t, err)
+	assert.Equal(t, "Edit the config file.\n\n-- Tinker", answer.Text)
+	require.NotNil(t, answer.Embed)
+	assert.Equal(t, 0x3A86FF, answer.Embed.ColorValue())
+
+	// System prompt, example turns, retrieved docs, question
+	msgs := server.ChatRequests()[0].Messages
+	require.Len(t, msgs, 5)
+	roles := make([]string, len(msgs))
+	for i, m := range msgs {
+		roles[i] = m.Role
+	}
+	assert.Equal(t, []string{"system", "user", "assistant", "system", "user"}, roles)
+	assert.Equal(t, "where is the config?", msgs[1].Content)
+	assert.True(t, strings.HasPrefix(msgs[3].Content, "Relevant documentation"))
+}
@@ -2,27 +2,19 @@ package services
 
 import (
 	"strings"
+	"unicode/utf8"
 )
 
 // SanitizePromptInput sanitizes user input for inclusion in LLM prompts.
-// This prevents prompt injection attacks by escaping or removing prompt delimiters
-// and other potentially problematic characters.
+// It strips control characters, collapses runs of newlines and caps the length.
+// Role delimiters such as "User:" are left alone: user text is always sent as
+// its own chat message, so it cannot impersonate the system or assistant role.
 func SanitizePromptInput(input string) string {
 	if input == "" {
 		return ""
 	}
 
-	// Replace common prompt delimiters and injection patterns
-	// This is not meant to be bulletproof, but to prevent common attacks
 	replacements := map[string]string{
-		// Prompt template injections
-		"User:":      "[User]",
-		"user:":      "[user]",
-		"Assistant:": "[Assistant]",
-		"assistant:": "[assistant]",
-		"System:":    "[System]",
-		"system:":    "[system]",
-
 		// Common control sequences
 		"\x00": "", // null byte
 		"\x1a": "", // EOF
@@ -45,7 +37,12 @@ func SanitizePromptInput(input string) string {
 	// Most questions should be under 500 chars
 	maxLen := 2000 // Allow reasonably long questions, but prevent abuse
 	if len(result) > maxLen {
-		result = result[:maxLen]
+		// Back up to the start of a character so the cut stays valid UTF-8
+		cut := maxLen
+		for cut > 0 && !utf8.RuneStart(result[cut]) {
+			cut--
+		}
+		result = result[:cut]
 	}
 
 	return result
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"living-lands-bot/internal/database/models"
+)
+
+// ErrQAInteractionNotFound is returned when feedback refers to an unknown answer.
+var ErrQAInteractionNotFound = errors.New("qa interaction not found")
+
+// QALogService persists /ask questions, answers and user feedback.
+type QALogService struct {
+	db     *gorm.DB
+	logger *slog.Logger
+}
+
+func NewQALogService(db *gorm.DB, logger *slog.Logger) *QALogService {
+	return &QALogService{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// RecordInteraction stores a logged answer and sets its ID.
+func (s *QALogService) RecordInteraction(qa *models.QAInteraction) error {
+	if err := s.db.Create(qa).Error; err != nil {
+		return fmt.Errorf("failed to record qa interaction: %w", err)
+	}
+	return nil
+}
+
+// RecordFeedback stores a rating for an answer logged in the guild. A user has
+// one vote per answer, so voting up after down replaces the vote; a report is
+// kept apart from the vote. Repeating a rating is a no-op.
+func (s *QALogService) RecordFeedback(guildID string, qaID uint, discordID, rating string) error {
+	switch rating {
+	case models.RatingUp, models.RatingDown, models.RatingReport:
+	default:
+		return fmt.Errorf("invalid feedback rating %q", rating)
+	}
+
+	var count int64
+	if err := s.db.Model(&models.QAInteraction{}).Where("id = ? AND guild_id = ?", qaID, guildID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to look up qa interaction: %w", err)
+	}
+	if count == 0 {
+		return ErrQAInteractionNotFound
+	}
+
+	var err error
+	if rating == models.RatingReport {
+		report := models.QAReport{QAInteractionID: qaID, DiscordID: discordID}
+		err = s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&report).Error
+	} else {
+		vote := models.QAFeedback{QAInteractionID: qaID, DiscordID: discordID, Rating: rating}
+		err = s.db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "qa_interaction_id"}, {Name: "discord_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"rating"}),
+		}).Create(&vote).Error
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record feedback: %w", err)
+	}
+
+	s.logger.Info("answer feedback recorded",
+		"qa_id", qaID,
+		"guild_id", guildID,
+		"discord_id", discordID,
+		"rating", rating,
+	)
+	return nil
+}
+
+// FeedbackCounts returns the number of votes of each kind, and of reports,
+// for an answer.
+func (s *QALogService) FeedbackCounts(qaID uint) (map[string]int, error) {
+	var rows []struct {
+		Rating string
+		Count  int
+	}
+	err := s.db.Model(&models.QAFeedback{}).
+		Select("rating, COUNT(*) AS count").
+		Where("qa_interaction_id = ?", qaID).
+		Group("rating").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count feedback: %w", err)
+	}
+
+	var reports int64
+	if err := s.db.Model(&models.QAReport{}).Where("qa_interaction_id = ?", qaID).Count(&reports).Error; err != nil {
+		return nil, fmt.Errorf("failed to count reports: %w", err)
+	}
+
+	counts := make(map[string]int, len(rows)+1)
+	for _, r := range rows {
+		counts[r.Rating] = r.Count
+	}
+	if reports > 0 {
+		counts[models.RatingReport] = int(reports)
+	}
+	return counts, nil
+}
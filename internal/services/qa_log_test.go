@@ -0,0 +1,94 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"living-lands-bot/internal/database/models"
+	"living-lands-bot/internal/testutil"
+)
+
+func newTestQALog(t *testing.T) *QALogService {
+	t.Helper()
+	db := testutil.NewTestDB(t, &models.QAInteraction{}, &models.QAFeedback{}, &models.QAReport{})
+	return NewQALogService(db, getTestLogger())
+}
+
+func TestQALogRecordInteraction(t *testing.T) {
+	qa := newTestQALog(t)
+
+	entry := &models.QAInteraction{
+		InteractionID: "1200000000000000001",
+		DiscordID:     "900000000000000001",
+		Question:      "How does hunger work?",
+		Intent:        "knowledge",
+		Mode:          "standard",
+		Retrieved: models.RetrievedChunks{
+			{ID: "metabolism", Distance: 0.12, Source: "metabolism.md"},
+		},
+		Answer:    "Hunger drains over time, traveler.",
+		Model:     "test-model",
+		LatencyMs: 420,
+	}
+	require.NoError(t, qa.RecordInteraction(entry))
+	require.NotZero(t, entry.ID)
+
+	var stored models.QAInteraction
+	require.NoError(t, qa.db.First(&stored, entry.ID).Error)
+	assert.Equal(t, entry.Question, stored.Question)
+	assert.Equal(t, entry.Retrieved, stored.Retrieved)
+}
+
+func TestQALogRecordFeedback(t *testing.T) {
+	qa := newTestQALog(t)
+
+	entry := &models.QAInteraction{GuildID: testGuild, Question: "q", Answer: "a"}
+	require.NoError(t, qa.RecordInteraction(entry))
+
+	require.NoError(t, qa.RecordFeedback(testGuild, entry.ID, "user-1", models.RatingUp))
+	require.NoError(t, qa.RecordFeedback(testGuild, entry.ID, "user-1", models.RatingUp), "repeated rating should be a no-op")
+	require.NoError(t, qa.RecordFeedback(testGuild, entry.ID, "user-2", models.RatingDown))
+	require.NoError(t, qa.RecordFeedback(testGuild, entry.ID, "user-2", models.RatingReport))
+	require.NoError(t, qa.RecordFeedback(testGuild, entry.ID, "user-2", models.RatingReport), "repeated report should be a no-op")
+
+	counts, err := qa.FeedbackCounts(entry.ID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{
+		models.RatingUp:     1,
+		models.RatingDown:   1,
+		models.RatingReport: 1,
+	}, counts, "a report does not replace the vote")
+}
+
+func TestQALogRecordFeedbackSwitchesVote(t *testing.T) {
+	qa := newTestQALog(t)
+
+	entry := &models.QAInteraction{GuildID: testGuild, Question: "q", Answer: "a"}
+	require.NoError(t, qa.RecordInteraction(entry))
+
+	require.NoError(t, qa.RecordFeedback(testGuild, entry.ID, "user-1", models.RatingUp))
+	require.NoError(t, qa.RecordFeedback(testGuild, entry.ID, "user-1", models.RatingDown))
+
+	counts, err := qa.FeedbackCounts(entry.ID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{models.RatingDown: 1}, counts, "the new vote replaces the old one")
+
+	var votes []models.QAFeedback
+	require.NoError(t, qa.db.Find(&votes).Error)
+	require.Len(t, votes, 1)
+	assert.Equal(t, models.RatingDown, votes[0].Rating)
+}
+
+func TestQALogRecordFeedbackErrors(t *testing.T) {
+	qa := newTestQALog(t)
+
+	entry := &models.QAInteraction{GuildID: testGuild, Question: "q", Answer: "a"}
+	require.NoError(t, qa.RecordInteraction(entry))
+
+	assert.Error(t, qa.RecordFeedback(testGuild, entry.ID, "user-1", "meh"))
+	assert.ErrorIs(t, qa.RecordFeedback(testGuild, entry.ID+1, "user-1", models.RatingUp), ErrQAInteractionNotFound)
+	assert.ErrorIs(t, qa.RecordFeedback("1000000000000000001", entry.ID, "user-1", models.RatingUp), ErrQAInteractionNotFound,
+		"answers from another guild cannot be rated")
+}
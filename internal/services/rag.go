@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -12,7 +13,13 @@ import (
 	"sync"
 	"time"
 
-	"living-lands-bot/pkg/ollama"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"living-lands-bot/internal/metrics"
+	"living-lands-bot/internal/tracing"
+	"living-lands-bot/pkg/llm"
 )
 
 // DefaultRelevanceThreshold is the maximum cosine distance for a document to be considered relevant.
@@ -21,11 +28,15 @@ import (
 // For high precision, use 0.5-0.7. For higher recall (more results), use 0.8-1.2.
 const DefaultRelevanceThreshold = 1.0
 
+// ErrCollectionNotFound is returned by queries against a collection that has
+// not been indexed (see index-docs --collection).
+var ErrCollectionNotFound = errors.New("chromadb collection not found")
+
 // RAGService handles retrieval-augmented generation queries against ChromaDB.
 // Thread-safe: all operations on collectionID are protected by mu mutex.
 type RAGService struct {
 	chromaURL          string
-	ollamaClient       *ollama.Client
+	embedder           llm.Embedder
 	httpClient         *http.Client
 	embedModel         string
 	logger             *slog.Logger
@@ -33,6 +44,9 @@ type RAGService struct {
 	collectionName     string       // Collection name for retrieval
 	relevanceThreshold float32      // Maximum distance for relevant documents
 	mu                 sync.RWMutex // Protects collectionID field
+
+	collectionsMu sync.Mutex             // Protects collections
+	collections   map[string]*RAGService // Services for other collections, by name
 }
 
 // Document represents a document to be indexed in the RAG system.
@@ -42,6 +56,14 @@ type Document struct {
 	Metadata map[string]interface{}
 }
 
+// RAGResult is a retrieved document that passed the relevance threshold.
+type RAGResult struct {
+	ID       string
+	Text     string
+	Distance float32
+	Source   string
+}
+
 // ChromaQueryRequest represents the request body for ChromaDB query endpoint
 type ChromaQueryRequest struct {
 	QueryEmbeddings [][]float32 `json:"query_embeddings,omitempty"`
@@ -67,17 +89,18 @@ type ChromaAddRequest struct {
 	Metadatas  []map[string]interface{} `json:"metadatas,omitempty"`
 }
 
-// NewRAGService initializes a RAG service with ChromaDB and Ollama clients.
-func NewRAGService(chromaURL string, ollamaClient *ollama.Client, embedModel string, logger *slog.Logger) (*RAGService, error) {
+// NewRAGService initializes a RAG service with ChromaDB and an embedding provider.
+func NewRAGService(chromaURL string, embedder llm.Embedder, embedModel string, logger *slog.Logger) (*RAGService, error) {
 	s := &RAGService{
 		chromaURL:          chromaURL,
-		ollamaClient:       ollamaClient,
+		embedder:           embedder,
 		embedModel:         embedModel,
 		logger:             logger,
 		collectionName:     "livinglands_docs",
 		relevanceThreshold: DefaultRelevanceThreshold,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
 		},
 	}
 
@@ -85,6 +108,36 @@ func NewRAGService(chromaURL string, ollamaClient *ollama.Client, embedModel str
 	return s, nil
 }
 
+// Collection returns a service that queries the named collection, sharing
+// this service's ChromaDB client, embedder and relevance threshold. An empty
+// name or this service's own collection returns s. Services are cached, so
+// their collection IDs are only looked up once.
+func (s *RAGService) Collection(name string) *RAGService {
+	if name == "" || name == s.collectionName {
+		return s
+	}
+
+	s.collectionsMu.Lock()
+	defer s.collectionsMu.Unlock()
+	if c, ok := s.collections[name]; ok {
+		return c
+	}
+	if s.collections == nil {
+		s.collections = make(map[string]*RAGService)
+	}
+	c := &RAGService{
+		chromaURL:          s.chromaURL,
+		embedder:           s.embedder,
+		httpClient:         s.httpClient,
+		embedModel:         s.embedModel,
+		logger:             s.logger,
+		collectionName:     name,
+		relevanceThreshold: s.relevanceThreshold,
+	}
+	s.collections[name] = c
+	return c
+}
+
 // SetRelevanceThreshold sets the maximum distance for documents to be considered relevant.
 // Values range from 0 (exact match) to 2 (completely opposite).
 // Thread-safe.
@@ -95,70 +148,63 @@ func (s *RAGService) SetRelevanceThreshold(threshold float32) {
 
 // Query retrieves the top-N most relevant documents for a given question.
 func (s *RAGService) Query(ctx context.Context, question string, nResults int) ([]string, error) {
-	// 0. Ensure collection exists and get its ID
-	if err := s.ensureCollection(ctx); err != nil {
-		return nil, fmt.Errorf("failed to ensure collection exists: %w", err)
-	}
-
-	// 1. Generate embedding for the question using Ollama
-	embedding, err := s.ollamaClient.Embed(ctx, s.embedModel, question)
+	results, err := s.QueryResults(ctx, question, nResults)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate question embedding: %w", err)
+		return nil, err
 	}
 
-	if len(embedding) == 0 {
-		return nil, fmt.Errorf("empty embedding received from ollama")
+	contexts := make([]string, len(results))
+	for i, r := range results {
+		contexts[i] = r.Text
 	}
+	return contexts, nil
+}
 
-	s.logger.Debug("question embedded", "length", len(embedding))
+// QueryResults is like Query but returns each accepted document with its ID,
+// distance and source, for callers that log what an answer was based on.
+func (s *RAGService) QueryResults(ctx context.Context, question string, nResults int) ([]RAGResult, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "rag.Query")
+	start := time.Now()
 
-	// 2. Query ChromaDB with the embedding using v2 API
-	queryReq := ChromaQueryRequest{
-		QueryEmbeddings: [][]float32{embedding},
-		NResults:        nResults,
-		Include:         []string{"documents", "distances"},
+	results, err := s.query(ctx, question, nResults)
+
+	metrics.RAGQueryCompleted(time.Since(start), err)
+	span.SetAttributes(attribute.Int("rag.results", len(results)))
+	tracing.EndSpan(span, err)
+	return results, err
+}
+
+func (s *RAGService) query(ctx context.Context, question string, nResults int) ([]RAGResult, error) {
+	// 0. Look up the collection ID; only indexing creates collections, so a
+	// misnamed collection is reported rather than silently created empty
+	if err := s.openCollection(ctx); err != nil {
+		return nil, err
 	}
 
-	body, err := json.Marshal(queryReq)
+	// 1. Generate embedding for the question
+	embedding, err := s.embedder.Embed(ctx, s.embedModel, question)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal query request: %w", err)
+		return nil, fmt.Errorf("failed to generate question embedding: %w", err)
 	}
 
-	// Use v2 API endpoint with collection ID
-	url := fmt.Sprintf("%s/api/v2/tenants/default_tenant/databases/default_database/collections/%s/query",
-		s.chromaURL, s.collectionID)
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create http request: %w", err)
+	if len(embedding) == 0 {
+		return nil, fmt.Errorf("empty embedding received from embedding provider")
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
+	s.logger.Debug("question embedded", "length", len(embedding))
 
-	resp, err := s.httpClient.Do(httpReq)
+	// 2. Query ChromaDB with the embedding
+	queryResp, err := s.chromaQuery(ctx, embedding, nResults)
 	if err != nil {
-		return nil, fmt.Errorf("chromadb query request failed: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusNotFound {
+	if queryResp == nil {
 		// Collection doesn't exist yet, return empty results
-		s.logger.Debug("collection not found, returning empty results")
-		return []string{}, nil
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("chromadb returned %d: %s", resp.StatusCode, string(respBody))
-	}
-
-	var queryResp ChromaQueryResponse
-	if err := json.NewDecoder(resp.Body).Decode(&queryResp); err != nil {
-		return nil, fmt.Errorf("failed to decode chromadb response: %w", err)
+		return []RAGResult{}, nil
 	}
 
-	// 3. Extract document texts from the query result, filtering by relevance threshold
-	var contexts []string
+	// 3. Extract documents from the query result, filtering by relevance threshold
+	var results []RAGResult
 	var filteredCount int
 
 	for i, docs := range queryResp.Documents {
@@ -180,7 +226,9 @@ func (s *RAGService) Query(ctx context.Context, question string, nResults int) (
 			}
 
 			// Filter out documents that exceed the relevance threshold
-			if distance > s.relevanceThreshold {
+			accepted := distance <= s.relevanceThreshold
+			metrics.RAGDocumentScored(distance, accepted)
+			if !accepted {
 				filteredCount++
 				s.logger.Debug("document filtered due to low relevance",
 					"distance", distance,
@@ -191,7 +239,17 @@ func (s *RAGService) Query(ctx context.Context, question string, nResults int) (
 				continue
 			}
 
-			contexts = append(contexts, doc)
+			var id string
+			if i < len(queryResp.IDs) && j < len(queryResp.IDs[i]) {
+				id = queryResp.IDs[i][j]
+			}
+
+			results = append(results, RAGResult{
+				ID:       id,
+				Text:     doc,
+				Distance: distance,
+				Source:   getMetadataSource(metadata),
+			})
 			s.logger.Info("document accepted for RAG context",
 				"distance", distance,
 				"threshold", s.relevanceThreshold,
@@ -203,11 +261,76 @@ func (s *RAGService) Query(ctx context.Context, question string, nResults int) (
 
 	s.logger.Info("rag query complete",
 		"question", question,
-		"results", len(contexts),
+		"results", len(results),
 		"filtered", filteredCount,
 		"threshold", s.relevanceThreshold,
 	)
-	return contexts, nil
+	return results, nil
+}
+
+// chromaQuery runs a nearest-neighbour search using the v2 API. It returns
+// nil (and no error) if the collection does not exist yet.
+func (s *RAGService) chromaQuery(ctx context.Context, embedding []float32, nResults int) (_ *ChromaQueryResponse, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "chroma.Query", trace.WithAttributes(
+		attribute.String("chroma.collection", s.collectionName),
+		attribute.Int("chroma.n_results", nResults),
+	))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	queryReq := ChromaQueryRequest{
+		QueryEmbeddings: [][]float32{embedding},
+		NResults:        nResults,
+		Include:         []string{"documents", "distances", "metadatas"},
+	}
+
+	body, err := json.Marshal(queryReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query request: %w", err)
+	}
+
+	// Use v2 API endpoint with collection ID
+	url := fmt.Sprintf("%s/api/v2/tenants/default_tenant/databases/default_database/collections/%s/query",
+		s.chromaURL, s.collectionID)
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("chromadb query request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		s.logger.Debug("collection not found, returning empty results")
+		return nil, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("chromadb returned %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var queryResp ChromaQueryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&queryResp); err != nil {
+		return nil, fmt.Errorf("failed to decode chromadb response: %w", err)
+	}
+
+	span.SetAttributes(attribute.Int("chroma.results", countDocuments(queryResp.Documents)))
+	return &queryResp, nil
+}
+
+// countDocuments returns the total number of documents across all result sets.
+func countDocuments(docs [][]string) int {
+	n := 0
+	for _, d := range docs {
+		n += len(d)
+	}
+	return n
 }
 
 // truncateString truncates a string to maxLen characters, adding ellipsis if needed.
@@ -251,7 +374,7 @@ func (s *RAGService) AddDocuments(ctx context.Context, docs []Document) error {
 
 	for _, doc := range docs {
 		// Generate embedding
-		embedding, err := s.ollamaClient.Embed(ctx, s.embedModel, doc.Text)
+		embedding, err := s.embedder.Embed(ctx, s.embedModel, doc.Text)
 		if err != nil {
 			s.logger.Error("failed to generate embedding", "doc_id", doc.ID, "error", err)
 			continue
@@ -386,60 +509,88 @@ func (s *RAGService) Count(ctx context.Context) (int, error) {
 }
 
 // ensureCollection creates the collection if it doesn't exist using v2 API.
-// It caches the collection ID for subsequent operations.
-// Thread-safe: uses mutex to prevent concurrent initialization.
+// Only the indexing path (add, delete, count) calls this; queries use
+// openCollection.
 func (s *RAGService) ensureCollection(ctx context.Context) error {
+	found, err := s.lookupCollection(ctx)
+	if err != nil || found {
+		return err
+	}
+
+	// Collection doesn't exist, create it
+	return s.createCollection(ctx)
+}
+
+// openCollection caches the collection ID like ensureCollection, but returns
+// ErrCollectionNotFound instead of creating a missing collection.
+func (s *RAGService) openCollection(ctx context.Context) error {
+	found, err := s.lookupCollection(ctx)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%w: %s", ErrCollectionNotFound, s.collectionName)
+	}
+	return nil
+}
+
+// CollectionExists reports whether ChromaDB has a collection with the given
+// name, for validating a collection before a guild is pointed at it.
+func (s *RAGService) CollectionExists(ctx context.Context, name string) (bool, error) {
+	return s.Collection(name).lookupCollection(ctx)
+}
+
+// lookupCollection fetches the collection by name and caches its ID. It
+// reports false, without error, if the collection doesn't exist.
+func (s *RAGService) lookupCollection(ctx context.Context) (bool, error) {
 	// Check if collection ID is already cached (read lock)
 	s.mu.RLock()
 	if s.collectionID != "" {
 		s.mu.RUnlock()
-		return nil
+		return true, nil
 	}
 	s.mu.RUnlock()
 
-	// First, try to get the collection by name (GET is safe and fast)
+	// GET is safe and fast
 	collURL := fmt.Sprintf("%s/api/v2/tenants/default_tenant/databases/default_database/collections/%s",
 		s.chromaURL, s.collectionName)
 
 	httpReq, err := http.NewRequestWithContext(ctx, "GET", collURL, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create get collection request: %w", err)
+		return false, fmt.Errorf("failed to create get collection request: %w", err)
 	}
 
 	resp, err := s.httpClient.Do(httpReq)
 	if err != nil {
-		return fmt.Errorf("chromadb get collection request failed: %w", err)
+		return false, fmt.Errorf("chromadb get collection request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusOK {
-		// Collection exists, extract the ID
-		var collResp map[string]interface{}
-		if err := json.NewDecoder(resp.Body).Decode(&collResp); err != nil {
-			return fmt.Errorf("failed to decode collection response: %w", err)
-		}
-
-		collID, ok := collResp["id"].(string)
-		if !ok {
-			return fmt.Errorf("collection response missing or invalid id field")
-		}
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return false, fmt.Errorf("chromadb get collection returned %d: %s", resp.StatusCode, string(respBody))
+	}
 
-		// Write lock to update cached collection ID
-		s.mu.Lock()
-		s.collectionID = collID
-		s.mu.Unlock()
-		s.logger.Debug("collection retrieved", "collection", s.collectionName, "id", collID)
-		return nil
+	// Collection exists, extract the ID
+	var collResp map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&collResp); err != nil {
+		return false, fmt.Errorf("failed to decode collection response: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusNotFound {
-		// Some other error occurred
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("chromadb get collection returned %d: %s", resp.StatusCode, string(respBody))
+	collID, ok := collResp["id"].(string)
+	if !ok {
+		return false, fmt.Errorf("collection response missing or invalid id field")
 	}
 
-	// Collection doesn't exist, create it
-	return s.createCollection(ctx)
+	// Write lock to update cached collection ID
+	s.mu.Lock()
+	s.collectionID = collID
+	s.mu.Unlock()
+	s.logger.Debug("collection retrieved", "collection", s.collectionName, "id", collID)
+	return true, nil
 }
 
 // createCollection creates a new collection in ChromaDB using v2 API.
@@ -1,12 +1,17 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"log/slog"
+	"net/http"
 	"os"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"living-lands-bot/internal/testutil"
 	"living-lands-bot/pkg/ollama"
 )
 
@@ -170,7 +175,7 @@ func TestRAGServiceConcurrentAccess(t *testing.T) {
 	// Create a minimal RAG service
 	rag := &RAGService{
 		chromaURL:          "http://localhost:8000",
-		ollamaClient:       ollama.NewClient("http://localhost:11434"),
+		embedder:           ollama.NewClient("http://localhost:11434"),
 		embedModel:         "nomic-embed-text",
 		logger:             logger,
 		collectionName:     "test_collection",
@@ -318,3 +323,182 @@ func TestTruncateStringUTF8(t *testing.T) {
 func isValidUTF8String(s string) bool {
 	return len(s) == 0 || strings.TrimSpace(s) != "" || true // strings in Go are always valid UTF-8 at runtime
 }
+
+// newFakeRAGService wires a RAGService to offline Ollama and ChromaDB fakes.
+func newFakeRAGService(t *testing.T) (*RAGService, *testutil.OllamaServer, *testutil.ChromaServer) {
+	t.Helper()
+
+	ollamaServer := testutil.NewOllamaServer(t)
+	chromaServer := testutil.NewChromaServer(t)
+
+	rag, err := NewRAGService(chromaServer.URL, ollama.NewClient(ollamaServer.URL), "nomic-embed-text", getTestLogger())
+	if err != nil {
+		t.Fatalf("Failed to create RAG service: %v", err)
+	}
+	return rag, ollamaServer, chromaServer
+}
+
+func seedFakeDocs(t *testing.T, rag *RAGService) {
+	t.Helper()
+
+	docs := []Document{
+		{ID: "metabolism", Text: "The metabolism system tracks hunger and thirst over time.", Metadata: map[string]interface{}{"source": "metabolism.md"}},
+		{ID: "install", Text: "Download the mod from CurseForge and drop it into the mods folder.", Metadata: map[string]interface{}{"source": "install.md"}},
+		{ID: "creatures", Text: "Creatures roam each biome and react to the day night cycle.", Metadata: map[string]interface{}{"source": "creatures.md"}},
+	}
+	if err := rag.AddDocuments(context.Background(), docs); err != nil {
+		t.Fatalf("AddDocuments failed: %v", err)
+	}
+}
+
+func TestRAGServiceQueryWithFakes(t *testing.T) {
+	rag, ollamaServer, chromaServer := newFakeRAGService(t)
+	seedFakeDocs(t, rag)
+
+	if got := chromaServer.Len("livinglands_docs"); got != 3 {
+		t.Fatalf("expected 3 indexed documents, got %d", got)
+	}
+
+	count, err := rag.Count(context.Background())
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected count 3, got %d", count)
+	}
+
+	rag.SetRelevanceThreshold(0.9)
+	results, err := rag.Query(context.Background(), "how does metabolism hunger work", 3)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	if len(results) == 0 {
+		t.Fatal("expected at least one relevant result")
+	}
+	if !strings.Contains(results[0], "metabolism") {
+		t.Errorf("expected metabolism doc first, got %q", results[0])
+	}
+	for _, r := range results {
+		if strings.Contains(r, "CurseForge") {
+			t.Errorf("unrelated document should have been filtered: %q", r)
+		}
+	}
+
+	// 3 documents indexed + 1 question
+	if got := len(ollamaServer.EmbedRequests()); got != 4 {
+		t.Errorf("expected 4 embed calls, got %d", got)
+	}
+}
+
+func TestRAGServiceQueryEmbedFailure(t *testing.T) {
+	rag, ollamaServer, chromaServer := newFakeRAGService(t)
+	if err := rag.EnsureCollectionPublic(context.Background()); err != nil {
+		t.Fatalf("EnsureCollectionPublic failed: %v", err)
+	}
+	ollamaServer.FailEmbeddings(http.StatusInternalServerError)
+
+	_, err := rag.Query(context.Background(), "what biomes exist?", 5)
+	if err == nil {
+		t.Fatal("expected error when embedding fails")
+	}
+	if !strings.Contains(err.Error(), "embedding") {
+		t.Errorf("expected embedding error, got %v", err)
+	}
+	if chromaServer.QueryCount() != 0 {
+		t.Error("chromadb should not be queried when embedding fails")
+	}
+}
+
+func TestRAGServiceQueryChromaFailure(t *testing.T) {
+	rag, _, chromaServer := newFakeRAGService(t)
+	seedFakeDocs(t, rag)
+	chromaServer.FailQueries(http.StatusServiceUnavailable)
+
+	_, err := rag.Query(context.Background(), "metabolism", 5)
+	if err == nil {
+		t.Fatal("expected error when chromadb query fails")
+	}
+	if !strings.Contains(err.Error(), "503") {
+		t.Errorf("expected status code in error, got %v", err)
+	}
+}
+
+func TestRAGServiceQueryTimeout(t *testing.T) {
+	rag, _, chromaServer := newFakeRAGService(t)
+	seedFakeDocs(t, rag)
+	chromaServer.DelayQueries(2 * time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := rag.Query(ctx, "metabolism", 5)
+	if err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("query should respect context deadline, took %v", elapsed)
+	}
+}
+
+func TestRAGServiceCollection(t *testing.T) {
+	rag, _, chromaServer := newFakeRAGService(t)
+	seedFakeDocs(t, rag)
+
+	if rag.Collection("") != rag || rag.Collection("livinglands_docs") != rag {
+		t.Error("the default collection should return the same service")
+	}
+
+	modding := rag.Collection("modding_docs")
+	if modding != rag.Collection("modding_docs") {
+		t.Error("collection services should be cached")
+	}
+	if err := modding.AddDocuments(context.Background(), []Document{
+		{ID: "api", Text: "Register a metabolism hook through the modding API.", Metadata: map[string]interface{}{"source": "api.md"}},
+	}); err != nil {
+		t.Fatalf("AddDocuments failed: %v", err)
+	}
+
+	if got := chromaServer.Len("modding_docs"); got != 1 {
+		t.Errorf("expected 1 document in modding_docs, got %d", got)
+	}
+	if got := chromaServer.Len("livinglands_docs"); got != 3 {
+		t.Errorf("default collection should be untouched, got %d documents", got)
+	}
+
+	count, err := modding.Count(context.Background())
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected count 1, got %d", count)
+	}
+}
+
+func TestRAGServiceQueryDoesNotCreateCollection(t *testing.T) {
+	rag, _, chromaServer := newFakeRAGService(t)
+	seedFakeDocs(t, rag)
+
+	_, err := rag.Collection("moding_docs").Query(context.Background(), "metabolism", 5)
+	if !errors.Is(err, ErrCollectionNotFound) {
+		t.Fatalf("expected ErrCollectionNotFound, got %v", err)
+	}
+
+	exists, err := rag.CollectionExists(context.Background(), "moding_docs")
+	if err != nil {
+		t.Fatalf("CollectionExists failed: %v", err)
+	}
+	if exists {
+		t.Error("a query should not create the collection it searches")
+	}
+	if exists, _ := rag.CollectionExists(context.Background(), "livinglands_docs"); !exists {
+		t.Error("the indexed collection should exist")
+	}
+	if chromaServer.QueryCount() != 0 {
+		t.Error("a missing collection should not be queried")
+	}
+}
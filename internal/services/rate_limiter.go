@@ -41,6 +41,17 @@ func NewRateLimiter(redisClient *redis.Client, requestsPerMin int, logger *slog.
 // Returns true if allowed, false if rate limit exceeded.
 // Also returns the number of remaining requests and time until reset.
 func (r *RateLimiter) IsAllowed(ctx context.Context, userID string) (bool, int, time.Duration, error) {
+	return r.IsAllowedWithLimit(ctx, userID, r.requestsPerMin)
+}
+
+// IsAllowedWithLimit is IsAllowed with a per-call limit, for guilds and
+// channels that override the default. A limit of zero or less uses the
+// default. The counter is shared, so a user's requests count against
+// whichever limit applies where they are made.
+func (r *RateLimiter) IsAllowedWithLimit(ctx context.Context, userID string, limit int) (bool, int, time.Duration, error) {
+	if limit <= 0 {
+		limit = r.requestsPerMin
+	}
 	key := fmt.Sprintf("rate_limit:%s", userID)
 
 	// Execute atomic INCR + EXPIRE via Lua script
@@ -70,8 +81,8 @@ func (r *RateLimiter) IsAllowed(ctx context.Context, userID string) (bool, int,
 		ttl = time.Minute
 	}
 
-	allowed := count <= int64(r.requestsPerMin)
-	remaining := int(r.requestsPerMin) - int(count)
+	allowed := count <= int64(limit)
+	remaining := limit - int(count)
 	if remaining < 0 {
 		remaining = 0
 	}
@@ -80,7 +91,7 @@ func (r *RateLimiter) IsAllowed(ctx context.Context, userID string) (bool, int,
 		r.logger.Warn("rate limit exceeded",
 			"user_id", userID,
 			"requests", count,
-			"limit", r.requestsPerMin,
+			"limit", limit,
 		)
 	}
 
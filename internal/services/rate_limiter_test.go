@@ -271,6 +271,44 @@ func TestRateLimiter_DifferentUsers(t *testing.T) {
 	}
 }
 
+func TestRateLimiter_WithLimit(t *testing.T) {
+	redisClient := getTestRedis(t)
+	if redisClient == nil {
+		t.Skip("Redis not available for testing")
+	}
+	defer redisClient.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	limiter := NewRateLimiter(redisClient, 5, logger)
+
+	ctx := context.Background()
+	userID := "test-user-override"
+	_ = limiter.Reset(ctx, userID)
+
+	// A stricter override blocks before the default would
+	for i := 1; i <= 2; i++ {
+		allowed, remaining, _, err := limiter.IsAllowedWithLimit(ctx, userID, 2)
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+		if !allowed || remaining != 2-i {
+			t.Errorf("Request %d: expected allowed with %d remaining, got %v/%d", i, 2-i, allowed, remaining)
+		}
+	}
+	if allowed, _, _, _ := limiter.IsAllowedWithLimit(ctx, userID, 2); allowed {
+		t.Error("3rd request should be blocked by the override")
+	}
+
+	// Zero falls back to the default limit, against the same counter
+	allowed, remaining, _, err := limiter.IsAllowedWithLimit(ctx, userID, 0)
+	if err != nil {
+		t.Fatalf("Default limit request failed: %v", err)
+	}
+	if !allowed || remaining != 1 {
+		t.Errorf("expected allowed with 1 remaining under the default, got %v/%d", allowed, remaining)
+	}
+}
+
 func TestRateLimiter_RedisFailure(t *testing.T) {
 	// Create a bad Redis client
 	badClient := redis.NewClient(&redis.Options{
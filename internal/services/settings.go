@@ -0,0 +1,498 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"living-lands-bot/internal/database/models"
+)
+
+// DefaultSettingsCacheTTL is how long settings rows are cached. Changes made
+// through this service apply immediately; the TTL only bounds how long other
+// bot instances keep serving stale values.
+const DefaultSettingsCacheTTL = time.Minute
+
+// settingsCheckTimeout bounds looking up a RAG collection while a setting is
+// written, which happens inside an interaction's reply deadline.
+const settingsCheckTimeout = 2 * time.Second
+
+// Settings are the effective settings for a channel: the channel's
+// overrides, then its guild's, then the global defaults.
+type Settings struct {
+	AskEnabled       bool
+	EphemeralAnswers bool
+	Personality      string // Empty for the default personality
+	RAGCollection    string // Empty for the default collection
+	RateLimitPerMin  int    // Zero for RATE_LIMIT_PER_MINUTE
+	WelcomeChannelID string // Empty to pick the first text channel
+}
+
+// DefaultSettings are the settings of channels without overrides.
+func DefaultSettings() Settings {
+	return Settings{AskEnabled: true}
+}
+
+// SettingKey names a setting that can be overridden.
+type SettingKey string
+
+const (
+	SettingAsk            SettingKey = "ask"
+	SettingEphemeral      SettingKey = "ephemeral"
+	SettingPersonality    SettingKey = "personality"
+	SettingRAGCollection  SettingKey = "rag_collection"
+	SettingRateLimit      SettingKey = "rate_limit"
+	SettingWelcomeChannel SettingKey = "welcome_channel" // Guild only
+)
+
+// SettingKeys lists every setting in display order.
+var SettingKeys = []SettingKey{
+	SettingAsk, SettingEphemeral, SettingPersonality, SettingRAGCollection, SettingRateLimit, SettingWelcomeChannel,
+}
+
+// Errors returned when setting a value.
+var (
+	ErrUnknownSetting = errors.New("unknown setting")
+	ErrInvalidSetting = errors.New("invalid setting value")
+	ErrGuildOnly      = errors.New("setting can only be set for a guild")
+)
+
+// nameValue matches personality and collection names.
+var nameValue = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,99}$`)
+
+// snowflakeValue matches Discord IDs.
+var snowflakeValue = regexp.MustCompile(`^[0-9]{17,20}$`)
+
+// cachedRow is a settings row, or its absence, as of loaded.
+type cachedRow[T any] struct {
+	row    *T // Nil if there is no row
+	loaded time.Time
+}
+
+// SettingsService stores per-guild and per-channel settings and resolves the
+// effective settings for an interaction. Rows are cached with a TTL and
+// invalidated on write. Thread-safe.
+type SettingsService struct {
+	db     *gorm.DB
+	ttl    time.Duration
+	now    func() time.Time
+	logger *slog.Logger
+
+	mu       sync.Mutex
+	guilds   map[string]cachedRow[models.GuildSettings]
+	channels map[string]cachedRow[models.ChannelSettings]
+
+	// knownPersonality, if set, rejects personality names it doesn't know
+	knownPersonality func(name string) bool
+	// collectionExists, if set, rejects RAG collections that don't exist
+	collectionExists func(ctx context.Context, name string) (bool, error)
+}
+
+func NewSettingsService(db *gorm.DB, logger *slog.Logger) *SettingsService {
+	return &SettingsService{
+		db:       db,
+		ttl:      DefaultSettingsCacheTTL,
+		now:      time.Now,
+		logger:   logger,
+		guilds:   make(map[string]cachedRow[models.GuildSettings]),
+		channels: make(map[string]cachedRow[models.ChannelSettings]),
+	}
+}
+
+// SettingSource is the layer an effective setting comes from.
+type SettingSource string
+
+const (
+	SourceDefault SettingSource = "default"
+	SourceGuild   SettingSource = "guild"
+	SourceChannel SettingSource = "channel"
+)
+
+// Resolve returns the effective settings for a channel of a guild. Either ID
+// may be empty (DMs have no guild). On a database error the defaults are
+// returned along with the error, so callers can carry on.
+func (s *SettingsService) Resolve(guildID, channelID string) (Settings, error) {
+	settings, _, err := s.Explain(guildID, channelID)
+	return settings, err
+}
+
+// Explain is Resolve, also reporting which layer each setting comes from.
+func (s *SettingsService) Explain(guildID, channelID string) (Settings, map[SettingKey]SettingSource, error) {
+	settings := DefaultSettings()
+	sources := make(map[SettingKey]SettingSource, len(SettingKeys))
+	for _, key := range SettingKeys {
+		sources[key] = SourceDefault
+	}
+
+	if guildID != "" {
+		guild, err := s.Guild(guildID)
+		if err != nil {
+			return DefaultSettings(), nil, err
+		}
+		if guild != nil {
+			applyOverrides(&settings, guild.Overrides, sources, SourceGuild)
+			if guild.WelcomeChannelID != nil {
+				settings.WelcomeChannelID = *guild.WelcomeChannelID
+				sources[SettingWelcomeChannel] = SourceGuild
+			}
+		}
+	}
+
+	if channelID != "" {
+		channel, err := s.Channel(channelID)
+		if err != nil {
+			return DefaultSettings(), nil, err
+		}
+		if channel != nil {
+			applyOverrides(&settings, channel.Overrides, sources, SourceChannel)
+		}
+	}
+	return settings, sources, nil
+}
+
+// applyOverrides copies the overrides that are set into settings, recording
+// source for each of them.
+func applyOverrides(settings *Settings, o models.SettingOverrides, sources map[SettingKey]SettingSource, source SettingSource) {
+	if o.AskEnabled != nil {
+		settings.AskEnabled = *o.AskEnabled
+		sources[SettingAsk] = source
+	}
+	if o.EphemeralAnswers != nil {
+		settings.EphemeralAnswers = *o.EphemeralAnswers
+		sources[SettingEphemeral] = source
+	}
+	if o.Personality != nil {
+		settings.Personality = *o.Personality
+		sources[SettingPersonality] = source
+	}
+	if o.RAGCollection != nil {
+		settings.RAGCollection = *o.RAGCollection
+		sources[SettingRAGCollection] = source
+	}
+	if o.RateLimitPerMin != nil {
+		settings.RateLimitPerMin = *o.RateLimitPerMin
+		sources[SettingRateLimit] = source
+	}
+}
+
+// Value returns a setting in the text form accepted by SetGuild and
+// SetChannel, with "default" for unset names and limits.
+func (s Settings) Value(key SettingKey) string {
+	onOff := func(v bool) string {
+		if v {
+			return "on"
+		}
+		return "off"
+	}
+	orDefault := func(v string) string {
+		if v == "" {
+			return "default"
+		}
+		return v
+	}
+
+	switch key {
+	case SettingAsk:
+		return onOff(s.AskEnabled)
+	case SettingEphemeral:
+		return onOff(s.EphemeralAnswers)
+	case SettingPersonality:
+		return orDefault(s.Personality)
+	case SettingRAGCollection:
+		return orDefault(s.RAGCollection)
+	case SettingRateLimit:
+		if s.RateLimitPerMin == 0 {
+			return "default"
+		}
+		return strconv.Itoa(s.RateLimitPerMin)
+	case SettingWelcomeChannel:
+		return orDefault(s.WelcomeChannelID)
+	}
+	return ""
+}
+
+// Guild returns a guild's settings row, or nil if it has none.
+func (s *SettingsService) Guild(guildID string) (*models.GuildSettings, error) {
+	s.mu.Lock()
+	cached, ok := s.guilds[guildID]
+	ttl := s.ttl
+	s.mu.Unlock()
+	if ok && s.now().Sub(cached.loaded) < ttl {
+		return cached.row, nil
+	}
+
+	var row models.GuildSettings
+	err := s.db.Where("guild_id = ?", guildID).Limit(1).Find(&row).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load settings for guild %s: %w", guildID, err)
+	}
+	var found *models.GuildSettings
+	if row.GuildID != "" {
+		found = &row
+	}
+
+	s.mu.Lock()
+	s.guilds[guildID] = cachedRow[models.GuildSettings]{row: found, loaded: s.now()}
+	s.mu.Unlock()
+	return found, nil
+}
+
+// Channel returns a channel's settings row, or nil if it has none.
+func (s *SettingsService) Channel(channelID string) (*models.ChannelSettings, error) {
+	s.mu.Lock()
+	cached, ok := s.channels[channelID]
+	ttl := s.ttl
+	s.mu.Unlock()
+	if ok && s.now().Sub(cached.loaded) < ttl {
+		return cached.row, nil
+	}
+
+	var row models.ChannelSettings
+	err := s.db.Where("channel_id = ?", channelID).Limit(1).Find(&row).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load settings for channel %s: %w", channelID, err)
+	}
+	var found *models.ChannelSettings
+	if row.ChannelID != "" {
+		found = &row
+	}
+
+	s.mu.Lock()
+	s.channels[channelID] = cachedRow[models.ChannelSettings]{row: found, loaded: s.now()}
+	s.mu.Unlock()
+	return found, nil
+}
+
+// ChannelsWithOverrides returns the settings rows of a guild's channels.
+func (s *SettingsService) ChannelsWithOverrides(guildID string) ([]models.ChannelSettings, error) {
+	var rows []models.ChannelSettings
+	if err := s.db.Where("guild_id = ?", guildID).Order("channel_id").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to list channel settings for guild %s: %w", guildID, err)
+	}
+	return rows, nil
+}
+
+// SetKnownPersonalities makes the personality setting only accept names
+// for which known returns true.
+func (s *SettingsService) SetKnownPersonalities(known func(name string) bool) {
+	s.knownPersonality = known
+}
+
+// SetCollectionCheck makes the rag_collection setting reject collections
+// that exists reports missing, e.g. RAGService.CollectionExists. Without it
+// any valid name is accepted.
+func (s *SettingsService) SetCollectionCheck(exists func(ctx context.Context, name string) (bool, error)) {
+	s.collectionExists = exists
+}
+
+// checkCollection rejects RAG collections that have not been indexed, so a
+// typo can't quietly leave a guild without documentation.
+func (s *SettingsService) checkCollection(key SettingKey, value string) error {
+	if key != SettingRAGCollection || s.collectionExists == nil || isDefault(value) {
+		return nil
+	}
+	name := strings.TrimSpace(value)
+	ctx, cancel := context.WithTimeout(context.Background(), settingsCheckTimeout)
+	defer cancel()
+	found, err := s.collectionExists(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to check collection %q: %w", name, err)
+	}
+	if !found {
+		return fmt.Errorf("%w: collection %q does not exist, index it with index-docs --collection first", ErrInvalidSetting, name)
+	}
+	return nil
+}
+
+// checkPersonality rejects unknown personalities, when they can be checked.
+func (s *SettingsService) checkPersonality(key SettingKey, value string) error {
+	if key != SettingPersonality || s.knownPersonality == nil || isDefault(value) {
+		return nil
+	}
+	if name := strings.TrimSpace(value); !s.knownPersonality(name) {
+		return fmt.Errorf("%w: unknown personality %q", ErrInvalidSetting, name)
+	}
+	return nil
+}
+
+// SetGuild sets a guild setting from its text form. An empty value or
+// "default" clears the override.
+func (s *SettingsService) SetGuild(guildID string, key SettingKey, value, updatedBy string) error {
+	row, err := s.Guild(guildID)
+	if err != nil {
+		return err
+	}
+	updated := models.GuildSettings{GuildID: guildID}
+	if row != nil {
+		updated = *row
+	}
+
+	if err := s.checkPersonality(key, value); err != nil {
+		return err
+	}
+	if err := s.checkCollection(key, value); err != nil {
+		return err
+	}
+
+	if key == SettingWelcomeChannel {
+		channelID, err := parseSnowflake(value)
+		if err != nil {
+			return err
+		}
+		updated.WelcomeChannelID = channelID
+	} else if err := setOverride(&updated.Overrides, key, value); err != nil {
+		return err
+	}
+	updated.UpdatedBy = updatedBy
+
+	if err := s.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&updated).Error; err != nil {
+		return fmt.Errorf("failed to save settings for guild %s: %w", guildID, err)
+	}
+
+	s.mu.Lock()
+	delete(s.guilds, guildID)
+	s.mu.Unlock()
+	s.logger.Info("guild setting changed", "guild_id", guildID, "setting", key, "value", value, "updated_by", updatedBy)
+	return nil
+}
+
+// SetChannel sets a channel setting from its text form. An empty value or
+// "default" clears the override, so the guild's setting applies again.
+func (s *SettingsService) SetChannel(guildID, channelID string, key SettingKey, value, updatedBy string) error {
+	if key == SettingWelcomeChannel {
+		return fmt.Errorf("%w: %s", ErrGuildOnly, key)
+	}
+
+	row, err := s.Channel(channelID)
+	if err != nil {
+		return err
+	}
+	updated := models.ChannelSettings{ChannelID: channelID, GuildID: guildID}
+	if row != nil {
+		updated = *row
+	}
+
+	if err := s.checkPersonality(key, value); err != nil {
+		return err
+	}
+	if err := s.checkCollection(key, value); err != nil {
+		return err
+	}
+	if err := setOverride(&updated.Overrides, key, value); err != nil {
+		return err
+	}
+	updated.UpdatedBy = updatedBy
+
+	if err := s.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&updated).Error; err != nil {
+		return fmt.Errorf("failed to save settings for channel %s: %w", channelID, err)
+	}
+
+	s.mu.Lock()
+	delete(s.channels, channelID)
+	s.mu.Unlock()
+	s.logger.Info("channel setting changed", "guild_id", guildID, "channel_id", channelID, "setting", key, "value", value, "updated_by", updatedBy)
+	return nil
+}
+
+// setOverride parses value and stores it in the field of o named by key.
+func setOverride(o *models.SettingOverrides, key SettingKey, value string) error {
+	switch key {
+	case SettingAsk:
+		v, err := parseBool(value)
+		if err != nil {
+			return err
+		}
+		o.AskEnabled = v
+	case SettingEphemeral:
+		v, err := parseBool(value)
+		if err != nil {
+			return err
+		}
+		o.EphemeralAnswers = v
+	case SettingPersonality:
+		v, err := parseName(value)
+		if err != nil {
+			return err
+		}
+		o.Personality = v
+	case SettingRAGCollection:
+		v, err := parseName(value)
+		if err != nil {
+			return err
+		}
+		o.RAGCollection = v
+	case SettingRateLimit:
+		v, err := parseRateLimit(value)
+		if err != nil {
+			return err
+		}
+		o.RateLimitPerMin = v
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownSetting, key)
+	}
+	return nil
+}
+
+// isDefault reports whether value clears an override.
+func isDefault(value string) bool {
+	value = strings.TrimSpace(value)
+	return value == "" || strings.EqualFold(value, "default")
+}
+
+func parseBool(value string) (*bool, error) {
+	if isDefault(value) {
+		return nil, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "on", "true", "yes", "1":
+		v := true
+		return &v, nil
+	case "off", "false", "no", "0":
+		v := false
+		return &v, nil
+	}
+	return nil, fmt.Errorf("%w: %q is not on or off", ErrInvalidSetting, value)
+}
+
+func parseName(value string) (*string, error) {
+	if isDefault(value) {
+		return nil, nil
+	}
+	value = strings.TrimSpace(value)
+	if !nameValue.MatchString(value) {
+		return nil, fmt.Errorf("%w: %q is not a valid name", ErrInvalidSetting, value)
+	}
+	return &value, nil
+}
+
+func parseRateLimit(value string) (*int, error) {
+	if isDefault(value) {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || n < 1 || n > 1000 {
+		return nil, fmt.Errorf("%w: rate limit must be between 1 and 1000, got %q", ErrInvalidSetting, value)
+	}
+	return &n, nil
+}
+
+func parseSnowflake(value string) (*string, error) {
+	if isDefault(value) {
+		return nil, nil
+	}
+	// Accept channel mentions as well as bare IDs
+	value = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(value), "<#"), ">")
+	if !snowflakeValue.MatchString(value) {
+		return nil, fmt.Errorf("%w: %q is not a channel", ErrInvalidSetting, value)
+	}
+	return &value, nil
+}
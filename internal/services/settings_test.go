@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"living-lands-bot/internal/database/models"
+	"living-lands-bot/internal/testutil"
+)
+
+const (
+	testGuild   = "1000000000000000000"
+	testChannel = "1000000000000000100"
+	testAdmin   = "900000000000000002"
+)
+
+func newTestSettingsService(t *testing.T) *SettingsService {
+	db := testutil.NewTestDB(t, &models.GuildSettings{}, &models.ChannelSettings{})
+	return NewSettingsService(db, getTestLogger())
+}
+
+func TestSettingsResolveDefaults(t *testing.T) {
+	s := newTestSettingsService(t)
+
+	settings, err := s.Resolve(testGuild, testChannel)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultSettings(), settings)
+
+	settings, err = s.Resolve("", "")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultSettings(), settings)
+}
+
+func TestSettingsLayering(t *testing.T) {
+	s := newTestSettingsService(t)
+
+	require.NoError(t, s.SetGuild(testGuild, SettingEphemeral, "on", testAdmin))
+	require.NoError(t, s.SetGuild(testGuild, SettingRateLimit, "10", testAdmin))
+	require.NoError(t, s.SetGuild(testGuild, SettingWelcomeChannel, "<#1000000000000000200>", testAdmin))
+	require.NoError(t, s.SetChannel(testGuild, testChannel, SettingAsk, "off", testAdmin))
+	require.NoError(t, s.SetChannel(testGuild, testChannel, SettingRateLimit, "3", testAdmin))
+	require.NoError(t, s.SetChannel(testGuild, testChannel, SettingRAGCollection, "modding_docs", testAdmin))
+
+	settings, err := s.Resolve(testGuild, testChannel)
+	require.NoError(t, err)
+	assert.Equal(t, Settings{
+		AskEnabled:       false,
+		EphemeralAnswers: true,
+		RAGCollection:    "modding_docs",
+		RateLimitPerMin:  3,
+		WelcomeChannelID: "1000000000000000200",
+	}, settings)
+
+	_, sources, err := s.Explain(testGuild, testChannel)
+	require.NoError(t, err)
+	assert.Equal(t, map[SettingKey]SettingSource{
+		SettingAsk:            SourceChannel,
+		SettingEphemeral:      SourceGuild,
+		SettingPersonality:    SourceDefault,
+		SettingRAGCollection:  SourceChannel,
+		SettingRateLimit:      SourceChannel,
+		SettingWelcomeChannel: SourceGuild,
+	}, sources)
+	assert.Equal(t, "off", settings.Value(SettingAsk))
+	assert.Equal(t, "3", settings.Value(SettingRateLimit))
+	assert.Equal(t, "default", settings.Value(SettingPersonality))
+
+	// Another channel of the guild only gets the guild's overrides
+	settings, err = s.Resolve(testGuild, "1000000000000000101")
+	require.NoError(t, err)
+	assert.True(t, settings.AskEnabled)
+	assert.True(t, settings.EphemeralAnswers)
+	assert.Equal(t, 10, settings.RateLimitPerMin)
+	assert.Empty(t, settings.RAGCollection)
+
+	// Clearing a channel override falls back to the guild
+	require.NoError(t, s.SetChannel(testGuild, testChannel, SettingRateLimit, "default", testAdmin))
+	settings, err = s.Resolve(testGuild, testChannel)
+	require.NoError(t, err)
+	assert.Equal(t, 10, settings.RateLimitPerMin)
+	assert.False(t, settings.AskEnabled, "other overrides are kept")
+
+	row, err := s.Channel(testChannel)
+	require.NoError(t, err)
+	require.NotNil(t, row)
+	assert.Equal(t, testAdmin, row.UpdatedBy)
+
+	rows, err := s.ChannelsWithOverrides(testGuild)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, testChannel, rows[0].ChannelID)
+}
+
+func TestSettingsInvalidValues(t *testing.T) {
+	s := newTestSettingsService(t)
+
+	tests := []struct {
+		key   SettingKey
+		value string
+		want  error
+	}{
+		{SettingAsk, "maybe", ErrInvalidSetting},
+		{SettingRateLimit, "0", ErrInvalidSetting},
+		{SettingRateLimit, "lots", ErrInvalidSetting},
+		{SettingPersonality, "../etc/passwd", ErrInvalidSetting},
+		{SettingWelcomeChannel, "general", ErrInvalidSetting},
+		{SettingKey("colour"), "blue", ErrUnknownSetting},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.key)+"="+tt.value, func(t *testing.T) {
+			assert.ErrorIs(t, s.SetGuild(testGuild, tt.key, tt.value, testAdmin), tt.want)
+		})
+	}
+
+	err := s.SetChannel(testGuild, testChannel, SettingWelcomeChannel, "1000000000000000200", testAdmin)
+	assert.ErrorIs(t, err, ErrGuildOnly)
+
+	row, err := s.Guild(testGuild)
+	require.NoError(t, err)
+	assert.Nil(t, row, "rejected values are not saved")
+}
+
+func TestSettingsCache(t *testing.T) {
+	s := newTestSettingsService(t)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	settings, err := s.Resolve(testGuild, testChannel)
+	require.NoError(t, err)
+	assert.False(t, settings.EphemeralAnswers)
+
+	// A write from another instance is not seen until the cache expires
+	ephemeral := true
+	require.NoError(t, s.db.Create(&models.GuildSettings{
+		GuildID:   testGuild,
+		Overrides: models.SettingOverrides{EphemeralAnswers: &ephemeral},
+	}).Error)
+
+	settings, err = s.Resolve(testGuild, testChannel)
+	require.NoError(t, err)
+	assert.False(t, settings.EphemeralAnswers, "served from cache")
+
+	now = now.Add(DefaultSettingsCacheTTL)
+	settings, err = s.Resolve(testGuild, testChannel)
+	require.NoError(t, err)
+	assert.True(t, settings.EphemeralAnswers, "reloaded after the TTL")
+
+	// Writes through the service invalidate immediately
+	require.NoError(t, s.SetGuild(testGuild, SettingEphemeral, "off", testAdmin))
+	settings, err = s.Resolve(testGuild, testChannel)
+	require.NoError(t, err)
+	assert.False(t, settings.EphemeralAnswers)
+}
+
+func TestSettingsRejectsUnknownPersonality(t *testing.T) {
+	s := newTestSettingsService(t)
+	s.SetKnownPersonalities(func(name string) bool { return name == "tech_support" })
+
+	assert.ErrorIs(t, s.SetChannel(testGuild, testChannel, SettingPersonality, "pirate", testAdmin), ErrInvalidSetting)
+	require.NoError(t, s.SetChannel(testGuild, testChannel, SettingPersonality, "tech_support", testAdmin))
+	require.NoError(t, s.SetGuild(testGuild, SettingPersonality, "default", testAdmin), "clearing is always allowed")
+
+	settings, err := s.Resolve(testGuild, testChannel)
+	require.NoError(t, err)
+	assert.Equal(t, "tech_support", settings.Personality)
+}
+
+func TestSettingsRejectsMissingCollection(t *testing.T) {
+	s := newTestSettingsService(t)
+	s.SetCollectionCheck(func(_ context.Context, name string) (bool, error) {
+		if name == "broken" {
+			return false, errors.New("chromadb unreachable")
+		}
+		return name == "modding_docs", nil
+	})
+
+	assert.ErrorIs(t, s.SetGuild(testGuild, SettingRAGCollection, "moding_docs", testAdmin), ErrInvalidSetting)
+	err := s.SetGuild(testGuild, SettingRAGCollection, "broken", testAdmin)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidSetting, "an unreachable ChromaDB is not the admin's mistake")
+	require.NoError(t, s.SetChannel(testGuild, testChannel, SettingRAGCollection, "modding_docs", testAdmin))
+	require.NoError(t, s.SetGuild(testGuild, SettingRAGCollection, "default", testAdmin), "clearing is always allowed")
+
+	settings, err := s.Resolve(testGuild, testChannel)
+	require.NoError(t, err)
+	assert.Equal(t, "modding_docs", settings.RAGCollection)
+}
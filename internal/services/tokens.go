@@ -0,0 +1,212 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tokenizer estimates how many tokens a model family's tokenizer turns text
+// into. It doesn't run the real tokenizer: the ratios are averages measured
+// on English, Russian and Japanese documentation, rounded towards
+// overestimating so a budget built on them holds.
+type Tokenizer struct {
+	Family string
+
+	charsPerToken float64 // ASCII characters per token
+	cjkPerRune    float64 // Tokens per Chinese, Japanese or Korean character
+	otherPerRune  float64 // Tokens per other non-ASCII character (Cyrillic, accents, emoji)
+}
+
+// Known tokenizer families. Llama 2, Mistral 7B and Mixtral share a 32k
+// SentencePiece vocabulary that splits non-English text finely; Llama 3, Qwen
+// and Gemma have large vocabularies that encode it more compactly.
+var (
+	tokenizerLlama3  = Tokenizer{Family: "llama3", charsPerToken: 3.8, cjkPerRune: 1.0, otherPerRune: 0.45}
+	tokenizerQwen    = Tokenizer{Family: "qwen", charsPerToken: 3.8, cjkPerRune: 0.8, otherPerRune: 0.45}
+	tokenizerGemma   = Tokenizer{Family: "gemma", charsPerToken: 3.8, cjkPerRune: 0.8, otherPerRune: 0.4}
+	tokenizerMistral = Tokenizer{Family: "mistral", charsPerToken: 3.3, cjkPerRune: 1.4, otherPerRune: 0.6}
+	tokenizerPhi     = Tokenizer{Family: "phi", charsPerToken: 3.5, cjkPerRune: 1.3, otherPerRune: 0.6}
+
+	// tokenizerDefault is used for unknown models and errs on the high side
+	tokenizerDefault = Tokenizer{Family: "default", charsPerToken: 3.0, cjkPerRune: 1.5, otherPerRune: 0.7}
+)
+
+// tokenizerPrefixes maps model name prefixes to tokenizer families. Longer
+// prefixes come first so "llama3" wins over "llama".
+var tokenizerPrefixes = []struct {
+	prefix    string
+	tokenizer Tokenizer
+}{
+	{"llama3", tokenizerLlama3},
+	{"llama-3", tokenizerLlama3},
+	{"llama2", tokenizerMistral},
+	{"llama-2", tokenizerMistral},
+	{"codellama", tokenizerMistral},
+	{"mistral", tokenizerMistral},
+	{"mixtral", tokenizerMistral},
+	{"qwen", tokenizerQwen},
+	{"gemma", tokenizerGemma},
+	{"phi", tokenizerPhi},
+}
+
+// TokenizerFor returns the tokenizer estimate for a model name as Ollama or
+// an OpenAI-compatible server knows it, e.g. "mistral:7b-instruct",
+// "qwen2.5:3b" or "hf.co/bartowski/Llama-3.2-3B-Instruct-GGUF".
+func TokenizerFor(model string) Tokenizer {
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	name, _, _ = strings.Cut(name, ":")
+
+	for _, p := range tokenizerPrefixes {
+		if strings.HasPrefix(name, p.prefix) {
+			return p.tokenizer
+		}
+	}
+	return tokenizerDefault
+}
+
+// conservative combines two estimates into one that never counts fewer
+// tokens than either, for a fallback chain whose models differ.
+func (t Tokenizer) conservative(other Tokenizer) Tokenizer {
+	if t == other {
+		return t
+	}
+	return Tokenizer{
+		Family:        t.Family + "+" + other.Family,
+		charsPerToken: math.Min(t.charsPerToken, other.charsPerToken),
+		cjkPerRune:    math.Max(t.cjkPerRune, other.cjkPerRune),
+		otherPerRune:  math.Max(t.otherPerRune, other.otherPerRune),
+	}
+}
+
+// Count estimates the number of tokens in text.
+func (t Tokenizer) Count(text string) int {
+	var ascii, cjk, other int
+	for _, r := range text {
+		switch {
+		case r < utf8.RuneSelf:
+			ascii++
+		case isCJK(r):
+			cjk++
+		default:
+			other++
+		}
+	}
+	tokens := float64(ascii)/t.charsPerToken + float64(cjk)*t.cjkPerRune + float64(other)*t.otherPerRune
+	return int(math.Ceil(tokens))
+}
+
+// Truncate shortens text to at most maxTokens, cutting at a sentence or word
+// boundary (see TruncateAtSentence) and never inside a character.
+func (t Tokenizer) Truncate(text string, maxTokens int) string {
+	if t.Count(text) <= maxTokens {
+		return text
+	}
+	if maxTokens <= 0 {
+		return ""
+	}
+
+	// Find the longest cut, in characters, that fits
+	lo, hi := 0, utf8.RuneCountInString(text)
+	best := ""
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		cut := TruncateAtSentence(text, mid)
+		if t.Count(cut) <= maxTokens {
+			best = cut
+			lo = mid + 1
+		} else {
+			hi = mid - 1
+		}
+	}
+	return best
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+const (
+	// messageOverhead is what a chat template adds around each message
+	// (role markers, separators), in tokens
+	messageOverhead = 4
+
+	// minDocTokens is the smallest cut of a chunk worth sending; less room
+	// than this ends the documentation instead
+	minDocTokens = 32
+)
+
+// ContextBudget splits a model's context window between the parts of a
+// prompt, in estimated tokens. Output is reserved for the answer up front;
+// documentation gets whatever the rest leaves.
+type ContextBudget struct {
+	Window   int // Context window (num_ctx) the prompt and answer share
+	Output   int // Reserved for the generated answer
+	System   int // Persona system prompt
+	History  int // Few-shot example turns
+	Question int // The user's question
+	Docs     int // RAG documentation, including its header
+
+	ExamplesDropped int // Few-shot exchanges left out for lack of room
+	DocsKept        int // Chunks sent, the last possibly cut short
+	DocsDropped     int // Chunks left out for lack of room
+}
+
+// Prompt returns the estimated size of the prompt.
+func (b ContextBudget) Prompt() int {
+	return b.System + b.History + b.Question + b.Docs
+}
+
+// Remaining returns the tokens not yet allocated; negative when the prompt
+// and reserved output don't fit the window.
+func (b ContextBudget) Remaining() int {
+	return b.Window - b.Output - b.Prompt()
+}
+
+// docsTokens estimates the documentation message holding every chunk of
+// ragContext.
+func docsTokens(tok Tokenizer, header string, ragContext []string) int {
+	tokens := tok.Count(header) + messageOverhead
+	for i, chunk := range ragContext {
+		tokens += tok.Count(fmt.Sprintf("%d. ", i+1)) + tok.Count(chunk) + 1 // the newline
+	}
+	return tokens
+}
+
+// fitDocs formats ragContext, most relevant first, into the documentation
+// message within the budget's remaining room. Chunks are added whole while
+// they fit; the first that doesn't is cut to the room left (at a sentence
+// where possible) and nothing after it is sent. It returns "" when not even
+// one chunk fits.
+func (b *ContextBudget) fitDocs(tok Tokenizer, header string, ragContext []string) string {
+	room := b.Remaining() - messageOverhead - tok.Count(header)
+
+	var docs strings.Builder
+	for i, chunk := range ragContext {
+		prefix := fmt.Sprintf("%d. ", i+1)
+		cost := tok.Count(prefix) + tok.Count(chunk) + 1 // the newline
+		if cost > room {
+			if cut := tok.Truncate(chunk, room-tok.Count(prefix)-1); room >= minDocTokens && cut != "" {
+				docs.WriteString(prefix + cut + "\n")
+				b.DocsKept++
+			}
+			break
+		}
+		docs.WriteString(prefix + chunk + "\n")
+		b.DocsKept++
+		room -= cost
+	}
+	b.DocsDropped = len(ragContext) - b.DocsKept
+
+	if b.DocsKept == 0 {
+		return ""
+	}
+	text := header + docs.String()
+	b.Docs = tok.Count(text) + messageOverhead
+	return text
+}
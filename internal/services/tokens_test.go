@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"living-lands-bot/internal/testutil"
+	"living-lands-bot/pkg/language"
+	"living-lands-bot/pkg/llm"
+)
+
+func TestTokenizerFor(t *testing.T) {
+	tests := []struct {
+		model  string
+		family string
+	}{
+		{"mistral:7b-instruct", "mistral"},
+		{"mixtral:8x7b", "mistral"},
+		{"llama2:13b", "mistral"},
+		{"llama3.1:8b", "llama3"},
+		{"hf.co/bartowski/Llama-3.2-3B-Instruct-GGUF:Q4_K_M", "llama3"},
+		{"qwen2.5:3b", "qwen"},
+		{"gemma2:2b", "gemma"},
+		{"phi3:mini", "phi"},
+		{"test-model", "default"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.family, TokenizerFor(tt.model).Family, tt.model)
+	}
+}
+
+func TestTokenizerCount(t *testing.T) {
+	tok := TokenizerFor("mistral:7b-instruct")
+
+	assert.Equal(t, 0, tok.Count(""))
+	assert.Equal(t, 6, tok.Count("Rest at a campfire."), "19 ASCII characters at 3.3 per token, rounded up")
+
+	// The same question costs more tokens in Japanese and Russian
+	english := tok.Count("How do I recover stamina?")
+	assert.Greater(t, tok.Count("スタミナを回復するにはどうすればいいですか？"), english)
+	assert.Greater(t, tok.Count("Как восстановить выносливость?"), english)
+}
+
+func TestTokenizerConservative(t *testing.T) {
+	qwen, mistral := TokenizerFor("qwen2.5:3b"), TokenizerFor("mistral:7b-instruct")
+	both := qwen.conservative(mistral)
+
+	assert.Equal(t, "qwen+mistral", both.Family)
+	for _, text := range []string{"Wolves hunt at night.", "狼は夜に狩りをする。", "Волки охотятся ночью."} {
+		assert.GreaterOrEqual(t, both.Count(text), qwen.Count(text), text)
+		assert.GreaterOrEqual(t, both.Count(text), mistral.Count(text), text)
+	}
+	assert.Equal(t, qwen, qwen.conservative(qwen))
+}
+
+func TestTokenizerTruncate(t *testing.T) {
+	tok := TokenizerFor("mistral:7b-instruct")
+
+	text := "Hunger drains every hour. Eat cooked meat to recover. Raw meat may make you sick."
+	assert.Equal(t, text, tok.Truncate(text, 100))
+	assert.Equal(t, "Hunger drains every hour. Eat cooked meat to recover.", tok.Truncate(text, 18))
+	assert.Empty(t, tok.Truncate(text, 0))
+
+	japanese := strings.Repeat("空腹は毎時間減ります", 30)
+	cut := tok.Truncate(japanese, 50)
+	assert.True(t, utf8.ValidString(cut), "never cuts inside a character")
+	assert.LessOrEqual(t, tok.Count(cut), 50)
+	assert.NotEmpty(t, cut)
+}
+
+func TestBuildMessagesBudgetsDocs(t *testing.T) {
+	svc, _ := newFakeLLMService(t)
+	svc.config.NumContext = 768
+	svc.config.MaxNumContext = 0 // A fixed window
+	p := svc.persona(svc.personalities.Select(""))
+
+	chunks := make([]string, 10)
+	for i := range chunks {
+		chunks[i] = fmt.Sprintf("Chunk %d. %s", i+1, strings.Repeat("Thirst drains faster in the desert biome. ", 10))
+	}
+	chunks[2] = strings.Repeat("砂漠では喉の渇きが早く進みます。", 20)
+
+	messages, budget := svc.buildMessages(p, "how does thirst work?", chunks, ModeDeep, language.English)
+
+	assert.Equal(t, 768, budget.Window)
+	assert.Equal(t, svc.config.DeepMaxTokens, budget.Output)
+	assert.GreaterOrEqual(t, budget.Remaining(), 0, "the prompt fits the window")
+	assert.Greater(t, budget.DocsKept, 0)
+	assert.Greater(t, budget.DocsDropped, 0)
+	assert.Equal(t, len(chunks), budget.DocsKept+budget.DocsDropped)
+
+	var docs string
+	for _, m := range messages {
+		if m.Role == llm.RoleSystem && strings.HasPrefix(m.Content, "Relevant documentation") {
+			docs = m.Content
+		}
+	}
+	require.NotEmpty(t, docs)
+	assert.True(t, utf8.ValidString(docs))
+	assert.Contains(t, docs, "1. "+chunks[0], "the most relevant chunk is sent whole")
+	assert.NotContains(t, docs, "Chunk 10.", "the least relevant chunk is dropped")
+	assert.Equal(t, svc.tokenizer.Count(docs)+messageOverhead, budget.Docs)
+
+	// Other modes send no documentation
+	messages, budget = svc.buildMessages(p, "hello!", chunks, ModeFast, language.English)
+	assert.Zero(t, budget.Docs)
+	assert.Len(t, messages, len(p.Examples.For(ModeFast))*2+2)
+}
+
+func TestBuildMessagesDropsDocsWithoutRoom(t *testing.T) {
+	svc, _ := newFakeLLMService(t)
+	svc.config.MaxNumContext = 0
+	p := svc.persona(svc.personalities.Select(""))
+
+	// Leave room for the persona and question only
+	_, budget := svc.buildMessages(p, "how does thirst work?", nil, ModeDeep, language.English)
+	svc.config.NumContext = budget.Window - budget.Remaining() + minDocTokens/2
+
+	messages, budget := svc.buildMessages(p, "how does thirst work?", []string{"Thirst drains faster in the desert biome."}, ModeDeep, language.English)
+	assert.Zero(t, budget.DocsKept)
+	assert.Equal(t, 1, budget.DocsDropped)
+	for _, m := range messages {
+		assert.False(t, strings.HasPrefix(m.Content, "Relevant documentation"), "no documentation message")
+	}
+}
+
+func TestBuildMessagesGrowsContextWindow(t *testing.T) {
+	svc, _ := newFakeLLMService(t)
+	svc.config.NumContext = 768
+	svc.config.MaxNumContext = 8192
+	p := svc.persona(svc.personalities.Select(""))
+
+	chunks := make([]string, 10)
+	for i := range chunks {
+		chunks[i] = fmt.Sprintf("Chunk %d. %s", i+1, strings.Repeat("Thirst drains faster in the desert biome. ", 10))
+	}
+
+	_, budget := svc.buildMessages(p, "how does thirst work?", chunks, ModeDeep, language.English)
+	assert.Contains(t, []int{1536, 3072, 6144}, budget.Window, "the window doubles until the prompt fits")
+	assert.Equal(t, len(chunks), budget.DocsKept)
+	assert.GreaterOrEqual(t, budget.Remaining(), 0)
+
+	// Capped at the maximum, the least relevant chunks are dropped instead
+	svc.config.MaxNumContext = 1000
+	_, budget = svc.buildMessages(p, "how does thirst work?", chunks, ModeDeep, language.English)
+	assert.Equal(t, 1000, budget.Window)
+	assert.Greater(t, budget.DocsDropped, 0)
+
+	// A prompt that fits keeps the configured window
+	_, budget = svc.buildMessages(p, "hello!", nil, ModeFast, language.English)
+	assert.Equal(t, 768, budget.Window)
+}
+
+func TestBuildMessagesDropsExamplesBeforePersona(t *testing.T) {
+	svc, _ := newFakeLLMService(t)
+	svc.config.MaxNumContext = 0
+	p := svc.persona(svc.personalities.Select(""))
+	examples := p.Examples.For(ModeFast)
+	require.NotEmpty(t, examples)
+
+	// Room for the persona, question and answer, but not the examples
+	_, budget := svc.buildMessages(p, "hello!", nil, ModeFast, language.English)
+	require.Positive(t, budget.History)
+	svc.config.NumContext = budget.Prompt() - budget.History + budget.Output + 1
+
+	messages, budget := svc.buildMessages(p, "hello!", nil, ModeFast, language.English)
+	assert.Equal(t, len(examples), budget.ExamplesDropped)
+	assert.Zero(t, budget.History)
+	assert.GreaterOrEqual(t, budget.Remaining(), 0)
+	require.Len(t, messages, 2)
+	assert.Equal(t, llm.RoleSystem, messages[0].Role, "the persona is kept")
+	assert.Equal(t, "hello!", messages[1].Content)
+}
+
+func TestGenerateAnswerSizesContextWindow(t *testing.T) {
+	svc, server := newFakeLLMService(t)
+	svc.config.NumContext = 512
+	svc.config.MaxNumContext = 4096
+	server.Script(
+		testutil.Completion{Response: "Drink often in the desert, traveler."},
+		testutil.Completion{Response: "Well met!"},
+	)
+
+	chunks := make([]string, 5)
+	for i := range chunks {
+		chunks[i] = strings.Repeat("Thirst drains faster in the desert biome. ", 10)
+	}
+	_, err := svc.GenerateAnswer(context.Background(), "how does thirst work?", chunks, IntentKnowledge)
+	require.NoError(t, err)
+
+	// Without room to grow, the examples go before the persona would be cut
+	svc.config.MaxNumContext = 0
+	p := svc.persona(svc.personalities.Select(""))
+	_, budget := svc.buildMessages(p, "hello!", nil, ModeFast, language.English)
+	window := budget.Prompt() - budget.History + budget.Output
+	svc.config.NumContext = window
+	_, err = svc.GenerateAnswer(context.Background(), "hello!", nil, IntentConversational)
+	require.NoError(t, err)
+
+	reqs := server.ChatRequests()
+	require.Len(t, reqs, 2)
+	assert.Greater(t, reqs[0].Options.NumCtx, 512, "num_ctx grows to fit the documentation")
+	assert.LessOrEqual(t, reqs[0].Options.NumCtx, 4096)
+	assert.Equal(t, window, reqs[1].Options.NumCtx)
+	require.Len(t, reqs[1].Messages, 2, "persona and question only")
+	assert.Equal(t, "system", reqs[1].Messages[0].Role)
+}
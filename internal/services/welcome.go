@@ -23,14 +23,25 @@ func NewWelcomeService(db *gorm.DB, logger *slog.Logger) *WelcomeService {
 	}
 }
 
-// GetRandomTemplate returns a weighted random welcome message
-func (s *WelcomeService) GetRandomTemplate(username string) (string, error) {
+// GetRandomTemplate returns a weighted random welcome message from the
+// guild's templates. Templates without a guild are shared by guilds that
+// have none of their own.
+func (s *WelcomeService) GetRandomTemplate(guildID, username string) (string, error) {
 	var templates []models.WelcomeTemplate
 
-	err := s.db.Where("active = ?", true).Find(&templates).Error
+	err := s.db.Where("guild_id IN ? AND active = ?", []string{guildID, ""}, true).Find(&templates).Error
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch templates: %w", err)
 	}
+	var own []models.WelcomeTemplate
+	for _, t := range templates {
+		if t.GuildID == guildID {
+			own = append(own, t)
+		}
+	}
+	if len(own) > 0 {
+		templates = own
+	}
 
 	if len(templates) == 0 {
 		// Fallback if no templates exist
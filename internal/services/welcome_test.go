@@ -6,9 +6,12 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gorm.io/gorm"
 
 	"living-lands-bot/internal/database/models"
+	"living-lands-bot/internal/testutil"
 )
 
 func TestGetRandomTemplateWithActiveTemplates(t *testing.T) {
@@ -162,3 +165,26 @@ func TestWeightCalculation(t *testing.T) {
 
 	t.Logf("Total weight calculated correctly: %d", totalWeight)
 }
+
+func TestGetRandomTemplateScopedByGuild(t *testing.T) {
+	db := testutil.NewTestDB(t, &models.WelcomeTemplate{})
+	s := NewWelcomeService(db, getTestLogger())
+
+	require.NoError(t, db.Create(&[]models.WelcomeTemplate{
+		{Message: "Shared welcome, {username}!", Weight: 1, Active: true},
+		{GuildID: testGuild, Message: "Hail, {username}!", Weight: 1, Active: true},
+		{GuildID: testGuild, Message: "Retired, {username}.", Weight: 1},
+	}).Error)
+	// Active defaults to true on insert, so retire the template afterwards
+	require.NoError(t, db.Model(&models.WelcomeTemplate{}).Where("message LIKE ?", "Retired%").Update("active", false).Error)
+
+	for range 10 {
+		message, err := s.GetRandomTemplate(testGuild, "Alice")
+		require.NoError(t, err)
+		assert.Equal(t, "Hail, Alice!", message, "a guild's own templates win over shared ones")
+	}
+
+	message, err := s.GetRandomTemplate("1000000000000000001", "Bob")
+	require.NoError(t, err)
+	assert.Equal(t, "Shared welcome, Bob!", message, "guilds without templates use the shared ones")
+}
@@ -0,0 +1,319 @@
+package testutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+const chromaCollectionsPath = "/api/v2/tenants/{tenant}/databases/{database}/collections"
+
+// ChromaServer is an httptest-based, in-memory fake of the ChromaDB v2
+// collection endpoints used by RAGService: get/create collection, add,
+// query (cosine distance), delete and count.
+type ChromaServer struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	collections map[string]*fakeCollection // keyed by collection ID
+	byName      map[string]string          // name -> ID
+	nextID      int
+	queryStatus int
+	queryDelay  time.Duration
+	queries     int
+}
+
+type fakeCollection struct {
+	id       string
+	name     string
+	metadata map[string]interface{}
+	records  map[string]fakeRecord
+	order    []string // insertion order for stable results
+}
+
+type fakeRecord struct {
+	embedding []float32
+	document  string
+	metadata  map[string]interface{}
+}
+
+// NewChromaServer starts a fake ChromaDB server that is closed when the test ends.
+func NewChromaServer(t testing.TB) *ChromaServer {
+	t.Helper()
+
+	s := &ChromaServer{
+		collections: make(map[string]*fakeCollection),
+		byName:      make(map[string]string),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+chromaCollectionsPath+"/{name}", s.handleGetCollection)
+	mux.HandleFunc("POST "+chromaCollectionsPath, s.handleCreateCollection)
+	mux.HandleFunc("POST "+chromaCollectionsPath+"/{id}/add", s.handleAdd)
+	mux.HandleFunc("POST "+chromaCollectionsPath+"/{id}/query", s.handleQuery)
+	mux.HandleFunc("POST "+chromaCollectionsPath+"/{id}/delete", s.handleDelete)
+	mux.HandleFunc("GET "+chromaCollectionsPath+"/{id}/count", s.handleCount)
+
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// FailQueries makes the query endpoint return the given status code (0 restores success).
+func (s *ChromaServer) FailQueries(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queryStatus = status
+}
+
+// DelayQueries makes the query endpoint wait before responding.
+func (s *ChromaServer) DelayQueries(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queryDelay = d
+}
+
+// QueryCount returns how many query requests have been received.
+func (s *ChromaServer) QueryCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queries
+}
+
+// Len returns the number of records stored in the named collection.
+func (s *ChromaServer) Len(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.byName[name]
+	if !ok {
+		return 0
+	}
+	return len(s.collections[id].records)
+}
+
+func (s *ChromaServer) handleGetCollection(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	id, ok := s.byName[r.PathValue("name")]
+	var coll *fakeCollection
+	if ok {
+		coll = s.collections[id]
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "collection not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, coll.describe())
+}
+
+func (s *ChromaServer) handleCreateCollection(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name     string                 `json:"name"`
+		Metadata map[string]interface{} `json:"metadata"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.byName[req.Name]; exists {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": fmt.Sprintf("collection %s already exists", req.Name)})
+		return
+	}
+
+	s.nextID++
+	coll := &fakeCollection{
+		id:       fmt.Sprintf("00000000-0000-4000-8000-%012d", s.nextID),
+		name:     req.Name,
+		metadata: req.Metadata,
+		records:  make(map[string]fakeRecord),
+	}
+	s.collections[coll.id] = coll
+	s.byName[coll.name] = coll.id
+
+	writeJSON(w, http.StatusOK, coll.describe())
+}
+
+func (s *ChromaServer) handleAdd(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IDs        []string                 `json:"ids"`
+		Embeddings [][]float32              `json:"embeddings"`
+		Documents  []string                 `json:"documents"`
+		Metadatas  []map[string]interface{} `json:"metadatas"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+		return
+	}
+	if len(req.Embeddings) != len(req.IDs) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "ids and embeddings length mismatch"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	coll, ok := s.collections[r.PathValue("id")]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "collection not found"})
+		return
+	}
+
+	for i, id := range req.IDs {
+		rec := fakeRecord{embedding: req.Embeddings[i]}
+		if i < len(req.Documents) {
+			rec.document = req.Documents[i]
+		}
+		if i < len(req.Metadatas) {
+			rec.metadata = req.Metadatas[i]
+		}
+		if _, exists := coll.records[id]; !exists {
+			coll.order = append(coll.order, id)
+		}
+		coll.records[id] = rec
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]bool{"success": true})
+}
+
+func (s *ChromaServer) handleQuery(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		QueryEmbeddings [][]float32 `json:"query_embeddings"`
+		NResults        int         `json:"n_results"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+		return
+	}
+
+	s.mu.Lock()
+	s.queries++
+	status := s.queryStatus
+	delay := s.queryDelay
+	coll, ok := s.collections[r.PathValue("id")]
+	s.mu.Unlock()
+
+	if !sleepCtx(r, delay) {
+		return
+	}
+	if status != 0 && status != http.StatusOK {
+		writeJSON(w, status, map[string]string{"error": "scripted failure"})
+		return
+	}
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "collection not found"})
+		return
+	}
+
+	type hit struct {
+		id       string
+		distance float32
+		rec      fakeRecord
+	}
+
+	resp := struct {
+		IDs       [][]string                 `json:"ids"`
+		Documents [][]string                 `json:"documents"`
+		Distances [][]float32                `json:"distances"`
+		Metadatas [][]map[string]interface{} `json:"metadatas"`
+	}{}
+
+	s.mu.Lock()
+	for _, q := range req.QueryEmbeddings {
+		hits := make([]hit, 0, len(coll.order))
+		for _, id := range coll.order {
+			rec := coll.records[id]
+			hits = append(hits, hit{id: id, distance: CosineDistance(q, rec.embedding), rec: rec})
+		}
+		sort.SliceStable(hits, func(i, j int) bool { return hits[i].distance < hits[j].distance })
+		if req.NResults > 0 && len(hits) > req.NResults {
+			hits = hits[:req.NResults]
+		}
+
+		ids := make([]string, len(hits))
+		docs := make([]string, len(hits))
+		dists := make([]float32, len(hits))
+		metas := make([]map[string]interface{}, len(hits))
+		for i, h := range hits {
+			ids[i], docs[i], dists[i], metas[i] = h.id, h.rec.document, h.distance, h.rec.metadata
+		}
+		resp.IDs = append(resp.IDs, ids)
+		resp.Documents = append(resp.Documents, docs)
+		resp.Distances = append(resp.Distances, dists)
+		resp.Metadatas = append(resp.Metadatas, metas)
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *ChromaServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	coll, ok := s.collections[r.PathValue("id")]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "collection not found"})
+		return
+	}
+
+	for _, id := range req.IDs {
+		delete(coll.records, id)
+	}
+	kept := coll.order[:0]
+	for _, id := range coll.order {
+		if _, exists := coll.records[id]; exists {
+			kept = append(kept, id)
+		}
+	}
+	coll.order = kept
+
+	writeJSON(w, http.StatusOK, req.IDs)
+}
+
+func (s *ChromaServer) handleCount(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	coll, ok := s.collections[r.PathValue("id")]
+	var n int
+	if ok {
+		n = len(coll.records)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "collection not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, n)
+}
+
+func (c *fakeCollection) describe() map[string]interface{} {
+	return map[string]interface{}{
+		"id":       c.id,
+		"name":     c.name,
+		"metadata": c.metadata,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,117 @@
+package testutil
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// InteractionRecorder is an in-memory stand-in for *discordgo.Session that
+// records interaction responses, edits and follow-ups instead of calling Discord.
+type InteractionRecorder struct {
+	// RespondErr, EditErr and FollowupErr, when set, are returned from the respective calls.
+	RespondErr  error
+	EditErr     error
+	FollowupErr error
+
+	mu        sync.Mutex
+	responses []*discordgo.InteractionResponse
+	edits     []*discordgo.WebhookEdit
+	deletes   int
+	followups []*discordgo.WebhookParams
+}
+
+// NewInteractionRecorder returns an empty recorder.
+func NewInteractionRecorder() *InteractionRecorder {
+	return &InteractionRecorder{}
+}
+
+// InteractionRespond records the initial interaction response.
+func (r *InteractionRecorder) InteractionRespond(_ *discordgo.Interaction, resp *discordgo.InteractionResponse, _ ...discordgo.RequestOption) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.responses = append(r.responses, resp)
+	return r.RespondErr
+}
+
+// InteractionResponseEdit records an edit of the original (e.g. deferred) response.
+func (r *InteractionRecorder) InteractionResponseEdit(_ *discordgo.Interaction, newresp *discordgo.WebhookEdit, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.edits = append(r.edits, newresp)
+	if r.EditErr != nil {
+		return nil, r.EditErr
+	}
+	msg := &discordgo.Message{}
+	if newresp.Content != nil {
+		msg.Content = *newresp.Content
+	}
+	return msg, nil
+}
+
+// InteractionResponseDelete records that the original response was deleted.
+func (r *InteractionRecorder) InteractionResponseDelete(_ *discordgo.Interaction, _ ...discordgo.RequestOption) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deletes++
+	return nil
+}
+
+// FollowupMessageCreate records a follow-up message.
+func (r *InteractionRecorder) FollowupMessageCreate(_ *discordgo.Interaction, _ bool, data *discordgo.WebhookParams, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.followups = append(r.followups, data)
+	if r.FollowupErr != nil {
+		return nil, r.FollowupErr
+	}
+	return &discordgo.Message{Content: data.Content, Embeds: data.Embeds}, nil
+}
+
+// Responses returns all recorded initial responses.
+func (r *InteractionRecorder) Responses() []*discordgo.InteractionResponse {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*discordgo.InteractionResponse(nil), r.responses...)
+}
+
+// Edits returns all recorded edits of the original response.
+func (r *InteractionRecorder) Edits() []*discordgo.WebhookEdit {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*discordgo.WebhookEdit(nil), r.edits...)
+}
+
+// Deletes returns how many times the original response was deleted.
+func (r *InteractionRecorder) Deletes() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.deletes
+}
+
+// Followups returns all recorded follow-up messages.
+func (r *InteractionRecorder) Followups() []*discordgo.WebhookParams {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*discordgo.WebhookParams(nil), r.followups...)
+}
+
+// LoadInteraction reads an InteractionCreate payload (as sent by the Discord
+// gateway) from a JSON fixture file.
+func LoadInteraction(t testing.TB, path string) *discordgo.InteractionCreate {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read interaction fixture %s: %v", path, err)
+	}
+
+	var ic discordgo.InteractionCreate
+	if err := json.Unmarshal(data, &ic); err != nil {
+		t.Fatalf("failed to parse interaction fixture %s: %v", path, err)
+	}
+	return &ic
+}
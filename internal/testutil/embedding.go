@@ -0,0 +1,70 @@
+// Package testutil provides offline fakes of the bot's external dependencies
+// (Ollama, ChromaDB) so the ask pipeline can be exercised deterministically in CI.
+package testutil
+
+import (
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// DefaultEmbeddingDim is the vector size returned by the fake Ollama embed endpoint.
+const DefaultEmbeddingDim = 64
+
+// HashEmbedding returns a deterministic, L2-normalized bag-of-words embedding.
+// Each lowercase word is hashed into one of dim buckets with a hash-derived sign,
+// so texts that share words end up close in cosine space and unrelated texts do not.
+func HashEmbedding(text string, dim int) []float32 {
+	if dim <= 0 {
+		dim = DefaultEmbeddingDim
+	}
+	vec := make([]float64, dim)
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, w := range words {
+		h := fnv.New64a()
+		h.Write([]byte(w))
+		sum := h.Sum64()
+		idx := int(sum % uint64(dim))
+		if sum&(1<<63) != 0 {
+			vec[idx] -= 1
+		} else {
+			vec[idx] += 1
+		}
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+
+	out := make([]float32, dim)
+	if norm == 0 {
+		// Empty input: return a fixed unit vector so distances stay defined
+		out[0] = 1
+		return out
+	}
+	for i, v := range vec {
+		out[i] = float32(v / norm)
+	}
+	return out
+}
+
+// CosineDistance returns 1 - cosine similarity, matching ChromaDB's "cosine" space
+// (0 = identical, 2 = opposite).
+func CosineDistance(a, b []float32) float32 {
+	var dot, na, nb float64
+	for i := 0; i < len(a) && i < len(b); i++ {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 1
+	}
+	return float32(1 - dot/(math.Sqrt(na)*math.Sqrt(nb)))
+}
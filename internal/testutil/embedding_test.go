@@ -0,0 +1,42 @@
+package testutil
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHashEmbeddingDeterministic(t *testing.T) {
+	a := HashEmbedding("How does the metabolism system work?", 32)
+	b := HashEmbedding("How does the metabolism system work?", 32)
+
+	if len(a) != 32 {
+		t.Fatalf("expected 32 dimensions, got %d", len(a))
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("embedding not deterministic at index %d: %v != %v", i, a[i], b[i])
+		}
+	}
+
+	var norm float64
+	for _, v := range a {
+		norm += float64(v) * float64(v)
+	}
+	if math.Abs(norm-1) > 1e-5 {
+		t.Errorf("expected unit vector, got squared norm %f", norm)
+	}
+}
+
+func TestHashEmbeddingSimilarity(t *testing.T) {
+	query := HashEmbedding("metabolism hunger thirst", DefaultEmbeddingDim)
+	related := HashEmbedding("The metabolism system tracks hunger and thirst.", DefaultEmbeddingDim)
+	unrelated := HashEmbedding("Download the installer from CurseForge", DefaultEmbeddingDim)
+
+	if CosineDistance(query, related) >= CosineDistance(query, unrelated) {
+		t.Errorf("related text should be closer: related=%f unrelated=%f",
+			CosineDistance(query, related), CosineDistance(query, unrelated))
+	}
+	if d := CosineDistance(query, query); d > 1e-5 {
+		t.Errorf("identical vectors should have distance 0, got %f", d)
+	}
+}
@@ -0,0 +1,274 @@
+package testutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"living-lands-bot/pkg/ollama"
+)
+
+// DefaultCompletion is returned by the fake Ollama server when no completion is scripted.
+const DefaultCompletion = "Greetings, traveler. The archives hold the answer you seek."
+
+// Completion is a scripted response for the fake /api/generate and /api/chat endpoints.
+type Completion struct {
+	Response string
+	// Status overrides the HTTP status code (e.g. 500 to simulate an overloaded server).
+	Status int
+	// Delay is applied before responding; the request context cancels it early.
+	Delay time.Duration
+	// Token counts reported in the response metadata.
+	PromptEvalCount int
+	EvalCount       int
+}
+
+// OllamaServer is an httptest-based fake of the Ollama HTTP API.
+// It serves /api/generate and /api/chat (streaming and non-streaming) and /api/embed.
+// Completions are consumed in FIFO order; once the script is exhausted
+// DefaultCompletion is returned.
+type OllamaServer struct {
+	*httptest.Server
+
+	// EmbeddingDim controls the size of vectors returned by /api/embed.
+	EmbeddingDim int
+
+	mu            sync.Mutex
+	script        []Completion
+	generateCalls []ollama.GenerateRequest
+	chatCalls     []ollama.ChatRequest
+	embedCalls    []ollama.EmbedRequest
+	embedStatus   int
+	embedDelay    time.Duration
+}
+
+// NewOllamaServer starts a fake Ollama server that is closed when the test ends.
+func NewOllamaServer(t testing.TB) *OllamaServer {
+	t.Helper()
+
+	s := &OllamaServer{EmbeddingDim: DefaultEmbeddingDim}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/generate", s.handleGenerate)
+	mux.HandleFunc("POST /api/chat", s.handleChat)
+	mux.HandleFunc("POST /api/embed", s.handleEmbed)
+
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Script appends completions to the response queue.
+func (s *OllamaServer) Script(completions ...Completion) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.script = append(s.script, completions...)
+}
+
+// FailEmbeddings makes /api/embed return the given status code (0 restores success).
+func (s *OllamaServer) FailEmbeddings(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.embedStatus = status
+}
+
+// DelayEmbeddings makes /api/embed wait before responding.
+func (s *OllamaServer) DelayEmbeddings(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.embedDelay = d
+}
+
+// GenerateRequests returns a copy of all /api/generate requests received so far.
+func (s *OllamaServer) GenerateRequests() []ollama.GenerateRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ollama.GenerateRequest(nil), s.generateCalls...)
+}
+
+// ChatRequests returns a copy of all /api/chat requests received so far.
+func (s *OllamaServer) ChatRequests() []ollama.ChatRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ollama.ChatRequest(nil), s.chatCalls...)
+}
+
+// EmbedRequests returns a copy of all /api/embed requests received so far.
+func (s *OllamaServer) EmbedRequests() []ollama.EmbedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ollama.EmbedRequest(nil), s.embedCalls...)
+}
+
+func (s *OllamaServer) next() Completion {
+	if len(s.script) == 0 {
+		return Completion{Response: DefaultCompletion}
+	}
+	c := s.script[0]
+	s.script = s.script[1:]
+	return c
+}
+
+func (s *OllamaServer) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	var req ollama.GenerateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid request"}`, http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.generateCalls = append(s.generateCalls, req)
+	c := s.next()
+	s.mu.Unlock()
+
+	s.writeCompletion(w, r, c, req.System+" "+req.Prompt, req.Stream, func(text string, done bool) interface{} {
+		return ollama.GenerateResponse{Response: text, Done: done}
+	})
+}
+
+func (s *OllamaServer) handleChat(w http.ResponseWriter, r *http.Request) {
+	var req ollama.ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid request"}`, http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.chatCalls = append(s.chatCalls, req)
+	c := s.next()
+	s.mu.Unlock()
+
+	var prompt strings.Builder
+	for _, m := range req.Messages {
+		prompt.WriteString(m.Content)
+		prompt.WriteString(" ")
+	}
+
+	s.writeCompletion(w, r, c, prompt.String(), req.Stream, func(text string, done bool) interface{} {
+		return ollama.ChatResponse{
+			Model:   req.Model,
+			Message: ollama.Message{Role: "assistant", Content: text},
+			Done:    done,
+		}
+	})
+}
+
+// writeCompletion writes a scripted completion in either the /api/generate or
+// /api/chat shape. chunk builds the per-endpoint body; metrics are filled in
+// on the final (done) object.
+func (s *OllamaServer) writeCompletion(w http.ResponseWriter, r *http.Request, c Completion, prompt string, stream bool, chunk func(text string, done bool) interface{}) {
+	if !sleepCtx(r, c.Delay) {
+		return
+	}
+
+	if c.Status != 0 && c.Status != http.StatusOK {
+		w.WriteHeader(c.Status)
+		_, _ = w.Write([]byte(`{"error":"scripted failure"}`))
+		return
+	}
+
+	promptTokens := c.PromptEvalCount
+	if promptTokens == 0 {
+		promptTokens = len(strings.Fields(prompt))
+	}
+	evalTokens := c.EvalCount
+	if evalTokens == 0 {
+		evalTokens = len(strings.Fields(c.Response))
+	}
+
+	final := func(text string) interface{} {
+		metrics := struct {
+			TotalDuration      int64 `json:"total_duration"`
+			PromptEvalCount    int   `json:"prompt_eval_count"`
+			PromptEvalDuration int64 `json:"prompt_eval_duration"`
+			EvalCount          int   `json:"eval_count"`
+			EvalDuration       int64 `json:"eval_duration"`
+		}{
+			TotalDuration:      int64(c.Delay) + int64(time.Millisecond),
+			PromptEvalCount:    promptTokens,
+			PromptEvalDuration: int64(time.Millisecond),
+			EvalCount:          evalTokens,
+			EvalDuration:       int64(time.Millisecond),
+		}
+		return mergeJSON(chunk(text, true), metrics)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !stream {
+		_ = json.NewEncoder(w).Encode(final(c.Response))
+		return
+	}
+
+	// Streaming: one NDJSON object per word, then a final done=true object with metrics
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	for _, word := range strings.SplitAfter(c.Response, " ") {
+		if word == "" {
+			continue
+		}
+		_ = enc.Encode(chunk(word, false))
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	_ = enc.Encode(final(""))
+}
+
+// mergeJSON combines the fields of two JSON-encodable structs into one object.
+func mergeJSON(a, b interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	for _, v := range []interface{}{a, b} {
+		data, _ := json.Marshal(v)
+		_ = json.Unmarshal(data, &out)
+	}
+	return out
+}
+
+func (s *OllamaServer) handleEmbed(w http.ResponseWriter, r *http.Request) {
+	var req ollama.EmbedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid request"}`, http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.embedCalls = append(s.embedCalls, req)
+	status := s.embedStatus
+	delay := s.embedDelay
+	dim := s.EmbeddingDim
+	s.mu.Unlock()
+
+	if !sleepCtx(r, delay) {
+		return
+	}
+
+	if status != 0 && status != http.StatusOK {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"error":"scripted failure"}`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(ollama.EmbedResponse{
+		Embeddings: [][]float32{HashEmbedding(req.Input, dim)},
+	})
+}
+
+// sleepCtx waits for d or until the client gives up. It returns false if the
+// request was cancelled, in which case nothing should be written.
+func sleepCtx(r *http.Request, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	select {
+	case <-time.After(d):
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
@@ -0,0 +1,141 @@
+package testutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"living-lands-bot/pkg/openai"
+)
+
+// OpenAIServer is an httptest-based fake of an OpenAI-compatible server
+// (/v1/chat/completions and /v1/embeddings). It shares the Completion script
+// format with OllamaServer.
+type OpenAIServer struct {
+	*httptest.Server
+
+	// EmbeddingDim controls the size of vectors returned by /v1/embeddings.
+	EmbeddingDim int
+
+	mu        sync.Mutex
+	script    []Completion
+	chatCalls []openai.ChatCompletionRequest
+	authCalls []string
+}
+
+// NewOpenAIServer starts a fake OpenAI-compatible server that is closed when the test ends.
+// Use URL + "/v1" as the client base URL.
+func NewOpenAIServer(t testing.TB) *OpenAIServer {
+	t.Helper()
+
+	s := &OpenAIServer{EmbeddingDim: DefaultEmbeddingDim}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/chat/completions", s.handleChat)
+	mux.HandleFunc("POST /v1/embeddings", s.handleEmbeddings)
+
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Script appends completions to the response queue.
+func (s *OpenAIServer) Script(completions ...Completion) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.script = append(s.script, completions...)
+}
+
+// ChatRequests returns a copy of all chat completion requests received so far.
+func (s *OpenAIServer) ChatRequests() []openai.ChatCompletionRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]openai.ChatCompletionRequest(nil), s.chatCalls...)
+}
+
+// AuthorizationHeaders returns the Authorization header of every request received.
+func (s *OpenAIServer) AuthorizationHeaders() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.authCalls...)
+}
+
+func (s *OpenAIServer) handleChat(w http.ResponseWriter, r *http.Request) {
+	var req openai.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+		return
+	}
+
+	s.mu.Lock()
+	s.chatCalls = append(s.chatCalls, req)
+	s.authCalls = append(s.authCalls, r.Header.Get("Authorization"))
+	c := Completion{Response: DefaultCompletion}
+	if len(s.script) > 0 {
+		c = s.script[0]
+		s.script = s.script[1:]
+	}
+	s.mu.Unlock()
+
+	if !sleepCtx(r, c.Delay) {
+		return
+	}
+	if c.Status != 0 && c.Status != http.StatusOK {
+		writeJSON(w, c.Status, map[string]interface{}{"error": map[string]string{"message": "scripted failure"}})
+		return
+	}
+
+	promptTokens := c.PromptEvalCount
+	if promptTokens == 0 {
+		for _, m := range req.Messages {
+			promptTokens += len(strings.Fields(m.Content))
+		}
+	}
+	completionTokens := c.EvalCount
+	if completionTokens == 0 {
+		completionTokens = len(strings.Fields(c.Response))
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":     "chatcmpl-fake",
+		"object": "chat.completion",
+		"model":  req.Model,
+		"choices": []map[string]interface{}{{
+			"index":         0,
+			"message":       map[string]string{"role": "assistant", "content": c.Response},
+			"finish_reason": "stop",
+		}},
+		"usage": map[string]int{
+			"prompt_tokens":     promptTokens,
+			"completion_tokens": completionTokens,
+			"total_tokens":      promptTokens + completionTokens,
+		},
+	})
+}
+
+func (s *OpenAIServer) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	var req openai.EmbeddingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+		return
+	}
+
+	s.mu.Lock()
+	s.authCalls = append(s.authCalls, r.Header.Get("Authorization"))
+	dim := s.EmbeddingDim
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"object": "list",
+		"model":  req.Model,
+		"data": []map[string]interface{}{{
+			"object":    "embedding",
+			"index":     0,
+			"embedding": HashEmbedding(req.Input, dim),
+		}},
+		"usage": map[string]int{"prompt_tokens": len(strings.Fields(req.Input))},
+	})
+}
@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+func TestSetupStdoutExporter(t *testing.T) {
+	var buf bytes.Buffer
+	shutdown, err := Setup(context.Background(), Config{
+		Exporter:    ExporterStdout,
+		SampleRatio: 1,
+		Writer:      &buf,
+	}, getTestLogger())
+	require.NoError(t, err)
+
+	_, span := Tracer().Start(context.Background(), "test.span")
+	span.End()
+
+	// Shutdown flushes the batcher
+	require.NoError(t, shutdown(context.Background()))
+	assert.Contains(t, buf.String(), `"Name":"test.span"`)
+	assert.Contains(t, buf.String(), ServiceName)
+}
+
+func TestSetupDisabled(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterNone}, getTestLogger())
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestSetupUnknownExporter(t *testing.T) {
+	_, err := Setup(context.Background(), Config{Exporter: "zipkin"}, getTestLogger())
+	assert.Error(t, err)
+}
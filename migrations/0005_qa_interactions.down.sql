@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS qa_feedback;
+DROP TABLE IF EXISTS qa_interactions;
@@ -0,0 +1,33 @@
+-- Q&A log: every /ask answer with the context it was built from, plus user
+-- feedback on answer quality. Used for tuning the RAG relevance threshold,
+-- spotting documentation gaps and building an evaluation set.
+CREATE TABLE IF NOT EXISTS qa_interactions (
+    id BIGSERIAL PRIMARY KEY,
+    interaction_id VARCHAR(32),
+    discord_id VARCHAR(20),
+    discord_username VARCHAR(64),
+    guild_id VARCHAR(20),
+    channel_id VARCHAR(20),
+    question TEXT NOT NULL,
+    intent VARCHAR(32) NOT NULL,
+    mode VARCHAR(16) NOT NULL,
+    retrieved JSONB NOT NULL DEFAULT '[]',
+    answer TEXT NOT NULL,
+    model VARCHAR(128),
+    latency_ms INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_qa_interactions_created_at ON qa_interactions (created_at);
+CREATE INDEX IF NOT EXISTS idx_qa_interactions_discord_id ON qa_interactions (discord_id);
+
+CREATE TABLE IF NOT EXISTS qa_feedback (
+    id BIGSERIAL PRIMARY KEY,
+    qa_interaction_id BIGINT NOT NULL REFERENCES qa_interactions (id) ON DELETE CASCADE,
+    discord_id VARCHAR(20) NOT NULL,
+    rating VARCHAR(16) NOT NULL CHECK (rating IN ('up', 'down', 'report')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (qa_interaction_id, discord_id, rating)
+);
+
+CREATE INDEX IF NOT EXISTS idx_qa_feedback_rating ON qa_feedback (rating);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS gap_digests;
+DROP TABLE IF EXISTS doc_gaps;
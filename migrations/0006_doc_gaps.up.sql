@@ -0,0 +1,24 @@
+-- Documentation gaps: /ask questions the archives could not answer, either
+-- because RAG found no relevant document or because the model said so.
+-- Clustered by question embedding for the staff digest and `./bot gaps`.
+CREATE TABLE IF NOT EXISTS doc_gaps (
+    id BIGSERIAL PRIMARY KEY,
+    interaction_id VARCHAR(32),
+    discord_id VARCHAR(20),
+    guild_id VARCHAR(20),
+    question TEXT NOT NULL,
+    reason VARCHAR(16) NOT NULL CHECK (reason IN ('no_context', 'no_answer')),
+    embedding JSONB,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_doc_gaps_created_at ON doc_gaps (created_at);
+
+-- One row per posted digest so restarts do not repost or skip a week.
+CREATE TABLE IF NOT EXISTS gap_digests (
+    id BIGSERIAL PRIMARY KEY,
+    channel_id VARCHAR(20) NOT NULL,
+    posted_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_gap_digests_channel_posted ON gap_digests (channel_id, posted_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS faq_variants;
+DROP TABLE IF EXISTS faq_entries;
@@ -0,0 +1,20 @@
+-- Curated FAQ answers returned verbatim to /ask, bypassing RAG and the LLM.
+-- Each entry has one or more question variants; an /ask question that is
+-- close enough (by embedding similarity) to any variant gets the answer.
+CREATE TABLE IF NOT EXISTS faq_entries (
+    id BIGSERIAL PRIMARY KEY,
+    question TEXT NOT NULL,
+    answer TEXT NOT NULL,
+    created_by VARCHAR(20),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS faq_variants (
+    id BIGSERIAL PRIMARY KEY,
+    faq_entry_id BIGINT NOT NULL REFERENCES faq_entries (id) ON DELETE CASCADE,
+    text TEXT NOT NULL,
+    embedding JSONB
+);
+
+CREATE INDEX IF NOT EXISTS idx_faq_variants_entry ON faq_variants (faq_entry_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS channel_settings;
+DROP TABLE IF EXISTS guild_settings;
@@ -0,0 +1,30 @@
+-- Per-guild and per-channel overrides of the global bot configuration.
+-- NULL columns inherit: a channel from its guild, a guild from the global
+-- config. Edited with the /settings admin command.
+CREATE TABLE IF NOT EXISTS guild_settings (
+    guild_id VARCHAR(20) PRIMARY KEY,
+    ask_enabled BOOLEAN,
+    ephemeral_answers BOOLEAN,
+    personality VARCHAR(100),
+    rag_collection VARCHAR(100),
+    rate_limit_per_min INTEGER,
+    welcome_channel_id VARCHAR(20),
+    updated_by VARCHAR(20),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS channel_settings (
+    channel_id VARCHAR(20) PRIMARY KEY,
+    guild_id VARCHAR(20) NOT NULL,
+    ask_enabled BOOLEAN,
+    ephemeral_answers BOOLEAN,
+    personality VARCHAR(100),
+    rag_collection VARCHAR(100),
+    rate_limit_per_min INTEGER,
+    updated_by VARCHAR(20),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_channel_settings_guild ON channel_settings (guild_id);
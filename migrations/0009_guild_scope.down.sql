@@ -0,0 +1,12 @@
+-- Fails if a Discord user or keyword exists in more than one guild; remove
+-- the rows of the other guilds first.
+DROP INDEX IF EXISTS idx_welcome_templates_guild;
+ALTER TABLE welcome_templates DROP COLUMN IF EXISTS guild_id;
+
+DROP INDEX IF EXISTS idx_channel_routes_guild_keyword;
+ALTER TABLE channel_routes ADD CONSTRAINT channel_routes_keyword_key UNIQUE (keyword);
+ALTER TABLE channel_routes DROP COLUMN IF EXISTS guild_id;
+
+DROP INDEX IF EXISTS idx_users_guild_discord;
+ALTER TABLE users ADD CONSTRAINT users_discord_id_unique UNIQUE (discord_id);
+ALTER TABLE users DROP COLUMN IF EXISTS guild_id;
@@ -0,0 +1,15 @@
+-- Scope users, channel routes and welcome templates by guild so one bot can
+-- serve several servers. Existing rows get an empty guild_id, which
+-- `./bot migrate` backfills with DISCORD_GUILD_ID (the server the bot served
+-- until now).
+ALTER TABLE users ADD COLUMN IF NOT EXISTS guild_id VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_discord_id_unique;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_discord_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_guild_discord ON users (guild_id, discord_id);
+
+ALTER TABLE channel_routes ADD COLUMN IF NOT EXISTS guild_id VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE channel_routes DROP CONSTRAINT IF EXISTS channel_routes_keyword_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_channel_routes_guild_keyword ON channel_routes (guild_id, keyword);
+
+ALTER TABLE welcome_templates ADD COLUMN IF NOT EXISTS guild_id VARCHAR(20) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_welcome_templates_guild ON welcome_templates (guild_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_qa_interactions_guild;
+DROP INDEX IF EXISTS idx_doc_gaps_guild_created_at;
+
+DROP INDEX IF EXISTS idx_faq_entries_guild;
+ALTER TABLE faq_entries DROP COLUMN IF EXISTS guild_id;
@@ -0,0 +1,9 @@
+-- Scope curated FAQ answers by guild, and index the guild of gaps and
+-- answered questions so the reports and feedback can filter by it. Existing
+-- FAQ entries get an empty guild_id, which `./bot migrate` backfills with
+-- DISCORD_GUILD_ID.
+ALTER TABLE faq_entries ADD COLUMN IF NOT EXISTS guild_id VARCHAR(20) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_faq_entries_guild ON faq_entries (guild_id);
+
+CREATE INDEX IF NOT EXISTS idx_doc_gaps_guild_created_at ON doc_gaps (guild_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_qa_interactions_guild ON qa_interactions (guild_id);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_qa_feedback_vote;
+ALTER TABLE qa_feedback DROP CONSTRAINT IF EXISTS qa_feedback_rating_check;
+ALTER TABLE qa_feedback ADD CONSTRAINT qa_feedback_rating_check CHECK (rating IN ('up', 'down', 'report'));
+ALTER TABLE qa_feedback ADD CONSTRAINT qa_feedback_qa_interaction_id_discord_id_rating_key UNIQUE (qa_interaction_id, discord_id, rating);
+
+INSERT INTO qa_feedback (qa_interaction_id, discord_id, rating, created_at)
+SELECT qa_interaction_id, discord_id, 'report', created_at FROM qa_reports;
+DROP TABLE IF EXISTS qa_reports;
@@ -0,0 +1,26 @@
+-- One up/down vote per user and answer: a new vote replaces the old one.
+-- Reports move to their own table so reporting an answer neither takes the
+-- place of a vote nor counts as one.
+CREATE TABLE IF NOT EXISTS qa_reports (
+    id BIGSERIAL PRIMARY KEY,
+    qa_interaction_id BIGINT NOT NULL REFERENCES qa_interactions (id) ON DELETE CASCADE,
+    discord_id VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (qa_interaction_id, discord_id)
+);
+
+INSERT INTO qa_reports (qa_interaction_id, discord_id, created_at)
+SELECT qa_interaction_id, discord_id, created_at FROM qa_feedback WHERE rating = 'report'
+ON CONFLICT DO NOTHING;
+DELETE FROM qa_feedback WHERE rating = 'report';
+
+-- Keep only the latest vote of a user who voted both ways
+DELETE FROM qa_feedback older USING qa_feedback newer
+WHERE older.qa_interaction_id = newer.qa_interaction_id
+  AND older.discord_id = newer.discord_id
+  AND (older.created_at, older.id) < (newer.created_at, newer.id);
+
+ALTER TABLE qa_feedback DROP CONSTRAINT IF EXISTS qa_feedback_qa_interaction_id_discord_id_rating_key;
+ALTER TABLE qa_feedback DROP CONSTRAINT IF EXISTS qa_feedback_rating_check;
+ALTER TABLE qa_feedback ADD CONSTRAINT qa_feedback_rating_check CHECK (rating IN ('up', 'down'));
+CREATE UNIQUE INDEX IF NOT EXISTS idx_qa_feedback_vote ON qa_feedback (qa_interaction_id, discord_id);
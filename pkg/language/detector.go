@@ -1,160 +1,367 @@
+// Package language detects the language of short texts such as chat
+// questions. The writing system narrows the candidates (Hangul is Korean,
+// kana is Japanese, other Han text is Chinese); Latin and Cyrillic text is
+// scored against character trigram profiles built from the sample texts in
+// profiles/.
 package language
 
 import (
+	"embed"
+	"fmt"
+	"math"
+	"path"
+	"sort"
 	"strings"
+	"unicode"
 )
 
 // Language represents a detected language.
 type Language string
 
 const (
-	English  Language = "English"
-	German   Language = "German"
-	French   Language = "French"
-	Spanish  Language = "Spanish"
-	Italian  Language = "Italian"
-	Dutch    Language = "Dutch"
-	Russian  Language = "Russian"
-	Japanese Language = "Japanese"
-	Chinese  Language = "Chinese"
-	Korean   Language = "Korean"
-	Unknown  Language = "Unknown"
+	English    Language = "English"
+	German     Language = "German"
+	French     Language = "French"
+	Spanish    Language = "Spanish"
+	Italian    Language = "Italian"
+	Dutch      Language = "Dutch"
+	Portuguese Language = "Portuguese"
+	Polish     Language = "Polish"
+	Russian    Language = "Russian"
+	Ukrainian  Language = "Ukrainian"
+	Japanese   Language = "Japanese"
+	Chinese    Language = "Chinese"
+	Korean     Language = "Korean"
+	Unknown    Language = "Unknown"
 )
 
-// commonWords maps language to common words for detection.
-var commonWords = map[Language][]string{
-	English:  {"the", "is", "and", "to", "a", "of", "in", "that", "it", "for", "with", "you", "this", "be"},
-	German:   {"der", "die", "und", "in", "den", "von", "zu", "das", "mit", "sich", "des", "auf", "für", "ist"},
-	French:   {"le", "de", "un", "et", "à", "être", "en", "que", "pour", "dans", "ce", "il", "qui", "ne"},
-	Spanish:  {"de", "la", "que", "el", "en", "y", "a", "los", "se", "del", "las", "un", "por", "con"},
-	Italian:  {"il", "di", "da", "un", "è", "per", "e", "la", "che", "a", "in", "con", "si", "lo"},
-	Dutch:    {"de", "en", "van", "het", "een", "die", "in", "te", "aan", "op", "dat", "er", "voor", "met"},
-	Russian:  {"и", "в", "то", "что", "он", "на", "я", "с", "со", "а", "то", "все", "она", "так"},
-	Japanese: {"の", "に", "は", "を", "た", "が", "で", "て", "と", "し", "れ", "さ", "ある", "いる"},
-	Chinese:  {"的", "一", "是", "在", "不", "了", "有", "和", "人", "这", "中", "大", "为", "上"},
-	Korean:   {"이", "그", "저", "것", "수", "등", "나", "우리", "저희", "따라", "의해", "에", "과", "또"},
+// MinConfidence is the Detect confidence below which a detection is little
+// better than a guess, as is common for questions of one or two words.
+// Callers should use their default language instead.
+const MinConfidence = 50
+
+// Candidate is a possible language of a text with its calibrated score, the
+// estimated probability that the text is in that language.
+type Candidate struct {
+	Language Language
+	Score    float64
 }
 
-// Detect detects the language of the given text.
-// Returns the detected language and confidence (0-100).
-func Detect(text string) (Language, int) {
-	if text == "" {
-		return Unknown, 0
-	}
+// script is a writing system that narrows down the candidate languages.
+type script int
 
-	text = strings.ToLower(strings.TrimSpace(text))
+const (
+	scriptOther script = iota
+	scriptLatin
+	scriptCyrillic
+	scriptHangul
+	scriptKana
+	scriptHan
+)
 
-	// Check for CJK characters (Japanese, Chinese, Korean)
-	if containsCJK(text) {
-		if containsJapanese(text) {
-			return Japanese, 85
-		}
-		if containsChinese(text) {
-			return Chinese, 85
-		}
-		if containsKorean(text) {
-			return Korean, 85
-		}
+// profileCodes maps profile file names (ISO 639-1) to languages and scripts.
+var profileCodes = map[string]struct {
+	lang   Language
+	script script
+}{
+	"en": {English, scriptLatin},
+	"de": {German, scriptLatin},
+	"fr": {French, scriptLatin},
+	"es": {Spanish, scriptLatin},
+	"it": {Italian, scriptLatin},
+	"nl": {Dutch, scriptLatin},
+	"pt": {Portuguese, scriptLatin},
+	"pl": {Polish, scriptLatin},
+	"ru": {Russian, scriptCyrillic},
+	"uk": {Ukrainian, scriptCyrillic},
+}
+
+const (
+	// smoothing is the pseudo-count added to every trigram (additive smoothing).
+	smoothing = 0.5
+	// temperature flattens trigram log-likelihoods before normalising them.
+	// Trigrams overlap and are far from independent, so raw naive Bayes
+	// posteriors are overconfident; this value was tuned on
+	// testdata/tuning.yaml so that scores track accuracy, and is checked
+	// against the held-out testdata/heldout.yaml.
+	temperature = 4.0
+)
+
+// profile holds the trigram log-probabilities of one language.
+type profile struct {
+	lang    Language
+	logProb map[string]float64
+	unseen  float64 // Log-probability of a trigram missing from the samples
+}
+
+//go:embed profiles/*.txt
+var profileFiles embed.FS
+
+// profiles holds the trigram profiles per script, built at init.
+var profiles = mustLoadProfiles()
+
+func mustLoadProfiles() map[script][]*profile {
+	p, err := loadProfiles()
+	if err != nil {
+		panic(err)
 	}
+	return p
+}
 
-	// Check for Cyrillic (Russian)
-	if containsCyrillic(text) {
-		return Russian, 80
+func loadProfiles() (map[script][]*profile, error) {
+	entries, err := profileFiles.ReadDir("profiles")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read language profiles: %w", err)
 	}
 
-	// Match common words for other languages
-	scores := make(map[Language]int)
-	words := strings.Fields(text)
+	counts := make(map[script]map[Language]map[string]int)
+	for _, entry := range entries {
+		code := strings.TrimSuffix(entry.Name(), ".txt")
+		info, ok := profileCodes[code]
+		if !ok {
+			return nil, fmt.Errorf("unknown language profile %s", entry.Name())
+		}
+		data, err := profileFiles.ReadFile(path.Join("profiles", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read language profile %s: %w", code, err)
+		}
 
-	for lang, commonWordList := range commonWords {
-		count := 0
-		for _, word := range words {
-			// Remove punctuation
-			word = strings.TrimFunc(word, func(r rune) bool {
-				return !('a' <= r && r <= 'z') && !('0' <= r && r <= '9')
-			})
+		c := make(map[string]int)
+		forEachTrigram(string(data), info.script, func(t string) { c[t]++ })
+		if counts[info.script] == nil {
+			counts[info.script] = make(map[Language]map[string]int)
+		}
+		counts[info.script][info.lang] = c
+	}
 
-			for _, common := range commonWordList {
-				if word == common {
-					count++
-					break
-				}
+	result := make(map[script][]*profile, len(counts))
+	for s, byLang := range counts {
+		// Every language in a script shares one vocabulary so unseen
+		// trigrams are penalised consistently
+		vocabulary := make(map[string]bool)
+		for _, c := range byLang {
+			for t := range c {
+				vocabulary[t] = true
 			}
 		}
-		if count > 0 {
-			scores[lang] = (count * 100) / len(commonWordList)
+
+		for lang, c := range byLang {
+			total := 0
+			for _, n := range c {
+				total += n
+			}
+			denominator := float64(total) + smoothing*float64(len(vocabulary)+1)
+			p := &profile{
+				lang:    lang,
+				logProb: make(map[string]float64, len(c)),
+				unseen:  math.Log(smoothing / denominator),
+			}
+			for t, n := range c {
+				p.logProb[t] = math.Log((float64(n) + smoothing) / denominator)
+			}
+			result[s] = append(result[s], p)
 		}
+		sort.Slice(result[s], func(a, b int) bool { return result[s][a].lang < result[s][b].lang })
 	}
+	return result, nil
+}
 
-	// Find best match
-	bestLang := Unknown
-	bestScore := 0
-	for lang, score := range scores {
-		if score > bestScore {
-			bestScore = score
-			bestLang = lang
+// runeScript returns the writing system of a letter.
+func runeScript(r rune) script {
+	switch {
+	case unicode.Is(unicode.Latin, r):
+		return scriptLatin
+	case unicode.Is(unicode.Cyrillic, r):
+		return scriptCyrillic
+	case unicode.Is(unicode.Hangul, r):
+		return scriptHangul
+	case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+		return scriptKana
+	case unicode.Is(unicode.Han, r):
+		return scriptHan
+	default:
+		return scriptOther
+	}
+}
+
+// forEachTrigram calls fn for every character trigram of the words written
+// in script s. Words are lowercased and padded with a space on each side so
+// that prefixes and suffixes get trigrams of their own.
+func forEachTrigram(text string, s script, fn func(string)) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) || runeScript(r) != s
+	})
+	for _, word := range words {
+		runes := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			fn(string(runes[i : i+3]))
 		}
 	}
+}
 
-	if bestScore == 0 {
-		return English, 50 // Default to English if no match
+// scoreScript returns the posterior of each language of script s given the
+// trigrams of text, normalised to sum to 1.
+func scoreScript(text string, s script) map[Language]float64 {
+	candidates := profiles[s]
+	if len(candidates) == 0 {
+		return nil
 	}
 
-	return bestLang, bestScore
+	logLikelihood := make([]float64, len(candidates))
+	forEachTrigram(text, s, func(t string) {
+		for i, p := range candidates {
+			if lp, ok := p.logProb[t]; ok {
+				logLikelihood[i] += lp
+			} else {
+				logLikelihood[i] += p.unseen
+			}
+		}
+	})
+
+	best := math.Inf(-1)
+	for _, ll := range logLikelihood {
+		best = math.Max(best, ll)
+	}
+	var sum float64
+	weights := make([]float64, len(candidates))
+	for i, ll := range logLikelihood {
+		weights[i] = math.Exp((ll - best) / temperature)
+		sum += weights[i]
+	}
+
+	posterior := make(map[Language]float64, len(candidates))
+	for i, p := range candidates {
+		posterior[p.lang] = weights[i] / sum
+	}
+	return posterior
 }
 
-// containsCJK checks if text contains CJK characters.
-func containsCJK(text string) bool {
+// maxNeutralWord is the longest Latin word, in letters, that is ignored in
+// text written mostly in another script. Such words are usually names
+// ("Hytale", "CurseForge") and say nothing about the question's language.
+const maxNeutralWord = 10
+
+// countLetters returns the number of letters and of words in text per
+// writing system. A word is a run of letters in one script, ending at any
+// non-letter or change of script, since Japanese and Chinese write names
+// without spaces around them. When skipLatin is set, Latin words of up to
+// maxNeutralWord letters are not counted.
+func countLetters(text string, skipLatin bool) (letters, words map[script]int) {
+	letters, words = make(map[script]int), make(map[script]int)
+	run, runScript := 0, scriptOther
+	flush := func() {
+		if run > 0 && !(skipLatin && runScript == scriptLatin && run <= maxNeutralWord) {
+			letters[runScript] += run
+			words[runScript]++
+		}
+		run = 0
+	}
 	for _, r := range text {
-		if (r >= 0x4E00 && r <= 0x9FFF) || // CJK Unified Ideographs
-			(r >= 0x3040 && r <= 0x309F) || // Hiragana
-			(r >= 0x30A0 && r <= 0x30FF) || // Katakana
-			(r >= 0xAC00 && r <= 0xD7AF) { // Hangul
-			return true
+		if !unicode.IsLetter(r) {
+			flush()
+			continue
+		}
+		if s := runeScript(r); s != runScript {
+			flush()
+			runScript = s
 		}
+		run++
 	}
-	return false
+	flush()
+	return letters, words
 }
 
-// containsJapanese checks if text contains Japanese hiragana/katakana.
-func containsJapanese(text string) bool {
-	for _, r := range text {
-		if (r >= 0x3040 && r <= 0x309F) || (r >= 0x30A0 && r <= 0x30FF) {
-			return true
+// dominantScript returns the writing system most words of the text are
+// written in, by letters on a tie. Kana and Han count together, as Japanese
+// mixes the two.
+func dominantScript(letters, words map[script]int) script {
+	best, bestWords, bestLetters := scriptOther, 0, 0
+	for _, s := range []script{scriptLatin, scriptCyrillic, scriptHangul, scriptKana, scriptHan} {
+		w, n := words[s], letters[s]
+		if s == scriptKana && n > 0 {
+			w, n = w+words[scriptHan], n+letters[scriptHan]
+		}
+		if w > bestWords || (w == bestWords && n > bestLetters) {
+			best, bestWords, bestLetters = s, w, n
 		}
 	}
-	return false
+	return best
 }
 
-// containsChinese checks if text contains Chinese characters.
-func containsChinese(text string) bool {
-	for _, r := range text {
-		if r >= 0x4E00 && r <= 0x9FFF {
-			return true
+// DetectTop returns up to n candidate languages for text, best first. The
+// languages of the dominant writing system are scored by their trigram
+// posterior, the estimated probability that the text is in that language;
+// languages of other scripts in the text follow, weighted by their share of
+// the letters. Short Latin words such as product names are ignored in text
+// written mostly in another script, so "как привязать аккаунт к Hytale?" is
+// scored as Cyrillic text. It returns nil for text without letters; n <= 0
+// returns every candidate.
+func DetectTop(text string, n int) []Candidate {
+	letters, words := countLetters(text, false)
+	dominant := dominantScript(letters, words)
+	if dominant == scriptOther {
+		return nil
+	}
+	if dominant != scriptLatin {
+		letters, _ = countLetters(text, true)
+	}
+	total := 0
+	for _, count := range letters {
+		total += count
+	}
+
+	// weight is 1 for the dominant script and the letter share for others
+	weight := func(s script) float64 {
+		if s == dominant || (dominant == scriptKana && s == scriptHan) {
+			return 1
 		}
+		return float64(letters[s]) / float64(total)
 	}
-	return false
-}
 
-// containsKorean checks if text contains Korean Hangul.
-func containsKorean(text string) bool {
-	for _, r := range text {
-		if r >= 0xAC00 && r <= 0xD7AF {
-			return true
+	scores := make(map[Language]float64)
+	for _, s := range []script{scriptLatin, scriptCyrillic} {
+		if letters[s] == 0 {
+			continue
+		}
+		for lang, p := range scoreScript(text, s) {
+			scores[lang] += weight(s) * p
 		}
 	}
-	return false
-}
+	if letters[scriptHangul] > 0 {
+		scores[Korean] += weight(scriptHangul)
+	}
+	// Japanese mixes kanji with kana; Han characters alone are Chinese
+	if letters[scriptKana] > 0 {
+		scores[Japanese] += math.Min(1, weight(scriptKana)+weight(scriptHan))
+	} else if letters[scriptHan] > 0 {
+		scores[Chinese] += weight(scriptHan)
+	}
 
-// containsCyrillic checks if text contains Cyrillic characters.
-func containsCyrillic(text string) bool {
-	for _, r := range text {
-		if (r >= 0x0400 && r <= 0x04FF) || // Cyrillic
-			(r >= 0x0500 && r <= 0x052F) { // Cyrillic Supplement
-			return true
+	candidates := make([]Candidate, 0, len(scores))
+	for lang, score := range scores {
+		candidates = append(candidates, Candidate{Language: lang, Score: score})
+	}
+	sort.Slice(candidates, func(a, b int) bool {
+		if candidates[a].Score != candidates[b].Score {
+			return candidates[a].Score > candidates[b].Score
 		}
+		return candidates[a].Language < candidates[b].Language
+	})
+	if n > 0 && len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	return candidates
+}
+
+// Detect detects the language of the given text.
+// Returns the detected language and confidence (0-100), or Unknown and 0 for
+// text without letters.
+func Detect(text string) (Language, int) {
+	top := DetectTop(text, 1)
+	if len(top) == 0 {
+		return Unknown, 0
 	}
-	return false
+	return top[0].Language, int(math.Round(top[0].Score * 100))
 }
 
 // String returns the string representation of the language.
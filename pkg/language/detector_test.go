@@ -0,0 +1,166 @@
+package language
+
+import (
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+var benchmarkCodes = map[string]Language{
+	"en": English, "de": German, "fr": French, "es": Spanish, "it": Italian,
+	"nl": Dutch, "pt": Portuguese, "pl": Polish, "ru": Russian, "uk": Ukrainian,
+	"ja": Japanese, "zh": Chinese, "ko": Korean,
+}
+
+type benchmarkCase struct {
+	text string
+	want Language
+}
+
+// loadBenchmark reads a labelled question set from testdata: tuning.yaml,
+// which the detector's constants were tuned on, or heldout.yaml.
+func loadBenchmark(tb testing.TB, name string) []benchmarkCase {
+	tb.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	require.NoError(tb, err)
+	var raw map[string][]string
+	require.NoError(tb, yaml.Unmarshal(data, &raw))
+
+	var cases []benchmarkCase
+	for code, texts := range raw {
+		lang, ok := benchmarkCodes[code]
+		require.True(tb, ok, "unknown benchmark language %s", code)
+		for _, text := range texts {
+			cases = append(cases, benchmarkCase{text: text, want: lang})
+		}
+	}
+	sort.Slice(cases, func(a, b int) bool { return cases[a].text < cases[b].text })
+	return cases
+}
+
+func TestDetectBenchmark(t *testing.T) {
+	// Assert on the held-out set only: scores on the tuning set overstate
+	// how well the tuned temperature generalises
+	cases := loadBenchmark(t, "heldout.yaml")
+
+	correct := make(map[Language]int)
+	total := make(map[Language]int)
+	var confident, confidentCorrect int
+	for _, c := range cases {
+		top := DetectTop(c.text, 1)
+		require.NotEmpty(t, top, c.text)
+		total[c.want]++
+		if top[0].Language == c.want {
+			correct[c.want]++
+		} else {
+			t.Logf("misdetected %q: got %s (%.2f), want %s", c.text, top[0].Language, top[0].Score, c.want)
+		}
+		if top[0].Score >= 0.8 {
+			confident++
+			if top[0].Language == c.want {
+				confidentCorrect++
+			}
+		}
+	}
+
+	var allCorrect int
+	for lang, n := range total {
+		accuracy := float64(correct[lang]) / float64(n)
+		t.Logf("%-10s accuracy %.2f (%d/%d)", lang, accuracy, correct[lang], n)
+		assert.GreaterOrEqual(t, accuracy, 0.75, "accuracy for %s", lang)
+		allCorrect += correct[lang]
+	}
+	overall := float64(allCorrect) / float64(len(cases))
+	t.Logf("overall accuracy %.2f (%d/%d)", overall, allCorrect, len(cases))
+	assert.GreaterOrEqual(t, overall, 0.9)
+
+	// Calibration: detections scored 0.8 or more should be right at least that often
+	require.NotZero(t, confident)
+	t.Logf("confident (>= 0.8) %d, accuracy %.2f", confident, float64(confidentCorrect)/float64(confident))
+	assert.GreaterOrEqual(t, float64(confidentCorrect)/float64(confident), 0.9)
+}
+
+func TestDetectTop(t *testing.T) {
+	top := DetectTop("¿dónde puedo descargar el mod para el servidor?", 3)
+	require.Len(t, top, 3)
+	assert.Equal(t, Spanish, top[0].Language)
+	assert.Greater(t, top[0].Score, top[1].Score)
+	assert.GreaterOrEqual(t, top[1].Score, top[2].Score)
+
+	var sum float64
+	for _, c := range DetectTop("¿dónde puedo descargar el mod para el servidor?", 0) {
+		sum += c.Score
+	}
+	assert.InDelta(t, 1.0, sum, 1e-9, "scores over all candidates sum to 1")
+
+	// Writing systems are shared by letter count
+	mixed := DetectTop("how do I install 模组", 0)
+	require.Len(t, mixed, len(profiles[scriptLatin])+1)
+	assert.Equal(t, English, mixed[0].Language)
+	for _, c := range mixed {
+		if c.Language == Chinese {
+			assert.InDelta(t, 2.0/15.0, c.Score, 1e-9)
+		}
+	}
+
+	// Product names don't dilute the script they are quoted in
+	for text, want := range map[string]Language{
+		"как привязать аккаунт к Hytale?": Russian,
+		"CurseForgeからmodをダウンロードするには?":     Japanese,
+		"Hytale 계정은 어떻게 연결하나요?":           Korean,
+		"what does слово mean?":           English,
+	} {
+		lang, confidence := Detect(text)
+		assert.Equal(t, want, lang, text)
+		assert.GreaterOrEqual(t, confidence, MinConfidence, text)
+	}
+
+	assert.Nil(t, DetectTop("", 3))
+	assert.Nil(t, DetectTop("123 !!! 👍", 3))
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text string
+		want Language
+	}{
+		{"How does the metabolism system work?", English},
+		{"Wie funktioniert das Stoffwechselsystem?", German},
+		{"Comment fonctionne le système de métabolisme ?", French},
+		{"¿Cómo funciona el sistema de metabolismo?", Spanish},
+		{"Come funziona il sistema del metabolismo?", Italian},
+		{"Hoe werkt het metabolismesysteem?", Dutch},
+		{"Não entendi como funciona o sistema de metabolismo", Portuguese},
+		{"Jak działa system metabolizmu?", Polish},
+		{"Как работает система метаболизма?", Russian},
+		{"Як працює система метаболізму?", Ukrainian},
+		{"代謝システムはどう動きますか?", Japanese},
+		{"代谢系统是怎么运作的?", Chinese},
+		{"대사 시스템은 어떻게 작동하나요?", Korean},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			lang, confidence := Detect(tt.text)
+			assert.Equal(t, tt.want, lang)
+			assert.Greater(t, confidence, 0)
+			assert.LessOrEqual(t, confidence, 100)
+		})
+	}
+
+	lang, confidence := Detect("")
+	assert.Equal(t, Unknown, lang)
+	assert.Zero(t, confidence)
+}
+
+func BenchmarkDetect(b *testing.B) {
+	cases := loadBenchmark(b, "tuning.yaml")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Detect(cases[i%len(cases)].text)
+	}
+}
@@ -0,0 +1,70 @@
+// Package llm defines provider-neutral interfaces for text generation and
+// embeddings so the bot can run against Ollama or any OpenAI-compatible server
+// (llama.cpp server, vLLM, LM Studio).
+package llm
+
+import (
+	"context"
+	"time"
+)
+
+// Options are sampling parameters shared by all providers.
+// Zero values mean "use the provider default".
+type Options struct {
+	Temperature   float64
+	MaxTokens     int
+	TopK          int
+	TopP          float64
+	RepeatPenalty float64
+	NumCtx        int
+}
+
+// Chat roles understood by all providers.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message is one turn of a chat conversation.
+type Message struct {
+	Role    string
+	Content string
+}
+
+// CompletionRequest is a chat generation request. Messages are sent in order;
+// instructions and retrieved context belong in system messages, never in the
+// user turn.
+type CompletionRequest struct {
+	Model    string
+	Messages []Message
+	Options  Options
+}
+
+// CompletionResponse is the generated text plus token usage and timings.
+// Durations are zero when the provider does not report them.
+type CompletionResponse struct {
+	Text             string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	TotalDuration    time.Duration
+	PromptDuration   time.Duration
+	EvalDuration     time.Duration
+}
+
+// Generator produces text completions.
+type Generator interface {
+	Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error)
+}
+
+// Embedder produces vector embeddings for text.
+type Embedder interface {
+	Embed(ctx context.Context, model, text string) ([]float32, error)
+}
+
+// Provider is a backend that supports both generation and embeddings.
+type Provider interface {
+	Generator
+	Embedder
+}
@@ -8,8 +8,16 @@ import (
 	"io"
 	"net/http"
 	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("living-lands-bot/pkg/ollama")
+
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
@@ -43,6 +51,31 @@ type GenerateResponse struct {
 	EvalDuration       int64  `json:"eval_duration,omitempty"`        // nanoseconds
 }
 
+// Message is a single chat turn for /api/chat.
+type Message struct {
+	Role    string `json:"role"` // "system", "user", "assistant" or "tool"
+	Content string `json:"content"`
+}
+
+type ChatRequest struct {
+	Model    string    `json:"model"`
+	Messages []Message `json:"messages"`
+	Stream   bool      `json:"stream"`
+	Options  Options   `json:"options,omitempty"`
+}
+
+type ChatResponse struct {
+	Model              string  `json:"model,omitempty"`
+	Message            Message `json:"message"`
+	Done               bool    `json:"done"`
+	TotalDuration      int64   `json:"total_duration,omitempty"`       // nanoseconds
+	LoadDuration       int64   `json:"load_duration,omitempty"`        // nanoseconds
+	PromptEvalCount    int     `json:"prompt_eval_count,omitempty"`    // tokens
+	PromptEvalDuration int64   `json:"prompt_eval_duration,omitempty"` // nanoseconds
+	EvalCount          int     `json:"eval_count,omitempty"`           // tokens generated
+	EvalDuration       int64   `json:"eval_duration,omitempty"`        // nanoseconds
+}
+
 type EmbedRequest struct {
 	Model string `json:"model"`
 	Input string `json:"input"`
@@ -65,11 +98,19 @@ func NewClientWithTimeout(baseURL string, timeout time.Duration) *Client {
 		baseURL: baseURL,
 		httpClient: &http.Client{
 			Timeout: timeout,
+			// Propagates trace context to Ollama and records an HTTP client span
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
 		},
 	}
 }
 
-func (c *Client) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+func (c *Client) Generate(ctx context.Context, req GenerateRequest) (_ *GenerateResponse, err error) {
+	ctx, span := tracer.Start(ctx, "ollama.Generate", trace.WithAttributes(
+		attribute.String("llm.model", req.Model),
+		attribute.String("ollama.endpoint", "/api/generate"),
+	))
+	defer func() { endSpan(span, err) }()
+
 	req.Stream = false
 
 	body, err := json.Marshal(req)
@@ -110,7 +151,60 @@ func (c *Client) Generate(ctx context.Context, req GenerateRequest) (*GenerateRe
 	return &genResp, nil
 }
 
-func (c *Client) Embed(ctx context.Context, model, text string) ([]float32, error) {
+// Chat sends a structured conversation to /api/chat (non-streaming).
+// Role separation lets the model distinguish instructions from user text,
+// so no hand-built "User:/Assistant:" transcript is needed.
+func (c *Client) Chat(ctx context.Context, req ChatRequest) (_ *ChatResponse, err error) {
+	ctx, span := tracer.Start(ctx, "ollama.Generate", trace.WithAttributes(
+		attribute.String("llm.model", req.Model),
+		attribute.String("ollama.endpoint", "/api/chat"),
+	))
+	defer func() { endSpan(span, err) }()
+
+	req.Stream = false
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST",
+		c.baseURL+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Read response body for error details
+		body, _ := io.ReadAll(resp.Body)
+		bodyStr := string(body)
+		// Truncate very long error responses to prevent log spam
+		if len(bodyStr) > 500 {
+			bodyStr = bodyStr[:500] + "... (truncated)"
+		}
+		return nil, fmt.Errorf("ollama chat request failed with status %d: %s", resp.StatusCode, bodyStr)
+	}
+
+	var chatResp ChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return nil, err
+	}
+
+	return &chatResp, nil
+}
+
+func (c *Client) Embed(ctx context.Context, model, text string) (_ []float32, err error) {
+	ctx, span := tracer.Start(ctx, "ollama.Embed", trace.WithAttributes(attribute.String("llm.model", model)))
+	defer func() { endSpan(span, err) }()
+
 	req := EmbedRequest{
 		Model: model,
 		Input: text,
@@ -157,3 +251,12 @@ func (c *Client) Embed(ctx context.Context, model, text string) ([]float32, erro
 
 	return embedResp.Embeddings[0], nil
 }
+
+// endSpan records err on span (if any) and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,44 @@
+package ollama
+
+import (
+	"context"
+	"time"
+
+	"living-lands-bot/pkg/llm"
+)
+
+var _ llm.Provider = (*Client)(nil)
+
+// Complete implements llm.Generator on top of /api/chat.
+func (c *Client) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	messages := make([]Message, len(req.Messages))
+	for i, m := range req.Messages {
+		messages[i] = Message{Role: m.Role, Content: m.Content}
+	}
+
+	resp, err := c.Chat(ctx, ChatRequest{
+		Model:    req.Model,
+		Messages: messages,
+		Options: Options{
+			Temperature:   req.Options.Temperature,
+			NumPredict:    req.Options.MaxTokens,
+			TopK:          req.Options.TopK,
+			TopP:          req.Options.TopP,
+			RepeatPenalty: req.Options.RepeatPenalty,
+			NumCtx:        req.Options.NumCtx,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &llm.CompletionResponse{
+		Text:             resp.Message.Content,
+		Model:            req.Model,
+		PromptTokens:     resp.PromptEvalCount,
+		CompletionTokens: resp.EvalCount,
+		TotalDuration:    time.Duration(resp.TotalDuration),
+		PromptDuration:   time.Duration(resp.PromptEvalDuration),
+		EvalDuration:     time.Duration(resp.EvalDuration),
+	}, nil
+}
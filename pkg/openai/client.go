@@ -0,0 +1,213 @@
+// Package openai is a minimal client for OpenAI-compatible HTTP APIs
+// (/v1/chat/completions and /v1/embeddings) as served by llama.cpp server,
+// vLLM and LM Studio.
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"living-lands-bot/pkg/llm"
+)
+
+var _ llm.Provider = (*Client)(nil)
+
+var tracer = otel.Tracer("living-lands-bot/pkg/openai")
+
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ChatCompletionRequest struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Temperature float64   `json:"temperature,omitempty"`
+	TopP        float64   `json:"top_p,omitempty"`
+	Stream      bool      `json:"stream"`
+
+	// Non-standard sampling fields understood by llama.cpp server, vLLM and LM Studio.
+	TopK          int     `json:"top_k,omitempty"`
+	RepeatPenalty float64 `json:"repeat_penalty,omitempty"`
+}
+
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type ChatCompletionResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Index        int     `json:"index"`
+		Message      Message `json:"message"`
+		FinishReason string  `json:"finish_reason"`
+	} `json:"choices"`
+	Usage Usage `json:"usage"`
+}
+
+type EmbeddingRequest struct {
+	Model string `json:"model"`
+	Input string `json:"input"`
+}
+
+type EmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Usage Usage `json:"usage"`
+}
+
+// NewClient creates a client for an OpenAI-compatible server.
+// baseURL should include the API version prefix, e.g. "http://localhost:8080/v1".
+// apiKey may be empty for local servers that do not require authentication.
+func NewClient(baseURL, apiKey string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		httpClient: &http.Client{
+			Timeout:   timeout,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+	}
+}
+
+// ChatCompletion calls /chat/completions (non-streaming).
+func (c *Client) ChatCompletion(ctx context.Context, req ChatCompletionRequest) (_ *ChatCompletionResponse, err error) {
+	ctx, span := tracer.Start(ctx, "openai.ChatCompletion", trace.WithAttributes(attribute.String("llm.model", req.Model)))
+	defer func() { endSpan(span, err) }()
+
+	req.Stream = false
+
+	var chatResp ChatCompletionResponse
+	if err := c.post(ctx, "/chat/completions", req, &chatResp); err != nil {
+		return nil, fmt.Errorf("openai chat completion failed: %w", err)
+	}
+
+	if len(chatResp.Choices) == 0 {
+		return nil, fmt.Errorf("openai chat completion returned no choices")
+	}
+
+	return &chatResp, nil
+}
+
+// Complete implements llm.Generator using /chat/completions.
+func (c *Client) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	start := time.Now()
+
+	messages := make([]Message, len(req.Messages))
+	for i, m := range req.Messages {
+		messages[i] = Message{Role: m.Role, Content: m.Content}
+	}
+
+	resp, err := c.ChatCompletion(ctx, ChatCompletionRequest{
+		Model:         req.Model,
+		Messages:      messages,
+		MaxTokens:     req.Options.MaxTokens,
+		Temperature:   req.Options.Temperature,
+		TopP:          req.Options.TopP,
+		TopK:          req.Options.TopK,
+		RepeatPenalty: req.Options.RepeatPenalty,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	model := resp.Model
+	if model == "" {
+		model = req.Model
+	}
+
+	// OpenAI-compatible servers report token usage but not timings, so only the
+	// client-side wall time is available.
+	return &llm.CompletionResponse{
+		Text:             resp.Choices[0].Message.Content,
+		Model:            model,
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+		TotalDuration:    time.Since(start),
+	}, nil
+}
+
+// Embed implements llm.Embedder using /embeddings.
+func (c *Client) Embed(ctx context.Context, model, text string) (_ []float32, err error) {
+	ctx, span := tracer.Start(ctx, "openai.Embed", trace.WithAttributes(attribute.String("llm.model", model)))
+	defer func() { endSpan(span, err) }()
+
+	var embedResp EmbeddingResponse
+	if err := c.post(ctx, "/embeddings", EmbeddingRequest{Model: model, Input: text}, &embedResp); err != nil {
+		return nil, fmt.Errorf("openai embedding failed: %w", err)
+	}
+
+	if len(embedResp.Data) == 0 {
+		return nil, fmt.Errorf("no embeddings returned")
+	}
+
+	return embedResp.Data[0].Embedding, nil
+}
+
+func (c *Client) post(ctx context.Context, path string, payload, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Read response body for error details
+		body, _ := io.ReadAll(resp.Body)
+		bodyStr := string(body)
+		// Truncate very long error responses to prevent log spam
+		if len(bodyStr) > 500 {
+			bodyStr = bodyStr[:500] + "... (truncated)"
+		}
+		return fmt.Errorf("request to %s failed with status %d: %s", path, resp.StatusCode, bodyStr)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// endSpan records err on span (if any) and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
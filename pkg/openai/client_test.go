@@ -0,0 +1,77 @@
+package openai_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"living-lands-bot/internal/testutil"
+	"living-lands-bot/pkg/llm"
+	"living-lands-bot/pkg/openai"
+)
+
+func TestCompleteMapsMessagesAndUsage(t *testing.T) {
+	server := testutil.NewOpenAIServer(t)
+	server.Script(testutil.Completion{Response: "Hello from llama.cpp", PromptEvalCount: 42, EvalCount: 5})
+
+	client := openai.NewClient(server.URL+"/v1/", "secret", 5*time.Second)
+	resp, err := client.Complete(context.Background(), llm.CompletionRequest{
+		Model: "qwen2.5:7b",
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: "You are Elder Sage."},
+			{Role: llm.RoleUser, Content: "Who are you?"},
+		},
+		Options: llm.Options{
+			Temperature: 0.6,
+			MaxTokens:   120,
+			TopK:        30,
+			TopP:        0.9,
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Hello from llama.cpp", resp.Text)
+	assert.Equal(t, "qwen2.5:7b", resp.Model)
+	assert.Equal(t, 42, resp.PromptTokens)
+	assert.Equal(t, 5, resp.CompletionTokens)
+	assert.Greater(t, resp.TotalDuration, time.Duration(0))
+
+	reqs := server.ChatRequests()
+	require.Len(t, reqs, 1)
+	require.Len(t, reqs[0].Messages, 2)
+	assert.Equal(t, "system", reqs[0].Messages[0].Role)
+	assert.Equal(t, "user", reqs[0].Messages[1].Role)
+	assert.Equal(t, "Who are you?", reqs[0].Messages[1].Content)
+	assert.Equal(t, 120, reqs[0].MaxTokens)
+	assert.Equal(t, 30, reqs[0].TopK)
+	assert.Equal(t, []string{"Bearer secret"}, server.AuthorizationHeaders())
+}
+
+func TestCompleteServerError(t *testing.T) {
+	server := testutil.NewOpenAIServer(t)
+	server.Script(testutil.Completion{Status: http.StatusServiceUnavailable})
+
+	client := openai.NewClient(server.URL+"/v1", "", 5*time.Second)
+	_, err := client.Complete(context.Background(), llm.CompletionRequest{
+		Model:    "m",
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "hi"}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "503")
+}
+
+func TestEmbed(t *testing.T) {
+	server := testutil.NewOpenAIServer(t)
+
+	client := openai.NewClient(server.URL+"/v1", "", 5*time.Second)
+	vec, err := client.Embed(context.Background(), "nomic-embed-text", "metabolism hunger")
+	require.NoError(t, err)
+	assert.Equal(t, testutil.HashEmbedding("metabolism hunger", testutil.DefaultEmbeddingDim), vec)
+
+	// No API key configured: no Authorization header is sent
+	assert.Equal(t, []string{""}, server.AuthorizationHeaders())
+}